@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/anonymize"
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/importer"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var anonymizeCmd = &cobra.Command{
+	Use:   "anonymize <service> <file>",
+	Short: "Write a SQL dump with its personal data replaced",
+	Long: `Write a SQL dump with the personal data replaced by the anonymize rules of the
+configuration, without loading it anywhere, e.g. to check the rules on a
+sample before importing a dump. The service decides the SQL dialect: mysql or
+postgres. gzip-compressed dumps are decompressed.
+
+The rules map table.column to the fake its values are replaced with:
+
+  anonymize:
+    salt: a-long-random-string   # Keeps fakes from being matched to guesses
+    rules:
+      users.email: email         # jane.smith.3fa9c21b07@example.com
+      users.full_name: name      # Jane Smith
+      users.phone: phone         # +1-555-123-4567
+      users.password: hash       # A hex digest as long as the value
+      public.users.ssn: null     # NULL; a schema narrows the table down
+
+Equal values get equal fakes, so the data still joins up. Without a salt, a
+random one is used, so the fakes differ from run to run.
+'dockenv import-data' applies the rules to every SQL dump it loads into MySQL
+or PostgreSQL, and saves a random salt to the configuration if there is none.
+
+Examples:
+  dockenv anonymize postgres prod-sample.sql | less
+  dockenv anonymize mysql prod.sql.gz -o prod-anon.sql`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAnonymize,
+}
+
+var anonymizeOutputFlag string
+
+func init() {
+	rootCmd.AddCommand(anonymizeCmd)
+
+	anonymizeCmd.Flags().StringVarP(&anonymizeOutputFlag, "output", "o", "", "Write the dump to this file instead of standard output")
+}
+
+func runAnonymize(cmd *cobra.Command, args []string) error {
+	serviceName, file := args[0], args[1]
+
+	if _, exists := anonymize.LookupDialect(serviceName); !exists {
+		return fmt.Errorf("%s dumps cannot be anonymized; only mysql and postgres dumps can", serviceName)
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if len(cfg.Anonymize.Rules) == 0 {
+		return fmt.Errorf("no anonymize rules; add them under 'anonymize' in %s", config.GetConfigPath())
+	}
+
+	dump, err := importer.Open(file, nil)
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	anonymizer, err := newAnonymizer(cfg, serviceName, dump, false)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	var outFile *os.File
+	if anonymizeOutputFlag != "" {
+		if outFile, err = os.Create(anonymizeOutputFlag); err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer outFile.Close()
+		out = outFile
+	}
+
+	if _, err := io.Copy(out, anonymizer); err != nil {
+		return fmt.Errorf("failed to anonymize dump: %w", err)
+	}
+	if outFile != nil {
+		if err := outFile.Close(); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+	}
+
+	printAnonymizeReport(os.Stderr, anonymizer)
+	return nil
+}
+
+// newAnonymizer returns a reader that applies the configured anonymize
+// rules to a dump loaded into a service, or nil if the service does not
+// load SQL or there are no rules. Only SQL dumps can be anonymized, so with
+// rules other formats are refused. saveSalt saves a generated salt to the
+// configuration.
+func newAnonymizer(cfg *config.Config, serviceName string, dump *importer.Dump, saveSalt bool) (*anonymize.Reader, error) {
+	dialect, exists := anonymize.LookupDialect(serviceName)
+	if !exists || len(cfg.Anonymize.Rules) == 0 {
+		return nil, nil
+	}
+
+	rules, err := anonymize.ParseRules(cfg.Anonymize.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if dump.Format != importer.FormatSQL {
+		return nil, fmt.Errorf("a %s dump cannot be anonymized; convert it to SQL first, e.g. with 'pg_restore -f dump.sql'",
+			importer.Descriptions[dump.Format])
+	}
+
+	salt, err := anonymizeSalt(cfg, saveSalt)
+	if err != nil {
+		return nil, err
+	}
+	return anonymize.NewReader(dump, dialect, rules, salt), nil
+}
+
+// anonymizeSalt returns the configured salt. Without one, fakes could be
+// matched to guessed values, so a random salt is generated. If save is set it
+// is saved to the configuration, which keeps the fakes of later imports
+// equal; otherwise, and in recording mode, it is used once.
+func anonymizeSalt(cfg *config.Config, save bool) (string, error) {
+	if cfg.Anonymize.Salt != "" {
+		return cfg.Anonymize.Salt, nil
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate anonymize salt: %w", err)
+	}
+	cfg.Anonymize.Salt = hex.EncodeToString(random)
+
+	switch {
+	case runner.Recording():
+		fmt.Fprintln(os.Stderr, "⚠️  No anonymize salt configured; using a random one for this dry run.")
+		return cfg.Anonymize.Salt, nil
+	case !save:
+		fmt.Fprintln(os.Stderr, "⚠️  No anonymize salt configured; using a random one, so the fakes change on every run.")
+		fmt.Fprintln(os.Stderr, "   Set anonymize.salt in the configuration, or import a dump to save one.")
+		return cfg.Anonymize.Salt, nil
+	}
+	if err := utils.SaveConfig(cfg); err != nil {
+		return "", fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "🔑 No anonymize salt configured; saved a random one to %s\n", config.GetConfigPath())
+	return cfg.Anonymize.Salt, nil
+}
+
+// printAnonymizeReport prints how many values each rule replaced.
+func printAnonymizeReport(out io.Writer, anonymizer *anonymize.Reader) {
+	width := 0
+	for _, rule := range anonymizer.Rules() {
+		width = max(width, len(rule.String()))
+	}
+
+	matches := anonymizer.Matches()
+	var unmatched []string
+	fmt.Fprintln(out, "🔒 Anonymized values:")
+	for _, rule := range anonymizer.Rules() {
+		fmt.Fprintf(out, "   %-*s %-6s %d\n", width, rule, rule.Fake, matches[rule])
+		if matches[rule] == 0 {
+			unmatched = append(unmatched, rule.String())
+		}
+	}
+	if len(unmatched) > 0 {
+		fmt.Fprintf(out, "⚠️  No values matched %s; check the table and column names.\n", strings.Join(unmatched, ", "))
+	}
+}
@@ -67,7 +67,7 @@ func runEnableAutostart(cmd *cobra.Command, args []string) error {
 	fmt.Println("   This requires sudo privileges.")
 	fmt.Println()
 
-	if err := systemd.EnableAutostart(); err != nil {
+	if err := systemd.EnableAutostart(cmd.Context()); err != nil {
 		return fmt.Errorf("failed to enable autostart: %w", err)
 	}
 
@@ -79,7 +79,7 @@ func runDisableAutostart(cmd *cobra.Command, args []string) error {
 	fmt.Println("   This requires sudo privileges.")
 	fmt.Println()
 
-	if err := systemd.DisableAutostart(); err != nil {
+	if err := systemd.DisableAutostart(cmd.Context()); err != nil {
 		return fmt.Errorf("failed to disable autostart: %w", err)
 	}
 
@@ -90,8 +90,8 @@ func runStatusAutostart(cmd *cobra.Command, args []string) error {
 	fmt.Println("📊 Auto-start Status:")
 	fmt.Println()
 
-	enabled := systemd.IsEnabled()
-	active := systemd.IsActive()
+	enabled := systemd.IsEnabled(cmd.Context())
+	active := systemd.IsActive(cmd.Context())
 
 	if enabled {
 		fmt.Println("✅ Auto-start is ENABLED")
@@ -108,7 +108,7 @@ func runStatusAutostart(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("📋 Detailed Status:")
 
-	if err := systemd.GetStatus(); err != nil {
+	if err := systemd.GetStatus(cmd.Context()); err != nil {
 		fmt.Printf("Failed to get detailed status: %v\n", err)
 	}
 
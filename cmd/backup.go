@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/backup"
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [service...]",
+	Short: "Dump services into a backup archive",
+	Long: `Dump the given services, or all configured services, with their native
+tools inside their running containers and write the dumps into a timestamped,
+compressed archive under the data path.
+
+  postgres   pg_dumpall
+  mysql      mysqldump
+  mongodb    mongodump
+  redis      BGSAVE and a copy of the RDB file
+  rabbitmq   rabbitmqctl export_definitions
+
+Other services have no logical backup and are skipped.
+
+Examples:
+  dockenv backup                       # Back up every supported service
+  dockenv backup postgres              # Back up only PostgreSQL
+  dockenv backup -o before-migration.tar.gz`,
+	RunE: runBackup,
+}
+
+var backupOutputFlag string
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.Flags().StringVarP(&backupOutputFlag, "output", "o", "", "Write the archive to this file instead of the backups directory")
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(cfg.Services) == 0 {
+		fmt.Println("📋 No services configured.")
+		fmt.Println("   Run 'dockenv init' to set up services.")
+		return nil
+	}
+
+	serviceNames := args
+	if len(serviceNames) == 0 {
+		serviceNames = cfg.Services
+	}
+
+	var toBackup, unsupported []string
+	for _, serviceName := range serviceNames {
+		if !utils.Contains(cfg.Services, serviceName) {
+			return fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+		}
+		if _, exists := backup.LookupMethod(serviceName); exists {
+			toBackup = append(toBackup, serviceName)
+		} else {
+			unsupported = append(unsupported, serviceName)
+		}
+	}
+
+	if len(args) > 0 && len(unsupported) > 0 {
+		return fmt.Errorf("no logical backup for: %s", strings.Join(unsupported, ", "))
+	}
+	if len(unsupported) > 0 {
+		fmt.Printf("⚠️  No logical backup for: %s (skipped)\n", strings.Join(unsupported, ", "))
+	}
+	if len(toBackup) == 0 {
+		return fmt.Errorf("none of the services supports logical backups")
+	}
+
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	created := time.Now().UTC().Truncate(time.Second)
+	archivePath := backupOutputFlag
+	if archivePath == "" {
+		archivePath = filepath.Join(cfg.GetBackupPath(), backup.FileName(config.GetProjectName(), created))
+	}
+
+	dumpDir, err := os.MkdirTemp("", "dockenv-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create dump directory: %w", err)
+	}
+	defer os.RemoveAll(dumpDir)
+
+	manifest := &backup.Manifest{
+		Version: backup.ManifestVersion,
+		Created: created,
+		Project: config.GetProjectName(),
+	}
+
+	for _, serviceName := range toBackup {
+		service, _ := services.GetService(serviceName)
+		method, _ := backup.LookupMethod(serviceName)
+
+		fmt.Printf("💾 Dumping %s with %s...\n", service.DisplayName, method.Tool)
+		dump := backup.Dump{
+			Service: serviceName,
+			Version: service.ResolveVersion(cfg.Versions[serviceName]),
+			Tool:    method.Tool,
+			File:    serviceName + "/" + method.File,
+		}
+		if err := dumpService(cmd, service, method, filepath.Join(dumpDir, filepath.FromSlash(dump.File))); err != nil {
+			return err
+		}
+		manifest.Services = append(manifest.Services, dump)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no backup was written.")
+		return nil
+	}
+
+	if err := writeBackup(archivePath, manifest, dumpDir); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Backup written!")
+	fmt.Printf("   Archive: %s\n", archivePath)
+	fmt.Printf("   Services: %s\n", strings.Join(manifest.ServiceNames(), ", "))
+	fmt.Printf("   Restore with: dockenv restore %s\n", archivePath)
+	return nil
+}
+
+// dumpService runs the dump tool of a service in its container and writes
+// the dump to file. In recording mode nothing is written.
+func dumpService(cmd *cobra.Command, service services.Service, method backup.Method, file string) error {
+	if runner.Recording() {
+		return docker.ExecScript(cmd.Context(), service.ContainerName(), nil, io.Discard, method.Dump)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to create dump directory: %w", err)
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create dump: %w", err)
+	}
+	defer f.Close()
+
+	if err := docker.ExecScript(cmd.Context(), service.ContainerName(), nil, f, method.Dump); err != nil {
+		return fmt.Errorf("failed to dump %s (is it running?): %w", service.Name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+	return nil
+}
+
+// writeBackup writes the archive next to its final path and renames it into
+// place, so that an interrupted backup leaves no partial archive.
+func writeBackup(archivePath string, manifest *backup.Manifest, dumpDir string) error {
+	dir := filepath.Dir(archivePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".dockenv-backup-*"+backup.Extension)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := backup.Write(tmp, manifest, dumpDir); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), archivePath); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/git"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/snapshot"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// branchHookMarker identifies the post-checkout hook dockenv installs.
+const branchHookMarker = "# Installed by dockenv: keep service data per git branch"
+
+// branchHook syncs only on branch checkouts, for which git passes 1 as the
+// third argument, not when single files are checked out.
+const branchHook = `#!/bin/sh
+` + branchHookMarker + `
+[ "$3" = "1" ] || exit 0
+command -v dockenv >/dev/null 2>&1 || exit 0
+exec dockenv branch sync
+`
+
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Keep separate service data per git branch",
+	Long: `Keep a snapshot of the service data for every git branch, so that switching
+between branches with different migrations does not break the local
+databases.
+
+'dockenv branch sync' saves the data of the branch it belongs to and restores
+the data of the checked-out branch. A branch without data of its own starts
+with a copy of the data of the branch it was switched from. Run it after
+'git checkout', or install a post-checkout hook that does.
+
+Branch data is off until enabled, and needs the same setup as snapshots.
+
+Examples:
+  dockenv branch enable   # Start keeping data per branch
+  dockenv branch hook     # Sync on every git checkout
+  dockenv branch sync     # Sync by hand
+  dockenv branch status`,
+}
+
+var enableBranchCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Keep service data per git branch",
+	Args:  cobra.NoArgs,
+	RunE:  runEnableBranch,
+}
+
+var disableBranchCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop keeping service data per git branch",
+	Args:  cobra.NoArgs,
+	RunE:  runDisableBranch,
+}
+
+var syncBranchCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Switch the service data to the checked-out branch",
+	Args:  cobra.NoArgs,
+	RunE:  runSyncBranch,
+}
+
+var statusBranchCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which branch the service data belongs to",
+	Args:  cobra.NoArgs,
+	RunE:  runStatusBranch,
+}
+
+var hookBranchCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Install a git post-checkout hook that runs 'dockenv branch sync'",
+	Args:  cobra.NoArgs,
+	RunE:  runHookBranch,
+}
+
+var hookBranchRemoveFlag bool
+
+func init() {
+	rootCmd.AddCommand(branchCmd)
+
+	branchCmd.AddCommand(enableBranchCmd)
+	branchCmd.AddCommand(disableBranchCmd)
+	branchCmd.AddCommand(syncBranchCmd)
+	branchCmd.AddCommand(statusBranchCmd)
+	branchCmd.AddCommand(hookBranchCmd)
+
+	hookBranchCmd.Flags().BoolVar(&hookBranchRemoveFlag, "remove", false, "Remove the hook instead")
+}
+
+// currentBranch returns the git directory and checked-out branch of the
+// repository around the working directory.
+func currentBranch() (string, string, error) {
+	gitDir, err := git.FindDir(".")
+	if err != nil {
+		return "", "", err
+	}
+	branch, err := git.CurrentBranch(gitDir)
+	return gitDir, branch, err
+}
+
+func runEnableBranch(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	_, branch, err := currentBranch()
+	if err != nil {
+		return err
+	}
+	if cfg.UsesVolumes(false) {
+		return fmt.Errorf("branch data needs data directories; set 'storage: %s'", config.StorageBind)
+	}
+
+	cfg.BranchData = true
+	if dryRunFlag {
+		return printPlan(cfg)
+	}
+	if err := utils.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// The data already there belongs to the checked-out branch
+	root := cfg.GetSnapshotPath()
+	live, err := snapshot.LiveBranch(root)
+	if err != nil {
+		return err
+	}
+	if live == "" {
+		live = branch
+		if err := snapshot.SetLiveBranch(root, branch); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("✅ Branch data enabled!")
+	fmt.Printf("   The current data belongs to branch %s.\n", live)
+	fmt.Println("\nNext steps:")
+	fmt.Println("  dockenv branch hook   # Sync the data on every git checkout")
+	return nil
+}
+
+func runDisableBranch(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cfg.BranchData = false
+	if dryRunFlag {
+		return printPlan(cfg)
+	}
+	if err := utils.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Println("✅ Branch data disabled.")
+	fmt.Println("   The data of other branches is kept as snapshots named branch-*;")
+	fmt.Println("   remove them with 'dockenv snapshot delete'.")
+	return nil
+}
+
+func runSyncBranch(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.BranchData {
+		fmt.Println("ℹ️  Branch data is off. Enable it with 'dockenv branch enable'.")
+		return nil
+	}
+
+	_, branch, err := currentBranch()
+	if err != nil {
+		// A detached HEAD, e.g. during a rebase, keeps the current data
+		fmt.Printf("ℹ️  Keeping the current data: %v\n", err)
+		return nil
+	}
+
+	root := cfg.GetSnapshotPath()
+	live, err := snapshot.LiveBranch(root)
+	if err != nil {
+		return err
+	}
+	if live == branch {
+		fmt.Printf("✅ The data already belongs to branch %s.\n", branch)
+		return nil
+	}
+	if live == "" {
+		if !runner.Recording() {
+			if err := snapshot.SetLiveBranch(root, branch); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("✅ The current data now belongs to branch %s.\n", branch)
+		return nil
+	}
+
+	if cfg, err = loadSnapshotConfig(cmd); err != nil {
+		return err
+	}
+
+	saved := newSnapshotManifest(cfg, snapshot.BranchName(live), cfg.Services)
+	target, err := snapshot.Load(root, snapshot.BranchName(branch))
+	switch {
+	case os.IsNotExist(err):
+		target = nil
+	case err != nil:
+		return err
+	default:
+		if err := checkSnapshotVersions(cmd, cfg, target); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("🌿 Switching data from branch %s to %s...\n", live, branch)
+
+	affected := append([]string(nil), saved.Dirs...)
+	if target != nil {
+		for _, dir := range target.Dirs {
+			if !utils.Contains(affected, dir) {
+				affected = append(affected, dir)
+			}
+		}
+	}
+
+	if !runner.Recording() && len(saved.Dirs) > 0 {
+		if err := os.MkdirAll(snapshot.Dir(root, saved.Name), 0755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+	}
+
+	err = withServicesStopped(cmd, affected, func() error {
+		if len(saved.Dirs) > 0 {
+			scriptArgs := append([]string{snapshotDataDir(saved.Name)}, saved.Dirs...)
+			if err := docker.RunDataScript(cmd.Context(), cfg.DataPath, snapshot.CreateScript, scriptArgs...); err != nil {
+				return fmt.Errorf("failed to save the data of branch %s: %w", live, err)
+			}
+		}
+		if target != nil {
+			scriptArgs := append([]string{snapshotDataDir(target.Name)}, target.Dirs...)
+			if err := docker.RunDataScript(cmd.Context(), cfg.DataPath, snapshot.RestoreScript, scriptArgs...); err != nil {
+				return fmt.Errorf("failed to restore the data of branch %s: %w", branch, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: the data was not switched.")
+		return nil
+	}
+
+	if len(saved.Dirs) > 0 {
+		if err := saved.Save(root); err != nil {
+			return err
+		}
+	}
+	if err := snapshot.SetLiveBranch(root, branch); err != nil {
+		return err
+	}
+
+	if target != nil {
+		fmt.Printf("✅ Restored the data of branch %s (saved %s).\n", branch, target.Created.Local().Format("2006-01-02 15:04"))
+	} else {
+		fmt.Printf("✅ Branch %s starts with a copy of the data of %s.\n", branch, live)
+	}
+	return nil
+}
+
+func runStatusBranch(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println("🌿 Branch data:")
+	if cfg.BranchData {
+		fmt.Println("   Enabled: yes")
+	} else {
+		fmt.Println("   Enabled: no")
+	}
+
+	gitDir, branch, branchErr := currentBranch()
+	if branchErr != nil {
+		fmt.Printf("   Checked out: unknown (%v)\n", branchErr)
+	} else {
+		fmt.Printf("   Checked out: %s\n", branch)
+	}
+
+	root := cfg.GetSnapshotPath()
+	live, err := snapshot.LiveBranch(root)
+	if err != nil {
+		return err
+	}
+	if live != "" {
+		fmt.Printf("   Data belongs to: %s\n", live)
+	}
+
+	manifests, err := snapshot.List(root)
+	if err != nil {
+		return err
+	}
+	var saved []string
+	for _, manifest := range manifests {
+		if strings.HasPrefix(manifest.Name, snapshot.BranchPrefix) {
+			saved = append(saved, manifest.Name)
+		}
+	}
+	if len(saved) > 0 {
+		fmt.Printf("   Saved: %s\n", strings.Join(saved, ", "))
+	}
+
+	if gitDir != "" {
+		hook := filepath.Join(git.HooksDir(gitDir), "post-checkout")
+		if data, err := os.ReadFile(hook); err == nil && strings.Contains(string(data), branchHookMarker) {
+			fmt.Println("   Hook: installed")
+		} else {
+			fmt.Println("   Hook: not installed (dockenv branch hook)")
+		}
+	}
+
+	if cfg.BranchData && branchErr == nil && live != "" && live != branch {
+		fmt.Println("\n⚠️  The data belongs to another branch. Run 'dockenv branch sync'.")
+	}
+	return nil
+}
+
+func runHookBranch(cmd *cobra.Command, args []string) error {
+	gitDir, err := git.FindDir(".")
+	if err != nil {
+		return err
+	}
+
+	hook := filepath.Join(git.HooksDir(gitDir), "post-checkout")
+	data, err := os.ReadFile(hook)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", hook, err)
+	}
+	ours := exists && strings.Contains(string(data), branchHookMarker)
+
+	if hookBranchRemoveFlag {
+		if !ours {
+			fmt.Println("ℹ️  No dockenv post-checkout hook is installed.")
+			return nil
+		}
+		if dryRunFlag {
+			fmt.Printf("🔍 Dry run: would remove %s\n", hook)
+			return nil
+		}
+		if err := os.Remove(hook); err != nil {
+			return fmt.Errorf("failed to remove hook: %w", err)
+		}
+		fmt.Println("✅ Post-checkout hook removed.")
+		return nil
+	}
+
+	if exists && !ours {
+		fmt.Printf("❌ %s already exists.\n", hook)
+		fmt.Println("   Add this line to it to sync the data on checkout:")
+		fmt.Println(`   [ "$3" = "1" ] && dockenv branch sync`)
+		cmd.SilenceUsage = true
+		return fmt.Errorf("post-checkout hook already exists")
+	}
+
+	if dryRunFlag {
+		fmt.Printf("🔍 Dry run: would write %s\n", hook)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(hook), 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(hook, []byte(branchHook), 0755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+
+	fmt.Println("✅ Post-checkout hook installed!")
+	fmt.Printf("   Hook: %s\n", hook)
+	fmt.Println("   The service data now follows 'git checkout' and 'git switch'.")
+	return nil
+}
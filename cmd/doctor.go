@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/doctor"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/systemd"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the development environment",
+	Long: `Check the environment dockenv depends on and suggest fixes for problems.
+
+The checks cover Docker/Podman and Compose versions, access to the engine
+socket, rootless mode, free disk space under the data path, ownership of the
+data directories, port conflicts, stale containers and the autostart unit.
+
+The command exits with a non-zero status when any check fails.
+
+Examples:
+  dockenv doctor          # Run all checks
+  dockenv doctor --json   # Machine-readable report`,
+	RunE: runDoctor,
+}
+
+var doctorJSONFlag bool
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorJSONFlag, "json", false, "Print the report as JSON")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	report := &doctor.Report{}
+
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
+	switch {
+	case err != nil && cmd.Context().Err() != nil:
+		return fmt.Errorf("failed to check Docker: %w", err)
+	case errors.As(err, new(*runner.TimeoutError)):
+		report.Add(doctor.Fail("engine", "the engine does not respond: "+err.Error(), "Restart the Docker daemon or Podman service"))
+		dockerInfo = &docker.DockerInfo{}
+	case err != nil:
+		report.Add(doctor.Fail("compose", err.Error(), "Set 'runtime' in the configuration to one of the supported runtimes"))
+		dockerInfo = &docker.DockerInfo{}
+	default:
+		report.Add(doctor.CheckEngine(dockerInfo), doctor.CheckCompose(dockerInfo))
+		report.Add(doctor.CheckSocket(dockerInfo, socketAccess(dockerInfo)))
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	report.Add(doctor.CheckRootless(dockerInfo, configuredHostPorts(cfg)))
+
+	// Named volumes and the data path of a remote engine are not on this
+	// machine
+	if !cfg.UsesVolumes(dockerInfo.Remote()) && !dockerInfo.Remote() {
+		if free, err := host.FreeDiskSpace(cfg.DataPath); err != nil {
+			report.Add(doctor.Warn("disk space", err.Error(), ""))
+		} else {
+			report.Add(doctor.CheckDiskSpace(cfg.DataPath, free))
+		}
+
+		report.Add(dataOwnerChecks(cmd.Context(), cfg, dockerInfo)...)
+	}
+
+	if conflicts, _ := findPortConflicts(cmd.Context(), cfg, cfg.Services); len(conflicts) > 0 {
+		for _, c := range conflicts {
+			report.Add(doctor.Fail("ports", c.String(), "Run 'dockenv up --auto-port' or choose another port with --port"))
+		}
+	} else {
+		report.Add(doctor.Pass("ports", "no conflicts"))
+	}
+
+	if dockerInfo.IsReady() {
+		containers, err := docker.ComposeContainers(cmd.Context(), config.GetComposePath(), config.GetProjectName())
+		if err != nil {
+			report.Add(doctor.Warn("stale containers", err.Error(), ""))
+		} else {
+			report.Add(doctor.CheckStaleContainers(cfg.Services, containers))
+		}
+	}
+
+	unit, err := systemd.ReadUnit()
+	switch {
+	case os.IsNotExist(err):
+		report.Add(doctor.CheckUnit(nil, dockerInfo.Engine))
+	case err != nil:
+		report.Add(doctor.Warn("autostart", fmt.Sprintf("failed to read %s: %v", systemd.ServiceFilePath, err), ""))
+	default:
+		report.Add(doctor.CheckUnit(unit, dockerInfo.Engine))
+	}
+
+	if doctorJSONFlag {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode report: %w", err)
+		}
+		os.Stdout.Write(append(data, '\n'))
+	} else {
+		fmt.Println("🩺 Checking your environment...")
+		fmt.Println()
+		report.Print(os.Stdout)
+	}
+
+	if report.Failed() {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d check(s) failed", report.Count(doctor.StatusFail))
+	}
+	return nil
+}
+
+// socketAccess inspects the engine socket for the current user.
+func socketAccess(dockerInfo *docker.DockerInfo) doctor.SocketAccess {
+	access := doctor.SocketAccess{Path: dockerInfo.SocketPath}
+	if _, err := os.Stat(access.Path); err != nil {
+		return access
+	}
+	access.Exists = true
+	access.Writable = host.Writable(access.Path)
+
+	current, err := user.Current()
+	if err != nil {
+		return access
+	}
+	group, err := user.LookupGroup("docker")
+	if err != nil {
+		return access
+	}
+	if groups, err := current.GroupIds(); err == nil {
+		access.InGroup = utils.Contains(groups, group.Gid)
+	}
+	return access
+}
+
+// configuredHostPorts returns the host ports of all configured services.
+func configuredHostPorts(cfg *config.Config) []int {
+	var ports []int
+	for _, serviceName := range cfg.Services {
+		service, exists := services.GetService(serviceName)
+		if !exists {
+			continue
+		}
+		for _, port := range service.HostPorts(cfg.Ports[serviceName]) {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// dataOwnerChecks compares the owners of existing data directories with the
+// users the containers write as. Under rootless Podman the owner is read in
+// the user namespace, where container ids are mapped.
+func dataOwnerChecks(ctx context.Context, cfg *config.Config, dockerInfo *docker.DockerInfo) []doctor.Result {
+	rootlessPodman := dockerInfo.Engine == "podman" && dockerInfo.Rootless
+
+	var results []doctor.Result
+	for _, serviceName := range cfg.Services {
+		service, exists := services.GetService(serviceName)
+		if !exists || service.DataOwner == "" {
+			continue
+		}
+
+		for _, dir := range service.DataDirs() {
+			path := filepath.Join(cfg.DataPath, dir)
+
+			var owner string
+			if utils.FileExists(path) {
+				if rootlessPodman {
+					owner, _ = docker.OwnerInUserNamespace(ctx, path)
+				} else if uid, gid, err := host.FileOwner(path); err == nil {
+					owner = strconv.Itoa(uid) + ":" + strconv.Itoa(gid)
+				}
+			}
+			results = append(results, doctor.CheckDataOwner(path, owner, service.DataOwner, rootlessPodman))
+		}
+	}
+	return results
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -43,7 +44,7 @@ func runDown(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check Docker
-	dockerInfo, err := docker.CheckDocker()
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("failed to check Docker: %w", err)
 	}
@@ -55,7 +56,7 @@ func runDown(cmd *cobra.Command, args []string) error {
 	}
 
 	// Warning for volume removal
-	if removeVolumesFlag {
+	if removeVolumesFlag && !runner.Recording() {
 		fmt.Println("⚠️  WARNING: This will permanently delete all data in volumes!")
 		if !utils.PromptConfirm("Are you sure you want to continue?") {
 			fmt.Println("Operation cancelled.")
@@ -66,25 +67,30 @@ func runDown(cmd *cobra.Command, args []string) error {
 	fmt.Println("🛑 Stopping services...")
 
 	// Stop services
-	if err := docker.ComposeDown(composePath); err != nil {
+	if err := docker.ComposeDown(cmd.Context(), composePath); err != nil {
 		return fmt.Errorf("failed to stop services: %w", err)
 	}
 
 	// Handle additional cleanup
 	if removeVolumesFlag {
 		fmt.Println("🗑️  Removing volumes...")
-		if err := docker.RunCompose("-f", composePath, "down", "-v"); err != nil {
+		if err := docker.RunCompose(cmd.Context(), "-f", composePath, "down", "-v"); err != nil {
 			return fmt.Errorf("failed to remove volumes: %w", err)
 		}
 	}
 
 	if removeImagesFlag {
 		fmt.Println("🗑️  Removing images...")
-		if err := docker.RunCompose("-f", composePath, "down", "--rmi", "all"); err != nil {
+		if err := docker.RunCompose(cmd.Context(), "-f", composePath, "down", "--rmi", "all"); err != nil {
 			return fmt.Errorf("failed to remove images: %w", err)
 		}
 	}
 
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no containers were stopped.")
+		return nil
+	}
+
 	fmt.Println("✅ Services stopped successfully!")
 
 	if removeVolumesFlag {
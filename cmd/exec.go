@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] <service> -- <command> [args...]",
+	Short: "Run a command in the container of a service",
+	Long: `Run a command in the container of a running service. It gets a terminal when
+dockenv runs in one, and otherwise reads from standard input, so that data can
+be piped in. dockenv exits with the command's exit status.
+
+Examples:
+  dockenv exec postgres -- pg_dump -U dockenv dockenv > dump.sql
+  dockenv exec redis -- redis-cli INFO memory
+  dockenv exec mysql -- bash
+  dockenv exec -T mongodb -- mongosh --quiet --eval 'db.version()'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// Flags after the service belong to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().BoolVarP(&noTTYFlag, "no-tty", "T", false, "Do not allocate a terminal, e.g. when piping output")
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	serviceName, command := args[0], trimDashes(args[1:])
+	if len(command) == 0 {
+		return fmt.Errorf("no command given; use 'dockenv exec %s -- <command>'", serviceName)
+	}
+
+	_, service, err := loadExecService(cmd, serviceName)
+	if err != nil {
+		return err
+	}
+
+	err = docker.Exec(cmd.Context(), service.ContainerName(), execTTY(), nil, command...)
+	return commandExit(cmd, err)
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/bundle"
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "Manage the images of the configured services",
+	Long: `Fetch the images of the configured services ahead of time, or move them
+to machines without registry access as a single bundle file.
+
+Examples:
+  dockenv images pull                  # Pre-fetch every image
+  dockenv images save services.tar     # Export the images with a manifest
+  dockenv images load services.tar     # Import them on an offline machine`,
+}
+
+var pullImagesCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Pull every image of the generated Compose file",
+	Long: `Pull every image of the generated Compose file. Images locked in
+dockenv.lock are pulled by digest.`,
+	Args: cobra.NoArgs,
+	RunE: runPullImages,
+}
+
+var saveImagesCmd = &cobra.Command{
+	Use:   "save <bundle.tar>",
+	Short: "Export the images into a bundle",
+	Long: `Export every image of the generated Compose file into a single bundle,
+together with a manifest of the configuration it was made for. The images
+must be present locally; run 'dockenv images pull' first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSaveImages,
+}
+
+var loadImagesCmd = &cobra.Command{
+	Use:   "load <bundle.tar>",
+	Short: "Import the images of a bundle",
+	Long: `Import the images of a bundle written by 'dockenv images save' and verify
+that every image the generated Compose file needs is now present, so that
+'dockenv up' does not have to pull anything.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLoadImages,
+}
+
+var pullImagesFrozenFlag bool
+
+func init() {
+	rootCmd.AddCommand(imagesCmd)
+
+	imagesCmd.AddCommand(pullImagesCmd)
+	imagesCmd.AddCommand(saveImagesCmd)
+	imagesCmd.AddCommand(loadImagesCmd)
+
+	pullImagesCmd.Flags().BoolVar(&pullImagesFrozenFlag, "frozen", false, "Fail when the lock file is missing or does not match the configuration")
+}
+
+// requireEngine fails unless the container engine and Compose are usable.
+func requireEngine(cmd *cobra.Command) error {
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to check Docker: %w", err)
+	}
+
+	if !dockerInfo.IsReady() {
+		fmt.Println("❌ Docker setup incomplete:")
+		fmt.Println(dockerInfo.GetInstallInstructions())
+		return fmt.Errorf("docker setup required")
+	}
+	return nil
+}
+
+// requiredImages returns the images of the generated Compose file and the
+// helper image dockenv runs for data directory work, which an offline
+// machine needs as well.
+func requiredImages() ([]string, error) {
+	composePath := config.GetComposePath()
+	if !utils.FileExists(composePath) {
+		fmt.Println("❌ No Docker Compose file found.")
+		fmt.Println("   Run 'dockenv init' first to set up your environment.")
+		return nil, fmt.Errorf("docker Compose file not found")
+	}
+
+	images, err := docker.ComposeImages(composePath)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.Contains(images, docker.HelperImage) {
+		images = append(images, docker.HelperImage)
+	}
+	return images, nil
+}
+
+func runPullImages(cmd *cobra.Command, args []string) error {
+	images, err := requiredImages()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkLock(cmd, cfg, pullImagesFrozenFlag); err != nil {
+		return err
+	}
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	fmt.Printf("📥 Pulling %d image(s): %s\n", len(images), strings.Join(images, ", "))
+	if err := docker.PullImages(cmd.Context(), config.GetComposePath()); err != nil {
+		return fmt.Errorf("failed to pull images: %w", err)
+	}
+	if err := docker.PullImage(cmd.Context(), docker.HelperImage); err != nil {
+		return err
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no images were pulled.")
+		return nil
+	}
+
+	fmt.Println("✅ All images are available locally.")
+	return nil
+}
+
+func runSaveImages(cmd *cobra.Command, args []string) error {
+	bundlePath := args[0]
+
+	images, err := requiredImages()
+	if err != nil {
+		return err
+	}
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	missing, err := docker.MissingImages(cmd.Context(), images)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		fmt.Printf("❌ Not available locally: %s\n", strings.Join(missing, ", "))
+		fmt.Println("   Run 'dockenv images pull' first.")
+		return fmt.Errorf("%d image(s) missing", len(missing))
+	}
+
+	composeData, err := os.ReadFile(config.GetComposePath())
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manifest := &bundle.Manifest{
+		Version:       bundle.ManifestVersion,
+		Created:       time.Now().UTC().Truncate(time.Second),
+		Project:       config.GetProjectName(),
+		Services:      cfg.Services,
+		Images:        images,
+		ComposeDigest: bundle.Digest(composeData),
+	}
+
+	fmt.Printf("📦 Saving %d image(s) to %s...\n", len(images), bundlePath)
+
+	// The engine's archive is staged next to the bundle, which is then
+	// renamed into place so that an interrupted save leaves no partial file
+	dir := filepath.Dir(bundlePath)
+	imagesFile, err := os.CreateTemp(dir, ".dockenv-images-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create image archive: %w", err)
+	}
+	imagesFile.Close()
+	defer os.Remove(imagesFile.Name())
+
+	if err := docker.SaveImages(cmd.Context(), imagesFile.Name(), images); err != nil {
+		return err
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no bundle was written.")
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(dir, ".dockenv-bundle-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := bundle.Write(tmp, manifest, imagesFile.Name()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), bundlePath); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	fmt.Println("✅ Bundle saved!")
+	fmt.Printf("   Images: %s\n", strings.Join(images, ", "))
+	fmt.Printf("   Load it with: dockenv images load %s\n", bundlePath)
+	return nil
+}
+
+func runLoadImages(cmd *cobra.Command, args []string) error {
+	bundlePath := args[0]
+
+	images, err := requiredImages()
+	if err != nil {
+		return err
+	}
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	imagesFile, err := os.CreateTemp("", "dockenv-images-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create image archive: %w", err)
+	}
+	imagesFile.Close()
+	defer os.Remove(imagesFile.Name())
+
+	manifest, err := bundle.Read(file, imagesFile.Name())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", bundlePath, err)
+	}
+
+	composeData, err := os.ReadFile(config.GetComposePath())
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+	if manifest.ComposeDigest != bundle.Digest(composeData) {
+		fmt.Printf("⚠️  The bundle was made for a different configuration (project %s, services %s).\n",
+			manifest.Project, strings.Join(manifest.Services, ", "))
+	}
+	if missing := manifest.Missing(images); len(missing) > 0 {
+		fmt.Printf("⚠️  Not in the bundle: %s\n", strings.Join(missing, ", "))
+	}
+
+	fmt.Printf("📦 Loading %d image(s) from %s...\n", len(manifest.Images), bundlePath)
+	if err := docker.LoadImages(cmd.Context(), imagesFile.Name()); err != nil {
+		return err
+	}
+
+	missing, err := docker.MissingImages(cmd.Context(), images)
+	if err != nil {
+		return err
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no images were loaded.")
+		return nil
+	}
+
+	if len(missing) > 0 {
+		fmt.Printf("❌ Still missing: %s\n", strings.Join(missing, ", "))
+		fmt.Println("   'dockenv up' will try to pull them. Save a new bundle from the current")
+		fmt.Println("   configuration with 'dockenv images save'.")
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d image(s) missing", len(missing))
+	}
+
+	fmt.Println("✅ All required images are present.")
+	fmt.Println("   Start the services with: dockenv up")
+	return nil
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+	"github.com/mohammed-bageri/dockenv/internal/importer"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var importDataCmd = &cobra.Command{
+	Use:   "import-data <service> <file>",
+	Short: "Load a dump file into a running service",
+	Long: `Load a dump file into a running service with the service's own restore tool.
+The format is detected from the file's contents, and gzip-compressed files are
+decompressed on the fly:
+
+  mysql      SQL
+  postgres   SQL, custom format (pg_dump -Fc)
+  mongodb    archive (mongodump --archive)
+  redis      RDB, replacing all current data
+
+SQL is loaded into the configured database, or the one given with --database,
+which is created first if it does not exist. With anonymize rules in
+dockenv.yaml, MySQL and PostgreSQL dumps have their personal data replaced
+while they stream; see 'dockenv anonymize'.
+
+Examples:
+  dockenv import-data postgres prod-anon.sql.gz
+  dockenv import-data postgres shop.dump --database shop
+  dockenv import-data mongodb shop.archive
+  dockenv import-data redis dump.rdb`,
+	Args: cobra.ExactArgs(2),
+	RunE: runImportData,
+}
+
+var (
+	importDatabaseFlag string
+	importForceFlag    bool
+)
+
+func init() {
+	rootCmd.AddCommand(importDataCmd)
+
+	importDataCmd.Flags().StringVarP(&importDatabaseFlag, "database", "d", "", "Database to load SQL into (default: the configured one)")
+	importDataCmd.Flags().BoolVarP(&importForceFlag, "force", "f", false, "Replace data without confirmation")
+}
+
+func runImportData(cmd *cobra.Command, args []string) error {
+	serviceName, file := args[0], args[1]
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("failed to open dump: %w", err)
+	}
+
+	cfg, service, err := loadExecService(cmd, serviceName)
+	if err != nil {
+		return err
+	}
+
+	method, exists := importer.LookupMethod(serviceName)
+	if !exists {
+		return fmt.Errorf("%s cannot import dumps", service.DisplayName)
+	}
+
+	var progress *importer.Progress
+	if host.IsTerminal(os.Stderr) && !runner.Recording() {
+		progress = importer.NewProgress(os.Stderr, info.Size())
+	}
+	dump, err := importer.Open(file, progress)
+	if err != nil {
+		return err
+	}
+	defer dump.Close()
+
+	_, canLoad := method.Scripts[dump.Format]
+	_, canReplace := method.Replace[dump.Format]
+	if !canLoad && !canReplace {
+		var supported []string
+		for _, format := range method.Formats() {
+			supported = append(supported, importer.Descriptions[format])
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s cannot load a %s dump (supported: %s)", service.DisplayName,
+			importer.Descriptions[dump.Format], strings.Join(supported, ", "))
+	}
+
+	var source io.Reader = dump
+	anonymizer, err := newAnonymizer(cfg, serviceName, dump, true)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	if anonymizer != nil {
+		source = anonymizer
+	}
+
+	database, err := importDatabase(cfg, service, method)
+	if err != nil {
+		return err
+	}
+
+	target := service.DisplayName
+	if database != "" {
+		target += " database " + database
+	}
+	fmt.Printf("📥 Importing %s (%s, %s) into %s\n", filepath.Base(file), dump.Description(),
+		importer.FormatBytes(dump.Size), target)
+	if anonymizer != nil {
+		fmt.Printf("🔒 Anonymizing with %d rules from %s\n", len(anonymizer.Rules()), config.ConfigFileName)
+	}
+
+	if canReplace {
+		err = replaceData(cmd, service, dump, method.Replace[dump.Format])
+	} else {
+		err = loadDump(cmd, service, source, method.Scripts[dump.Format], method, database)
+	}
+	if progress != nil {
+		progress.Done()
+	}
+	if err != nil {
+		return err
+	}
+
+	if anonymizer != nil {
+		// A dry run loads nothing, but still checks the rules on the dump
+		if runner.Recording() {
+			if _, err := io.Copy(io.Discard, anonymizer); err != nil {
+				return fmt.Errorf("failed to anonymize dump: %w", err)
+			}
+		}
+		printAnonymizeReport(os.Stdout, anonymizer)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: nothing was imported.")
+		return nil
+	}
+
+	fmt.Printf("✅ Imported %s into %s!\n", filepath.Base(file), target)
+	return nil
+}
+
+// importDatabase returns the database to load a dump into: the one given
+// with --database, or the configured one. It is empty for services without
+// databases.
+func importDatabase(cfg *config.Config, service services.Service, method importer.Method) (string, error) {
+	if method.DatabaseVar == "" {
+		if importDatabaseFlag != "" {
+			return "", fmt.Errorf("%s dumps name their own databases; --database is not supported", service.DisplayName)
+		}
+		return "", nil
+	}
+
+	database := importDatabaseFlag
+	if database == "" {
+		database = cfg.Env[method.DatabaseVar]
+	}
+	if database == "" {
+		database = service.EnvVars[method.DatabaseVar]
+	}
+	if err := importer.ValidateDatabase(database); err != nil {
+		return "", err
+	}
+	return database, nil
+}
+
+// loadDump streams a dump into the restore tool of a running service,
+// which script runs, creating the database first.
+func loadDump(cmd *cobra.Command, service services.Service, dump io.Reader, script string, method importer.Method, database string) error {
+	container := service.ContainerName()
+
+	if database != "" && method.CreateDatabase != "" {
+		if err := docker.ExecScript(cmd.Context(), container, nil, os.Stdout, method.CreateDatabase, database); err != nil {
+			return fmt.Errorf("failed to create database %s: %w", database, err)
+		}
+	}
+
+	if err := docker.ExecScript(cmd.Context(), container, dump, os.Stdout, script, database); err != nil {
+		return fmt.Errorf("failed to import into %s: %w", service.Name, err)
+	}
+	return nil
+}
+
+// replaceData replaces the data file of a service with a dump while the
+// service is stopped.
+func replaceData(cmd *cobra.Command, service services.Service, dump *importer.Dump, target string) (err error) {
+	if !importForceFlag && !runner.Recording() {
+		fmt.Printf("⚠️  WARNING: This replaces all current %s data!\n", service.DisplayName)
+		if !utils.PromptConfirm("Are you sure you want to continue?") {
+			return fmt.Errorf("import cancelled")
+		}
+	}
+
+	// The engine needs the size up front, which a compressed dump only
+	// tells once it is decompressed
+	var source io.Reader = dump
+	size := dump.Size
+	if dump.Compressed {
+		spool, err := os.CreateTemp("", "dockenv-import-*")
+		if err != nil {
+			return fmt.Errorf("failed to decompress dump: %w", err)
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+
+		if size, err = io.Copy(spool, dump); err != nil {
+			return fmt.Errorf("failed to decompress dump: %w", err)
+		}
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to decompress dump: %w", err)
+		}
+		source = spool
+	}
+
+	composePath := config.GetComposePath()
+	if err := docker.ComposeStop(cmd.Context(), composePath, service.Name); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", service.Name, err)
+	}
+	// Start the service again even if the copy failed, with its old data
+	defer func() {
+		ctx, cancel := cleanupContext(cmd)
+		defer cancel()
+		if startErr := docker.ComposeStart(ctx, composePath, service.Name); startErr != nil && err == nil {
+			err = fmt.Errorf("failed to start %s: %w", service.Name, startErr)
+		}
+	}()
+
+	return docker.CopyStreamToContainer(cmd.Context(), source, size, service.ContainerName(), target)
+}
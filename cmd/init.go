@@ -2,13 +2,11 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/docker"
 	"github.com/mohammed-bageri/dockenv/internal/services"
-	"github.com/mohammed-bageri/dockenv/internal/templates"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
 
 	"github.com/manifoldco/promptui"
@@ -35,7 +33,11 @@ var (
 	servicesFlag   []string
 	autoDetectFlag bool
 	portFlag       []string
+	memoryFlag     []string
+	cpusFlag       []string
 	dataPathFlag   string
+	exposeFlag     bool
+	autoPortFlag   bool
 )
 
 func init() {
@@ -44,8 +46,12 @@ func init() {
 	initCmd.Flags().StringVar(&profileFlag, "profile", "", "Use a predefined service profile")
 	initCmd.Flags().StringSliceVar(&servicesFlag, "services", []string{}, "Specify services directly")
 	initCmd.Flags().BoolVar(&autoDetectFlag, "auto-detect", false, "Auto-detect project type and suggest services")
-	initCmd.Flags().StringSliceVar(&portFlag, "port", []string{}, "Custom ports in format service:port")
+	initCmd.Flags().StringSliceVar(&portFlag, "port", []string{}, "Custom ports in format service[.name]:port")
+	initCmd.Flags().StringSliceVar(&memoryFlag, "memory", []string{}, "Memory limits in format service:size (e.g. mysql:1g)")
+	initCmd.Flags().StringSliceVar(&cpusFlag, "cpus", []string{}, "CPU limits in format service:cpus (e.g. mysql:1.5)")
 	initCmd.Flags().StringVar(&dataPathFlag, "data-path", "", "Custom data directory path")
+	initCmd.Flags().BoolVar(&exposeFlag, "expose", false, "Publish ports on all interfaces instead of 127.0.0.1 (LAN access)")
+	initCmd.Flags().BoolVar(&autoPortFlag, "auto-port", false, "Move ports that are already in use to the next free port")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -54,7 +60,7 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Check Docker installation
-	dockerInfo, err := docker.CheckDocker()
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("failed to check Docker: %w", err)
 	}
@@ -83,6 +89,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 		cfg.DataPath = dataPathFlag
 	}
 
+	if exposeFlag {
+		cfg.BindAddress = config.ExposeBindAddress
+	}
+
 	var selectedServices []string
 
 	// Handle different initialization modes
@@ -141,39 +151,37 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Handle resource limits
+	if err := parseResourceFlags(cfg, memoryFlag, cpusFlag, cfg.Services); err != nil {
+		return err
+	}
+
 	// Set default ports and env vars
 	for _, serviceName := range selectedServices {
-		service, _ := services.GetService(serviceName)
-
-		if cfg.Ports[serviceName] == 0 {
-			cfg.Ports[serviceName] = service.DefaultPort
-		}
-
-		for key, value := range service.EnvVars {
-			if cfg.Env[key] == "" {
-				cfg.Env[key] = value
-			}
-		}
+		applyServiceDefaults(cfg, serviceName)
 	}
 
-	// Create data directory
-	if err := config.EnsureDataDir(); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
+	if _, err := checkPortConflicts(cmd.Context(), cfg, cfg.Services, autoPortFlag); err != nil {
+		return err
 	}
 
-	// Save configuration
-	if err := utils.SaveConfig(cfg); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	if dryRunFlag {
+		fmt.Println()
+		return printPlan(cfg)
 	}
 
-	// Generate Docker Compose file
-	if err := templates.GenerateDockerComposeEmbedded(cfg); err != nil {
-		return fmt.Errorf("failed to generate Docker Compose file: %w", err)
+	// Create data directory, unless the data lives in volumes or on the
+	// machine of a remote engine
+	remote := docker.CurrentContext().Remote()
+	if !remote && !cfg.UsesVolumes(remote) {
+		if err := config.EnsureDataDir(); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
 	}
 
-	// Generate .env file
-	if err := utils.CreateEnvFile(cfg.Env); err != nil {
-		return fmt.Errorf("failed to create .env file: %w", err)
+	// Save the configuration and generate the Docker Compose and .env files
+	if err := writeProjectFiles(cfg); err != nil {
+		return err
 	}
 
 	fmt.Println()
@@ -181,7 +189,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Printf("   Services: %s\n", strings.Join(cfg.Services, ", "))
 	fmt.Printf("   Config: %s\n", config.GetConfigPath())
 	fmt.Printf("   Compose: %s\n", config.GetComposePath())
-	fmt.Printf("   Data: %s\n", cfg.DataPath)
+	if cfg.UsesVolumes(remote) {
+		fmt.Println("   Data: named volumes")
+	} else {
+		fmt.Printf("   Data: %s\n", cfg.DataPath)
+	}
 	fmt.Printf("   Environment: %s\n", config.EnvFileName)
 	fmt.Println()
 	fmt.Println("Next steps:")
@@ -199,7 +211,7 @@ func selectServicesInteractive() ([]string, error) {
 	serviceMap := make(map[string]string)
 
 	for name, service := range services.AvailableServices {
-		option := fmt.Sprintf("%s - %s (port %d)", service.DisplayName, service.Description, service.DefaultPort)
+		option := fmt.Sprintf("%s - %s (port %d)", service.DisplayName, service.Description, service.PrimaryPort().DefaultPort)
 		serviceOptions = append(serviceOptions, option)
 		serviceMap[option] = name
 	}
@@ -241,24 +253,19 @@ func selectServicesInteractive() ([]string, error) {
 
 func parseCustomPorts(cfg *config.Config) error {
 	for _, portSpec := range portFlag {
-		parts := strings.Split(portSpec, ":")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid port specification: %s (expected format: service:port)", portSpec)
-		}
-
-		serviceName := parts[0]
-		portStr := parts[1]
-
-		port, err := strconv.Atoi(portStr)
+		serviceName, portName, port, err := services.ParsePortSpec(portSpec)
 		if err != nil {
-			return fmt.Errorf("invalid port number: %s", portStr)
+			return err
 		}
 
 		if !utils.Contains(cfg.Services, serviceName) {
 			return fmt.Errorf("service %s not in selected services", serviceName)
 		}
 
-		cfg.Ports[serviceName] = port
+		if cfg.Ports[serviceName] == nil {
+			cfg.Ports[serviceName] = make(config.ServicePorts)
+		}
+		cfg.Ports[serviceName][portName] = port
 	}
 
 	return nil
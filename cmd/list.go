@@ -88,15 +88,10 @@ func showCurrentServices() error {
 			continue
 		}
 
-		port := cfg.Ports[serviceName]
-		if port == 0 {
-			port = service.DefaultPort
-		}
-
-		fmt.Printf("   %-12s %-20s port %d\n",
+		fmt.Printf("   %-12s %-20s %s\n",
 			service.DisplayName,
 			service.Description,
-			port)
+			describePorts(service, service.HostPorts(cfg.Ports[serviceName])))
 	}
 
 	return nil
@@ -107,10 +102,10 @@ func showAvailableServices() {
 
 	for _, serviceName := range services.GetServiceNames() {
 		service, _ := services.GetService(serviceName)
-		fmt.Printf("   %-12s %-30s (default port %d)\n",
+		fmt.Printf("   %-12s %-30s (default %s)\n",
 			serviceName,
 			service.Description,
-			service.DefaultPort)
+			describePorts(service, service.HostPorts(nil)))
 	}
 
 	fmt.Println()
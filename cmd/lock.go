@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/lock"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Manage the image lock file",
+	Long: `Pin the images of the configured services to the digests they resolve to.
+
+The lock file, dockenv.lock, is kept next to the configuration. While it
+exists the generated Docker Compose file references the locked digests
+instead of floating tags such as 'latest', so everyone starting the project
+runs the same image versions.
+
+Use 'dockenv up --frozen' in CI to fail when the lock file is missing or does
+not match the configured services.`,
+}
+
+var updateLockCmd = &cobra.Command{
+	Use:   "update [service...]",
+	Short: "Resolve the images of services and record their digests",
+	Long: `Pull the images of the given services, or of all configured services,
+record the digests they resolve to in the lock file and regenerate the Docker
+Compose file to use them.
+
+Examples:
+  dockenv lock update          # Pin every service
+  dockenv lock update kafka    # Move only Kafka to the current tag`,
+	RunE: runUpdateLock,
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+
+	lockCmd.AddCommand(updateLockCmd)
+}
+
+func runUpdateLock(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for _, serviceName := range args {
+		if !utils.Contains(cfg.Services, serviceName) {
+			return fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+		}
+	}
+	if len(cfg.Services) == 0 {
+		fmt.Println("📋 No services configured.")
+		fmt.Println("   Run 'dockenv init' to set up services.")
+		return nil
+	}
+
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	lockPath := config.GetLockPath()
+	lockFile, err := lock.Load(lockPath)
+	if os.IsNotExist(err) {
+		lockFile = lock.New()
+	} else if err != nil {
+		return err
+	}
+
+	required, err := serviceImages(cfg)
+	if err != nil {
+		return err
+	}
+
+	servicesToUpdate := args
+	if len(servicesToUpdate) == 0 {
+		servicesToUpdate = cfg.Services
+
+		// A full update also drops services that are no longer configured
+		for serviceName := range lockFile.Services {
+			if !utils.Contains(cfg.Services, serviceName) {
+				delete(lockFile.Services, serviceName)
+			}
+		}
+	}
+
+	fmt.Printf("🔒 Resolving images of: %s\n", strings.Join(servicesToUpdate, ", "))
+	if err := resolveImages(cmd.Context(), lockFile, required, servicesToUpdate); err != nil {
+		return err
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: the lock file was not written.")
+		return nil
+	}
+
+	if err := lockFile.Save(lockPath); err != nil {
+		return err
+	}
+	env, err := templateEnvironment(cfg)
+	if err != nil {
+		return err
+	}
+	if err := templates.GenerateDockerComposeEmbedded(cfg, env); err != nil {
+		return fmt.Errorf("failed to update Docker Compose file: %w", err)
+	}
+
+	fmt.Println("✅ Lock file updated!")
+	fmt.Printf("   Lock file: %s\n", lockPath)
+	for _, serviceName := range servicesToUpdate {
+		for _, image := range lockFile.Services[serviceName] {
+			fmt.Printf("   %-14s %s\n", serviceName, lock.Pin(image.Image, image.Digest))
+		}
+	}
+	fmt.Println("\nApply the pinned images with: dockenv up")
+
+	return nil
+}
+
+// resolveImages pulls the images of the given services and records the
+// digests they resolve to in the lock file.
+func resolveImages(ctx context.Context, lockFile *lock.File, required map[string][]string, serviceNames []string) error {
+	for _, serviceName := range serviceNames {
+		var locked []lock.Image
+		for _, image := range required[serviceName] {
+			if err := docker.PullImage(ctx, image); err != nil {
+				return err
+			}
+			digest, err := docker.ImageDigest(ctx, image)
+			if runner.Recording() {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			locked = append(locked, lock.Image{Image: image, Digest: digest})
+		}
+		lockFile.Services[serviceName] = locked
+	}
+	return nil
+}
+
+// checkLock compares the lock file with the configured services. With
+// frozen a missing or stale lock file is an error; otherwise a stale one is
+// only reported, and unlocked images keep their floating tags.
+func checkLock(cmd *cobra.Command, cfg *config.Config, frozen bool) error {
+	lockPath := config.GetLockPath()
+	lockFile, err := lock.Load(lockPath)
+	if os.IsNotExist(err) {
+		if frozen {
+			fmt.Printf("❌ No lock file found at %s.\n", lockPath)
+			fmt.Println("   Create it with 'dockenv lock update'.")
+			cmd.SilenceUsage = true
+			return fmt.Errorf("lock file not found")
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	required, err := serviceImages(cfg)
+	if err != nil {
+		return err
+	}
+
+	stale := lockFile.Stale(required)
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if frozen {
+		fmt.Printf("❌ The lock file does not match the configuration for: %s\n", strings.Join(stale, ", "))
+		fmt.Println("   Update it with 'dockenv lock update'.")
+		cmd.SilenceUsage = true
+		return fmt.Errorf("lock file is stale")
+	}
+
+	fmt.Printf("⚠️  Not locked to the configured images: %s\n", strings.Join(stale, ", "))
+	fmt.Println("   Update the lock file with 'dockenv lock update'.")
+	return nil
+}
+
+// serviceImages returns the images each configured service needs, as
+// written in its template and not pinned to the lock file.
+func serviceImages(cfg *config.Config) (map[string][]string, error) {
+	env, err := templateEnvironment(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make(map[string][]string, len(cfg.Services))
+	for _, serviceName := range cfg.Services {
+		content, err := templates.RenderService(cfg, env, serviceName)
+		if err != nil {
+			return nil, err
+		}
+
+		serviceImages, err := docker.ParseComposeImages(append([]byte("services:\n"), content...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read images of %s: %w", serviceName, err)
+		}
+		images[serviceName] = serviceImages
+	}
+	return images, nil
+}
+
+// unlockServices drops removed services from the lock file, if there is one.
+func unlockServices(serviceNames []string) error {
+	lockPath := config.GetLockPath()
+	lockFile, err := lock.Load(lockPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, serviceName := range serviceNames {
+		delete(lockFile.Services, serviceName)
+	}
+	return lockFile.Save(lockPath)
+}
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -46,7 +48,7 @@ func runLogs(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check Docker
-	dockerInfo, err := docker.CheckDocker()
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("failed to check Docker: %w", err)
 	}
@@ -85,14 +87,16 @@ func runLogs(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Show logs
-	if followLogsFlag {
-		return docker.ComposeLogs(composePath, args...)
-	} else {
-		// For non-follow mode, use docker-compose logs with tail
-		logArgs := []string{"-f", composePath, "logs", "--tail", tailFlag}
-		if len(args) > 0 {
-			logArgs = append(logArgs, args...)
-		}
-		return docker.RunCompose(logArgs...)
+	// Following shows the complete logs unless --tail is given explicitly
+	tail := tailFlag
+	if followLogsFlag && !cmd.Flags().Changed("tail") {
+		tail = ""
+	}
+	err = docker.ComposeLogs(cmd.Context(), composePath, followLogsFlag, tail, args...)
+
+	// Ctrl+C is how following ends
+	if followLogsFlag && errors.As(err, new(*runner.InterruptedError)) {
+		return nil
 	}
+	return err
 }
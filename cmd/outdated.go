@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Show services with newer image versions",
+	Long: `Compare the image tag of each configured service with the newer tags
+the catalog knows about and the ones of images already present locally.
+
+WANTED is the newest tag within the current major version, which
+'dockenv upgrade <service>' moves to. LATEST is the newest tag overall.`,
+	Args: cobra.NoArgs,
+	RunE: runOutdated,
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+}
+
+func runOutdated(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if len(cfg.Services) == 0 {
+		fmt.Println("📋 No services configured.")
+		fmt.Println("   Run 'dockenv init' to set up services.")
+		return nil
+	}
+
+	fmt.Println("📦 Service versions:")
+	fmt.Printf("   %-14s %-18s %-24s %s\n", "SERVICE", "CURRENT", "WANTED", "LATEST")
+
+	var outdated, floating int
+	for _, serviceName := range cfg.Services {
+		service, exists := services.GetService(serviceName)
+		if !exists {
+			continue
+		}
+
+		current := service.ResolveVersion(cfg.Versions[serviceName])
+		tags, local := versionCandidates(cmd.Context(), service)
+
+		if _, ok := services.ParseVersion(current); !ok {
+			floating++
+			latest := "-"
+			if len(service.Versions) > 0 {
+				latest = service.Versions[len(service.Versions)-1]
+			}
+			fmt.Printf("   %-14s %-18s %-24s %s\n", serviceName, current, "(floating)", latest)
+			continue
+		}
+
+		wanted, latest := services.LatestVersions(current, tags)
+		if latest == "" {
+			fmt.Printf("   %-14s %-18s %-24s %s\n", serviceName, current, "up to date", "-")
+			continue
+		}
+
+		outdated++
+		wantedColumn := "-"
+		if wanted != "" {
+			wantedColumn = wanted
+			if local[wanted] {
+				wantedColumn += " (local)"
+			}
+		}
+		if local[latest] {
+			latest += " (local)"
+		}
+		fmt.Printf("   %-14s %-18s %-24s %s\n", serviceName, current, wantedColumn, latest)
+	}
+
+	fmt.Println()
+	if floating > 0 {
+		fmt.Println("ℹ️  Floating tags follow the registry; pin them with 'dockenv lock update'.")
+	}
+	if outdated == 0 {
+		fmt.Println("✅ All versioned services are on the newest known tag.")
+		return nil
+	}
+
+	fmt.Println("Next steps:")
+	fmt.Println("  dockenv upgrade <service>          # Move to the WANTED tag")
+	fmt.Println("  dockenv upgrade <service> --major  # Move to the LATEST tag")
+	return nil
+}
+
+// versionCandidates returns the tags of a service the catalog knows and the
+// ones of its images present locally, which are also returned as a set.
+// Without a usable engine only the catalog is consulted.
+func versionCandidates(ctx context.Context, service services.Service) ([]string, map[string]bool) {
+	tags := append([]string(nil), service.Versions...)
+	local := make(map[string]bool)
+	if service.Image == "" {
+		return tags, local
+	}
+
+	localTags, err := docker.LocalImageTags(ctx, service.Image)
+	if err != nil {
+		return tags, local
+	}
+
+	catalog := make(map[string]bool)
+	for _, tag := range service.Versions {
+		catalog[tag] = true
+	}
+	for _, tag := range localTags {
+		if !catalog[tag] {
+			local[tag] = true
+		}
+		tags = append(tags, tag)
+	}
+	return tags, local
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/plan"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes that would be applied to generated files",
+	Long: `Compute the configuration, Docker Compose and .env files in memory and
+print a unified diff against the files on disk, followed by the container
+actions (create, recreate, remove) that would follow. Nothing is written.
+
+Without flags, the plan shows what regenerating from the current
+configuration would change, e.g. after editing dockenv.yaml by hand.
+
+Examples:
+  dockenv plan                           # Diff the current configuration
+  dockenv plan --add mysql               # Preview adding MySQL
+  dockenv plan --add mysql --port mysql:3307
+  dockenv plan --remove redis            # Preview removing Redis`,
+	RunE: runPlan,
+}
+
+var (
+	planAddFlag    []string
+	planRemoveFlag []string
+	planPortFlag   []string
+)
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringSliceVar(&planAddFlag, "add", []string{}, "Services to add")
+	planCmd.Flags().StringSliceVar(&planRemoveFlag, "remove", []string{}, "Services to remove")
+	planCmd.Flags().StringSliceVar(&planPortFlag, "port", []string{}, "Custom ports for added services in format service:port")
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	if err := services.ValidateServices(planAddFlag); err != nil {
+		return err
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var newServices []string
+	for _, serviceName := range planAddFlag {
+		if !utils.Contains(cfg.Services, serviceName) && !utils.Contains(newServices, serviceName) {
+			newServices = append(newServices, serviceName)
+		}
+	}
+
+	customPorts, err := parseAddPorts(planPortFlag, newServices)
+	if err != nil {
+		return err
+	}
+	addServicesToConfig(cfg, newServices, customPorts)
+
+	var servicesToRemove []string
+	for _, serviceName := range planRemoveFlag {
+		if !utils.Contains(cfg.Services, serviceName) {
+			return fmt.Errorf("service '%s' not configured. Available services: %s", serviceName, strings.Join(cfg.Services, ", "))
+		}
+		servicesToRemove = append(servicesToRemove, serviceName)
+	}
+	removeServicesFromConfig(cfg, servicesToRemove)
+
+	return printPlan(cfg)
+}
+
+// printPlan renders the generated files for cfg and prints how they differ
+// from the files on disk without writing anything.
+func printPlan(cfg *config.Config) error {
+	env, err := templateEnvironment(cfg)
+	if err != nil {
+		return err
+	}
+
+	p, err := plan.Build(cfg, env)
+	if err != nil {
+		return fmt.Errorf("failed to build plan: %w", err)
+	}
+
+	p.Print(os.Stdout)
+	return nil
+}
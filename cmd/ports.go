@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+	"github.com/mohammed-bageri/dockenv/internal/lock"
+	"github.com/mohammed-bageri/dockenv/internal/seed"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+)
+
+// describePorts formats the host ports of a service for display, e.g.
+// "port 3306" or "ports amqp 5672, management 15672".
+func describePorts(service services.Service, hostPorts map[string]int) string {
+	var parts []string
+	for _, spec := range service.Ports {
+		if port, exists := hostPorts[spec.Name]; exists {
+			parts = append(parts, fmt.Sprintf("%s %d", spec.Name, port))
+		}
+	}
+
+	if len(parts) == 1 {
+		return fmt.Sprintf("port %d", hostPorts[service.PrimaryPort().Name])
+	}
+	return "ports " + strings.Join(parts, ", ")
+}
+
+// warnExposedPorts prints a warning for every service whose ports are
+// published on a non-loopback address and thus reachable from the network.
+// Remote engines publish on all their interfaces by default.
+func warnExposedPorts(cfg *config.Config) {
+	engineHost := docker.CurrentContext().Hostname()
+	remote := engineHost != ""
+
+	var exposed []string
+	for _, serviceName := range cfg.Services {
+		address := cfg.GetPublishAddress(serviceName, remote)
+		if ip := net.ParseIP(address); ip != nil && ip.IsLoopback() {
+			continue
+		}
+		if remote {
+			address += " on " + engineHost
+		}
+		exposed = append(exposed, fmt.Sprintf("%s (%s)", serviceName, address))
+	}
+
+	if len(exposed) > 0 {
+		fmt.Printf("⚠️  Reachable from other machines: %s\n", strings.Join(exposed, ", "))
+		if remote {
+			fmt.Println("   These services use default credentials. Set 'bind_address' in the")
+			fmt.Println("   configuration to publish them on one address of the Docker host only.")
+		} else {
+			fmt.Println("   These services use default credentials. Remove 'bind_address' from the")
+			fmt.Println("   configuration to publish them on 127.0.0.1 only.")
+		}
+		fmt.Println()
+	}
+}
+
+// portConflict is a configured host port that something else already holds.
+type portConflict struct {
+	service  string
+	portName string
+	port     int
+	holder   string
+}
+
+// checkPortConflicts probes the host ports of the given services before they
+// are written or started. With autoPort, conflicting ports are moved to the
+// next free port and cfg is updated; it reports whether any port changed.
+func checkPortConflicts(ctx context.Context, cfg *config.Config, serviceNames []string, autoPort bool) (bool, error) {
+	conflicts, reserved := findPortConflicts(ctx, cfg, serviceNames)
+	if len(conflicts) == 0 {
+		return false, nil
+	}
+
+	if !autoPort {
+		fmt.Println("❌ Port conflicts:")
+		for _, c := range conflicts {
+			fmt.Printf("   %s\n", c)
+		}
+		fmt.Println("   Use --auto-port to pick free ports, or choose ports with --port.")
+		return false, fmt.Errorf("%d port conflict(s) found", len(conflicts))
+	}
+
+	remote := docker.CurrentContext().Remote()
+	for _, c := range conflicts {
+		port, err := findFreePort(cfg, c.service, c.port, reserved, remote)
+		if err != nil {
+			return false, fmt.Errorf("failed to find a free port for %s: %w", c.service, err)
+		}
+		reserved[port] = true
+
+		if cfg.Ports[c.service] == nil {
+			cfg.Ports[c.service] = make(config.ServicePorts)
+		}
+		cfg.Ports[c.service][c.portName] = port
+		applyServiceDefaults(cfg, c.service)
+
+		fmt.Printf("🔀 %s, using %d\n", c, port)
+	}
+
+	return true, nil
+}
+
+// findFreePort returns the next port after start for a service. Ports of a
+// remote engine cannot be probed from here, so only the reserved ports are
+// skipped for it.
+func findFreePort(cfg *config.Config, serviceName string, start int, reserved map[int]bool, remote bool) (int, error) {
+	if !remote {
+		return host.FindFreePort(cfg.GetBindAddress(serviceName), start, reserved)
+	}
+
+	for port := start + 1; port <= 65535; port++ {
+		if !reserved[port] {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found after %d", start)
+}
+
+func (c portConflict) String() string {
+	return fmt.Sprintf("%s %s port %d is in use by %s", c.service, c.portName, c.port, c.holder)
+}
+
+// findPortConflicts returns the host ports of the given services that are
+// already taken, along with every port that is reserved by the configuration
+// or by containers. Ports held by containers of other projects count as taken
+// even while those containers are stopped. For a remote engine only the
+// containers' ports are known; other processes there are not probed.
+func findPortConflicts(ctx context.Context, cfg *config.Config, serviceNames []string) ([]portConflict, map[int]bool) {
+	projectName := config.GetProjectName()
+	remote := docker.CurrentContext().Remote()
+
+	// Ports bound by this project's own containers are expected to be taken
+	ownPorts := make(map[int]bool)
+	containerPorts := make(map[int]string)
+	if published, err := docker.ListPublishedPorts(ctx); err == nil {
+		for _, p := range published {
+			if p.Project == projectName {
+				ownPorts[p.HostPort] = true
+			} else if p.Project != "" {
+				containerPorts[p.HostPort] = fmt.Sprintf("container %s (project %s)", p.Container, p.Project)
+			} else {
+				containerPorts[p.HostPort] = fmt.Sprintf("container %s", p.Container)
+			}
+		}
+	}
+
+	reserved := make(map[int]bool)
+	claimedBy := make(map[int]string)
+	for port := range containerPorts {
+		reserved[port] = true
+	}
+
+	var conflicts []portConflict
+	for _, serviceName := range cfg.Services {
+		service, exists := services.GetService(serviceName)
+		if !exists {
+			continue
+		}
+
+		checked := utils.Contains(serviceNames, serviceName)
+		hostPorts := service.HostPorts(cfg.Ports[serviceName])
+		for _, spec := range service.Ports {
+			port, exists := hostPorts[spec.Name]
+			if !exists {
+				continue
+			}
+			reserved[port] = true
+
+			holder := claimedBy[port]
+			if holder == "" {
+				claimedBy[port] = "dockenv service " + serviceName
+			}
+			if !checked {
+				continue
+			}
+
+			if holder == "" && !ownPorts[port] {
+				if holder = containerPorts[port]; holder == "" && !remote && host.PortInUse(cfg.GetBindAddress(serviceName), port) {
+					if holder = host.PortOwner(port); holder == "" {
+						holder = "another process"
+					}
+				}
+			}
+			if holder != "" {
+				conflicts = append(conflicts, portConflict{serviceName, spec.Name, port, holder})
+			}
+		}
+	}
+
+	return conflicts, reserved
+}
+
+// writeProjectFiles saves the configuration and regenerates the Docker
+// Compose and .env files from it. Env keys the saved configuration set and
+// cfg no longer does are dropped.
+func writeProjectFiles(cfg *config.Config) error {
+	previous, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := utils.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	env, err := templateEnvironment(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := templates.GenerateDockerComposeEmbedded(cfg, env); err != nil {
+		return fmt.Errorf("failed to update Docker Compose file: %w", err)
+	}
+	for _, legacyPath := range templates.LegacyTemplates(cfg) {
+		fmt.Fprintf(os.Stderr, "⚠️  %s is read from a deprecated location; move it to %s/\n", legacyPath, config.TemplatesDir)
+	}
+
+	if err := utils.CreateEnvFile(templates.EnvVars(cfg, env), utils.StaleEnvKeys(previous, cfg)); err != nil {
+		return fmt.Errorf("failed to update .env file: %w", err)
+	}
+
+	return nil
+}
+
+// templateEnvironment resolves what the generated files depend on besides
+// the configuration: the engine the services run on, the init scripts the
+// images run themselves and the lock file's digests.
+func templateEnvironment(cfg *config.Config) (templates.Environment, error) {
+	env := templates.Environment{
+		EngineHost: docker.CurrentContext().Hostname(),
+	}
+	// The runtime in use, which DOCKENV_RUNTIME may have chosen over the
+	// configured one
+	if rt, err := docker.CurrentRuntime(); err == nil {
+		env.Podman = rt.Engine() == "podman"
+	}
+	env.InitMounts = seed.InitMounts(cfg.Services, env.EngineHost != "")
+
+	lockFile, err := lock.Load(config.GetLockPath())
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return env, err
+	default:
+		env.Pins = lockFile.Pins()
+	}
+	return env, nil
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/services"
-	"github.com/mohammed-bageri/dockenv/internal/templates"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -21,7 +21,8 @@ This will update the Docker Compose file and stop the removed services.
 Examples:
   dockenv remove mysql         # Remove MySQL
   dockenv remove redis mongodb # Remove Redis and MongoDB
-  dockenv rm mysql             # Same as remove`,
+  dockenv rm mysql             # Same as remove
+  dockenv remove --dry-run redis  # Preview the changes only`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runRemove,
 }
@@ -68,6 +69,11 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("➖ Removing services: %s\n", strings.Join(servicesToRemove, ", "))
 
+	if dryRunFlag {
+		removeServicesFromConfig(cfg, servicesToRemove)
+		return printPlan(cfg)
+	}
+
 	// Confirmation prompt
 	if !removeForceFlag {
 		if removeVolumesRmFlag {
@@ -82,32 +88,16 @@ func runRemove(cmd *cobra.Command, args []string) error {
 	}
 
 	// Remove services from config
-	for _, serviceName := range servicesToRemove {
-		cfg.Services = utils.RemoveString(cfg.Services, serviceName)
-		delete(cfg.Ports, serviceName)
-
-		// Remove service-specific environment variables
-		service, exists := services.GetService(serviceName)
-		if exists {
-			for key := range service.EnvVars {
-				delete(cfg.Env, key)
-			}
-		}
-	}
-
-	// Save updated configuration
-	if err := utils.SaveConfig(cfg); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
-	}
+	removeServicesFromConfig(cfg, servicesToRemove)
 
-	// Update Docker Compose file
-	if err := templates.GenerateDockerComposeEmbedded(cfg); err != nil {
-		return fmt.Errorf("failed to update Docker Compose file: %w", err)
+	if err := unlockServices(servicesToRemove); err != nil {
+		return err
 	}
 
-	// Update .env file
-	if err := utils.CreateEnvFile(cfg.Env); err != nil {
-		return fmt.Errorf("failed to update .env file: %w", err)
+	// Save the configuration and update the Docker Compose and .env files,
+	// dropping the variables of the removed services
+	if err := writeProjectFiles(cfg); err != nil {
+		return err
 	}
 
 	fmt.Println("✅ Services removed successfully!")
@@ -130,3 +120,25 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func removeServicesFromConfig(cfg *config.Config, servicesToRemove []string) {
+	for _, serviceName := range servicesToRemove {
+		cfg.Services = utils.RemoveString(cfg.Services, serviceName)
+		delete(cfg.Ports, serviceName)
+		delete(cfg.Resources, serviceName)
+		delete(cfg.BindAddresses, serviceName)
+
+		// Remove service-specific environment variables
+		service, exists := services.GetService(serviceName)
+		if exists {
+			for key := range service.EnvVars {
+				delete(cfg.Env, key)
+			}
+			for _, spec := range service.Ports {
+				if spec.EnvVar != "" {
+					delete(cfg.Env, spec.EnvVar)
+				}
+			}
+		}
+	}
+}
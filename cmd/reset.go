@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// resetScript removes data directories, given relative to /data. It runs in
+// a container as root, as the directories belong to the users of the
+// service images.
+const resetScript = `set -e
+for dir in "$@"; do
+  rm -rf "/data/$dir"
+done`
+
+var resetCmd = &cobra.Command{
+	Use:   "reset <service>",
+	Short: "Wipe the data of a service and start it fresh",
+	Long: `Delete the data of one service and start it again with empty data, so that
+its image initializes it and its init scripts in .dockenv/init/<service>/ run
+as on the first start. Other services and their data are left untouched.
+
+The service's containers are removed and its data directories, or named
+volumes, are deleted. Take a snapshot or a backup first to keep the data.
+
+Examples:
+  dockenv reset postgres      # Start PostgreSQL with an empty database
+  dockenv reset kafka -f      # Reset Kafka and ZooKeeper without confirmation`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReset,
+}
+
+var resetForceFlag bool
+
+func init() {
+	rootCmd.AddCommand(resetCmd)
+
+	resetCmd.Flags().BoolVarP(&resetForceFlag, "force", "f", false, "Reset without confirmation")
+}
+
+func runReset(cmd *cobra.Command, args []string) error {
+	serviceName := args[0]
+
+	composePath := config.GetComposePath()
+	if !utils.FileExists(composePath) {
+		fmt.Println("❌ No Docker Compose file found.")
+		fmt.Println("   Run 'dockenv init' first to set up your environment.")
+		return fmt.Errorf("docker Compose file not found")
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	service, exists := services.GetService(serviceName)
+	if !exists || !utils.Contains(cfg.Services, serviceName) {
+		return fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+	}
+
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to check Docker: %w", err)
+	}
+	if !dockerInfo.IsReady() {
+		fmt.Println("❌ Docker setup incomplete:")
+		fmt.Println(dockerInfo.GetInstallInstructions())
+		return fmt.Errorf("docker setup required")
+	}
+
+	// The data directories are named after the Compose services using them
+	composeServices := service.DataDirs()
+	volumes := cfg.UsesVolumes(dockerInfo.Remote())
+
+	fmt.Printf("🧹 Resetting %s\n", service.DisplayName)
+	for _, dir := range composeServices {
+		if volumes {
+			fmt.Printf("   Volume: %s\n", composeVolumeName(dir))
+		} else {
+			fmt.Printf("   Data:   %s/%s\n", cfg.DataPath, dir)
+		}
+	}
+
+	// A dry run deletes nothing and so does not ask, while the record
+	// runtime asks as a real engine would
+	if !resetForceFlag && !dryRunFlag {
+		fmt.Printf("⚠️  WARNING: This permanently deletes all %s data!\n", service.DisplayName)
+		if !utils.PromptConfirm("Are you sure you want to continue?") {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	if err := removeServiceContainers(cmd, composeServices); err != nil {
+		return err
+	}
+
+	fmt.Println("🗑️  Deleting data...")
+	if volumes {
+		for _, dir := range composeServices {
+			if err := docker.RemoveVolume(cmd.Context(), composeVolumeName(dir)); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := docker.RunDataScript(cmd.Context(), cfg.DataPath, resetScript, composeServices...); err != nil {
+			return fmt.Errorf("failed to delete data: %w", err)
+		}
+		if !dockerInfo.Remote() && !dryRunFlag {
+			if err := prepareDataDirs(cmd.Context(), cfg, []string{serviceName}, dockerInfo); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := updateInitMounts(cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Starting %s...\n", service.DisplayName)
+	if err := docker.ComposeUp(cmd.Context(), composePath, composeServices...); err != nil {
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+
+	if err := seedFreshServices(cmd.Context(), []string{serviceName}, map[string]bool{serviceName: true}, dockerInfo.Remote()); err != nil {
+		return fmt.Errorf("failed to seed %s: %w", serviceName, err)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no data was deleted.")
+		return nil
+	}
+
+	fmt.Printf("✅ %s reset with empty data!\n", service.DisplayName)
+	fmt.Printf("   Follow its initialization with: dockenv logs -f %s\n", serviceName)
+	return nil
+}
+
+// removeServiceContainers removes the containers of the given Compose
+// services, so that nothing holds on to their data.
+func removeServiceContainers(cmd *cobra.Command, composeServices []string) error {
+	var names []string
+	if runner.Recording() {
+		for _, composeService := range composeServices {
+			names = append(names, "dockenv-"+composeService)
+		}
+	} else {
+		containers, err := docker.ComposeContainers(cmd.Context(), config.GetComposePath(), config.GetProjectName())
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		for _, container := range containers {
+			if utils.Contains(composeServices, container.Service) {
+				names = append(names, container.Name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+	fmt.Printf("🛑 Removing %s...\n", strings.Join(names, ", "))
+	return docker.RemoveContainers(cmd.Context(), names...)
+}
+
+// composeVolumeName returns the name Compose gives the named volume of a
+// data directory, prefixed with the project name.
+func composeVolumeName(dir string) string {
+	return config.GetProjectName() + "_" + dir + "_data"
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+)
+
+// warnMemoryBudget prints a warning when the memory limits of the configured
+// services add up to more than the host has. Hosts without /proc/meminfo and
+// services without limits are skipped.
+func warnMemoryBudget(cfg *config.Config) {
+	total, err := host.TotalMemory()
+	if err != nil {
+		return
+	}
+
+	var budget int64
+	for _, serviceName := range cfg.Services {
+		limit := cfg.Resources[serviceName].Memory
+		if limit == "" {
+			continue
+		}
+		bytes, err := config.ParseMemory(limit)
+		if err != nil {
+			continue
+		}
+		budget += bytes
+	}
+
+	if budget > total {
+		fmt.Printf("⚠️  Memory limits of configured services (%s) exceed host memory (%s).\n",
+			config.FormatMemory(budget), config.FormatMemory(total))
+		fmt.Println("   Lower 'resources.<service>.memory' in the configuration to avoid swapping.")
+		fmt.Println()
+	}
+}
+
+func parseResourceFlags(cfg *config.Config, memorySpecs, cpuSpecs []string, allowed []string) error {
+	for _, spec := range memorySpecs {
+		serviceName, value, err := splitServiceSpec(spec, allowed)
+		if err != nil {
+			return err
+		}
+		if _, err := config.ParseMemory(value); err != nil {
+			return err
+		}
+
+		resources := cfg.Resources[serviceName]
+		resources.Memory = value
+		cfg.Resources[serviceName] = resources
+	}
+
+	for _, spec := range cpuSpecs {
+		serviceName, value, err := splitServiceSpec(spec, allowed)
+		if err != nil {
+			return err
+		}
+
+		cpus := 0.0
+		if _, err := fmt.Sscanf(value, "%g", &cpus); err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid cpus value: %s", value)
+		}
+
+		resources := cfg.Resources[serviceName]
+		resources.CPUs = cpus
+		cfg.Resources[serviceName] = resources
+	}
+
+	return nil
+}
+
+func splitServiceSpec(spec string, allowed []string) (string, string, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid resource specification: %s (expected format: service:value)", spec)
+	}
+
+	if !utils.Contains(allowed, parts[0]) {
+		return "", "", fmt.Errorf("service %s not in selected services", parts[0])
+	}
+
+	return parts[0], parts[1], nil
+}
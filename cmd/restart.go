@@ -5,6 +5,7 @@ import (
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
 
 	"github.com/spf13/cobra"
@@ -38,7 +39,7 @@ func runRestart(cmd *cobra.Command, args []string) error {
 	}
 
 	// Check Docker
-	dockerInfo, err := docker.CheckDocker()
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
 	if err != nil {
 		return fmt.Errorf("failed to check Docker: %w", err)
 	}
@@ -67,10 +68,15 @@ func runRestart(cmd *cobra.Command, args []string) error {
 	fmt.Println("🔄 Restarting services...")
 
 	// Restart services
-	if err := docker.ComposeRestart(composePath, args...); err != nil {
+	if err := docker.ComposeRestart(cmd.Context(), composePath, args...); err != nil {
 		return fmt.Errorf("failed to restart services: %w", err)
 	}
 
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no containers were restarted.")
+		return nil
+	}
+
 	fmt.Println("✅ Services restarted successfully!")
 
 	// Show what was restarted
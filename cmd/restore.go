@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/backup"
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive> [service]",
+	Short: "Restore services from a backup archive",
+	Long: `Restore the services of an archive written by 'dockenv backup', or only the
+given one. The archive may be a path or the name of a file in the backups
+directory.
+
+Before anything is restored, every service in the archive must be configured
+and run the image version it was dumped from. The services must be running.
+
+Examples:
+  dockenv restore shop-20240501-120000.tar.gz
+  dockenv restore backup.tar.gz postgres    # Restore only PostgreSQL`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runRestore,
+}
+
+var restoreForceFlag bool
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().BoolVarP(&restoreForceFlag, "force", "f", false, "Restore without confirmation")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	composePath := config.GetComposePath()
+	if !utils.FileExists(composePath) {
+		fmt.Println("❌ No Docker Compose file found.")
+		fmt.Println("   Run 'dockenv init' first to set up your environment.")
+		return fmt.Errorf("docker Compose file not found")
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	archivePath := args[0]
+	if !utils.FileExists(archivePath) && utils.FileExists(filepath.Join(cfg.GetBackupPath(), archivePath)) {
+		archivePath = filepath.Join(cfg.GetBackupPath(), archivePath)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	dumpDir, err := os.MkdirTemp("", "dockenv-restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create dump directory: %w", err)
+	}
+	defer os.RemoveAll(dumpDir)
+
+	manifest, err := backup.Read(file, dumpDir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", archivePath, err)
+	}
+
+	dumps := manifest.Services
+	if len(args) == 2 {
+		dump, found := manifest.Find(args[1])
+		if !found {
+			return fmt.Errorf("the backup has no dump of '%s'. It contains: %s", args[1], strings.Join(manifest.ServiceNames(), ", "))
+		}
+		dumps = []backup.Dump{dump}
+	}
+
+	if err := checkRestore(cmd, cfg, dumps); err != nil {
+		return err
+	}
+	if manifest.Project != config.GetProjectName() {
+		fmt.Printf("⚠️  The backup was made for project %s.\n", manifest.Project)
+	}
+
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(dumps))
+	for _, dump := range dumps {
+		names = append(names, dump.Service)
+	}
+	fmt.Printf("♻️  Restoring %s from %s (%s)\n", strings.Join(names, ", "), archivePath, manifest.Created.Local().Format("2006-01-02 15:04:05"))
+
+	if !restoreForceFlag && !runner.Recording() {
+		fmt.Println("⚠️  WARNING: This replaces the current data of these services!")
+		if !utils.PromptConfirm("Are you sure you want to continue?") {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	for _, dump := range dumps {
+		if err := restoreDump(cmd, composePath, dump, filepath.Join(dumpDir, filepath.FromSlash(dump.File))); err != nil {
+			return err
+		}
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: nothing was restored.")
+		return nil
+	}
+
+	fmt.Println("✅ Restore complete!")
+	return nil
+}
+
+// checkRestore verifies that the services of the dumps are configured and
+// run the image version they were dumped from.
+func checkRestore(cmd *cobra.Command, cfg *config.Config, dumps []backup.Dump) error {
+	for _, dump := range dumps {
+		service, exists := services.GetService(dump.Service)
+		if !exists || !utils.Contains(cfg.Services, dump.Service) {
+			return fmt.Errorf("the backup contains '%s', which is not configured. Restore the other services one by one", dump.Service)
+		}
+		if _, exists := backup.LookupMethod(dump.Service); !exists {
+			return fmt.Errorf("no logical restore for %s", dump.Service)
+		}
+
+		current := service.ResolveVersion(cfg.Versions[dump.Service])
+		if dump.Version != current {
+			fmt.Printf("❌ %s was dumped from %s %s, but %s is configured.\n", dump.Service, service.DisplayName, dump.Version, current)
+			fmt.Printf("   Set 'versions: {%s: \"%s\"}' in %s to restore into the original version.\n", dump.Service, dump.Version, config.ConfigFileName)
+			cmd.SilenceUsage = true
+			return fmt.Errorf("version mismatch for %s", dump.Service)
+		}
+	}
+	return nil
+}
+
+// restoreDump feeds a dump to the restore tool of its service, or copies it
+// into the stopped container for services restored from a data file.
+func restoreDump(cmd *cobra.Command, composePath string, dump backup.Dump, file string) (err error) {
+	service, _ := services.GetService(dump.Service)
+	method, _ := backup.LookupMethod(dump.Service)
+
+	fmt.Printf("📥 Restoring %s...\n", service.DisplayName)
+
+	if method.Restore != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("failed to open dump: %w", err)
+		}
+		defer f.Close()
+
+		if err := docker.ExecScript(cmd.Context(), service.ContainerName(), f, os.Stdout, method.Restore); err != nil {
+			return fmt.Errorf("failed to restore %s (is it running?): %w", dump.Service, err)
+		}
+		return nil
+	}
+
+	// The copy is owned by root in the container, so the service's own
+	// user must be able to read it
+	if err := os.Chmod(file, 0644); err != nil {
+		return fmt.Errorf("failed to prepare dump: %w", err)
+	}
+	if err := docker.ComposeStop(cmd.Context(), composePath, dump.Service); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", dump.Service, err)
+	}
+	// Start the service again even if the copy failed, with its old data
+	defer func() {
+		ctx, cancel := cleanupContext(cmd)
+		defer cancel()
+		if startErr := docker.ComposeStart(ctx, composePath, dump.Service); startErr != nil && err == nil {
+			err = fmt.Errorf("failed to start %s: %w", dump.Service, startErr)
+		}
+	}()
+
+	return docker.CopyToContainer(cmd.Context(), file, service.ContainerName(), method.RestorePath)
+}
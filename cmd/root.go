@@ -1,6 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
 	"github.com/spf13/cobra"
 )
 
@@ -19,12 +31,78 @@ Example usage:
   dockenv add mysql    # Add a service
   dockenv remove redis # Remove a service`,
 	Version: "0.2.0",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if dryRunFlag {
+			runner.Record(os.Stderr)
+		}
+
+		// Bound everything the command runs, including runtime detection
+		ctx, cancel := runner.WithTimeout(cmd.Context(), timeoutFlag)
+		cmd.SetContext(ctx)
+		cancelTimeout = cancel
+
+		// Commands report config errors themselves when they need the
+		// config; DOCKENV_RUNTIME applies even without one
+		cfg, err := utils.LoadConfig()
+		if err != nil {
+			cfg = &config.Config{}
+		}
+		docker.SetRuntime(ctx, cfg.GetRuntime())
+	},
 }
 
+var (
+	dryRunFlag    bool
+	timeoutFlag   time.Duration
+	cancelTimeout context.CancelFunc = func() {}
+)
+
+// Execute runs the command line. SIGINT and SIGTERM end the command's
+// context, which passes the signal on to running child processes.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := runner.WithSignals(context.Background())
+	defer stop()
+	defer func() { cancelTimeout() }()
+
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// ExitError ends dockenv with the exit status of a command it ran in a
+// container, whose own output explains the failure.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// commandExit turns the failure of a command run in a container into an
+// *ExitError, silencing Cobra's report of it.
+func commandExit(cmd *cobra.Command, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+		return &ExitError{Code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+// cleanupTimeout bounds the steps that put things back after a command
+// failed, such as starting the services it stopped.
+const cleanupTimeout = 2 * time.Minute
+
+// cleanupContext returns the context for those steps. It is not cancelled
+// with the command's, which has already ended when they run after Ctrl+C or
+// --timeout.
+func cleanupContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return runner.WithTimeout(context.WithoutCancel(cmd.Context()), cleanupTimeout)
 }
 
 func init() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	rootCmd.PersistentFlags().BoolVar(&dryRunFlag, "dry-run", false, "Print the docker, compose and systemctl commands instead of running them, and write no files")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "Abort the command after this long, e.g. 30s or 5m (0 means no limit)")
 }
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/seed"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var seedCmd = &cobra.Command{
+	Use:   "seed [service...]",
+	Short: "Run the init scripts of services",
+	Long: `Run the init scripts in .dockenv/init/<service>/ against the running services.
+
+The scripts also run by themselves when a service starts with empty data:
+MySQL, PostgreSQL and MongoDB run them from docker-entrypoint-initdb.d, where
+dockenv mounts the directory, and dockenv runs them for the other services
+once they are healthy. 'dockenv seed' runs them on demand, e.g. after adding a
+script to a database that already has data, which is never seeded by itself.
+
+Scripts run in the order of their names:
+  .sql, .sql.gz   MySQL and PostgreSQL, against the configured database
+  .js             MongoDB
+  .redis          Redis, one command per line
+  .sh             Every service, run in its container
+
+Examples:
+  dockenv seed            # Run the scripts of every service that has some
+  dockenv seed postgres   # Run only PostgreSQL's scripts`,
+	RunE: runSeed,
+}
+
+func init() {
+	rootCmd.AddCommand(seedCmd)
+}
+
+func runSeed(cmd *cobra.Command, args []string) error {
+	if !utils.FileExists(config.GetComposePath()) {
+		fmt.Println("❌ No Docker Compose file found.")
+		fmt.Println("   Run 'dockenv init' first to set up your environment.")
+		return fmt.Errorf("docker Compose file not found")
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for _, serviceName := range args {
+		if !utils.Contains(cfg.Services, serviceName) {
+			return fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+		}
+		if !seed.HasScripts(serviceName) {
+			return fmt.Errorf("no init scripts for '%s' in %s", serviceName, seed.ServiceDir(serviceName))
+		}
+	}
+
+	serviceNames := args
+	if len(serviceNames) == 0 {
+		for _, serviceName := range cfg.Services {
+			if seed.HasScripts(serviceName) {
+				serviceNames = append(serviceNames, serviceName)
+			}
+		}
+	}
+	if len(serviceNames) == 0 {
+		fmt.Println("📋 No init scripts.")
+		fmt.Printf("   Add them to %s/<service>/.\n", config.InitDir)
+		return nil
+	}
+
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	if err := requireRunning(cmd, serviceNames); err != nil {
+		return err
+	}
+
+	for _, serviceName := range serviceNames {
+		if err := seedService(cmd.Context(), serviceName); err != nil {
+			return err
+		}
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no scripts were run.")
+		return nil
+	}
+
+	fmt.Println("✅ Services seeded!")
+	return nil
+}
+
+// freshServices returns which of the given services with init scripts have
+// no data yet, so that starting them creates it. Data dockenv cannot look
+// into, such as bind mounts on a remote engine, does not count as fresh.
+func freshServices(ctx context.Context, cfg *config.Config, serviceNames []string, dockerInfo *docker.DockerInfo) map[string]bool {
+	fresh := make(map[string]bool)
+	for _, serviceName := range serviceNames {
+		service, exists := services.GetService(serviceName)
+		if !exists || !seed.HasScripts(serviceName) {
+			continue
+		}
+
+		fresh[serviceName] = true
+		for _, dir := range service.DataDirs() {
+			switch {
+			case cfg.UsesVolumes(dockerInfo.Remote()):
+				if docker.VolumeExists(ctx, composeVolumeName(dir)) {
+					fresh[serviceName] = false
+				}
+			case dockerInfo.Remote():
+				fresh[serviceName] = false
+			default:
+				if utils.FileExists(filepath.Join(cfg.DataPath, dir)) {
+					fresh[serviceName] = false
+				}
+			}
+		}
+	}
+	return fresh
+}
+
+// seedFreshServices runs the init scripts of the given services whose
+// images do not run them, once the services are healthy. Only services whose
+// data was just created are seeded, as the images only run their scripts on
+// empty data too. The data of the others is marked as seeded without running
+// the scripts, which 'dockenv seed' then runs on demand.
+func seedFreshServices(ctx context.Context, serviceNames []string, fresh map[string]bool, remote bool) error {
+	for _, serviceName := range serviceNames {
+		method, exists := seed.LookupMethod(serviceName)
+		if !exists || !seed.HasScripts(serviceName) || seed.Mounted(serviceName, remote) {
+			continue
+		}
+		service, _ := services.GetService(serviceName)
+
+		if !runner.Recording() {
+			if err := waitForService(ctx, serviceName, false); err != nil {
+				return err
+			}
+			if docker.ExecScript(ctx, service.ContainerName(), nil, nil, `test -e "$1"`, method.MarkerPath()) == nil {
+				continue
+			}
+		}
+
+		if !fresh[serviceName] {
+			if err := markSeeded(ctx, service, method); err != nil {
+				return err
+			}
+			fmt.Printf("ℹ️  %s already has data, so its init scripts were not run.\n", service.DisplayName)
+			fmt.Printf("   Run 'dockenv seed %s' to run them against it.\n", serviceName)
+			continue
+		}
+
+		if !runner.Recording() {
+			fmt.Printf("⏳ Waiting for %s to become healthy...\n", service.DisplayName)
+			if err := waitForService(ctx, serviceName, true); err != nil {
+				return err
+			}
+		}
+		if err := seedService(ctx, serviceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedService runs the init scripts of a service in its container and marks
+// its data as seeded.
+func seedService(ctx context.Context, serviceName string) error {
+	service, _ := services.GetService(serviceName)
+	method, exists := seed.LookupMethod(serviceName)
+	if !exists {
+		return fmt.Errorf("init scripts are not supported for %s", serviceName)
+	}
+
+	scripts, skipped, err := seed.Scripts(seed.ServiceDir(serviceName), method)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🌱 Seeding %s...\n", service.DisplayName)
+	if len(skipped) > 0 {
+		fmt.Printf("⚠️  Skipping files %s cannot run: %s\n", service.DisplayName, strings.Join(skipped, ", "))
+	}
+	for _, script := range scripts {
+		fmt.Printf("   %s\n", script.Name)
+		file, err := os.Open(script.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open init script: %w", err)
+		}
+		err = docker.ExecScript(ctx, service.ContainerName(), file, os.Stdout, script.Command)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("init script %s failed: %w", script.Name, err)
+		}
+	}
+
+	return markSeeded(ctx, service, method)
+}
+
+// markSeeded records in the data of a service that its init scripts ran.
+func markSeeded(ctx context.Context, service services.Service, method seed.Method) error {
+	if err := docker.ExecScript(ctx, service.ContainerName(), nil, nil, `touch "$1"`, method.MarkerPath()); err != nil {
+		return fmt.Errorf("failed to mark %s as seeded: %w", service.Name, err)
+	}
+	return nil
+}
+
+// serviceRunning reports whether the container of a Compose service runs.
+func serviceRunning(containers []docker.Container, composeService string) bool {
+	for _, container := range containers {
+		if container.Service == composeService && container.Running() {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForService polls the container of a Compose service until it runs or,
+// if healthy is set, passes its health check. Unlike following the engine's
+// events, polling works with remote engines too.
+func waitForService(ctx context.Context, composeService string, healthy bool) error {
+	ctx, cancel := runner.WithTimeout(ctx, upWaitTimeoutFlag)
+	defer cancel()
+
+	for {
+		containers, err := docker.ComposeContainers(ctx, config.GetComposePath(), config.GetProjectName())
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%s did not start: %w", composeService, context.Cause(ctx))
+			}
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+
+		for _, container := range containers {
+			if container.Service != composeService {
+				continue
+			}
+			if container.Healthy() || (!healthy && container.Running()) {
+				return nil
+			}
+			if container.Health == "unhealthy" || container.State == "exited" || container.State == "dead" {
+				return fmt.Errorf("%s is %s; see 'dockenv logs %s'", composeService, container.Status, composeService)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not become healthy: %w", composeService, context.Cause(ctx))
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// updateInitMounts rewrites the project files when init script directories
+// were added or removed since the Compose file was written, so that the
+// images find the scripts on their first start.
+func updateInitMounts(cfg *config.Config) error {
+	current, err := os.ReadFile(config.GetComposePath())
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+	env, err := templateEnvironment(cfg)
+	if err != nil {
+		return err
+	}
+	rendered, err := templates.RenderDockerCompose(cfg, env)
+	if err != nil {
+		return err
+	}
+
+	if strings.Join(initMountLines(current), "\n") == strings.Join(initMountLines(rendered), "\n") {
+		return nil
+	}
+
+	fmt.Println("📝 Init scripts changed, updating the Compose file...")
+	if dryRunFlag {
+		return printPlan(cfg)
+	}
+	return writeProjectFiles(cfg)
+}
+
+// initMountLines returns the lines of a Compose file that mount init scripts.
+func initMountLines(content []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, seed.InitDBDir) {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
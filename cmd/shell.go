@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/shell"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell [flags] <service> [client args...]",
+	Short: "Open the native client of a service",
+	Long: `Open the native client of a service inside its container, connected with the
+user, password and database of the configuration:
+
+  mysql      mysql
+  postgres   psql
+  mongodb    mongosh
+  redis      redis-cli
+  rabbitmq   rabbitmqadmin, showing the overview without arguments
+  kafka      kafka-topics --list without arguments; with a topic
+             kafka-console-consumer, or kafka-console-producer when
+             messages are piped in
+
+Arguments after the service are passed to the client. When standard input is
+not a terminal the client reads from it, e.g. to run a SQL file.
+
+Examples:
+  dockenv shell postgres                       # Interactive psql
+  dockenv shell postgres < schema.sql          # Run a SQL file
+  dockenv shell mysql -e "SHOW TABLES"         # Run one statement
+  dockenv shell kafka orders                   # Follow a topic
+  echo '{"id": 1}' | dockenv shell kafka orders  # Produce a message`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runShell,
+}
+
+var noTTYFlag bool
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+
+	// Flags after the service belong to the client
+	shellCmd.Flags().SetInterspersed(false)
+	shellCmd.Flags().BoolVarP(&noTTYFlag, "no-tty", "T", false, "Do not allocate a terminal, e.g. when piping output")
+}
+
+func runShell(cmd *cobra.Command, args []string) error {
+	serviceName, clientArgs := args[0], trimDashes(args[1:])
+
+	cfg, service, err := loadExecService(cmd, serviceName)
+	if err != nil {
+		return err
+	}
+
+	client, exists := shell.LookupClient(serviceName)
+	if !exists {
+		return fmt.Errorf("%s has no native client; use 'dockenv exec %s -- <command>'", service.DisplayName, serviceName)
+	}
+
+	conn := client.Connect(cfg.Env, service.EnvVars)
+	command := client.Command(conn, clientArgs, host.IsTerminal(os.Stdin))
+
+	err = docker.Exec(cmd.Context(), service.ContainerName(), execTTY(), client.Env(conn), command...)
+	return commandExit(cmd, err)
+}
+
+// loadExecService loads the configuration and checks that a service is
+// configured and running, to run commands in its container.
+func loadExecService(cmd *cobra.Command, serviceName string) (*config.Config, services.Service, error) {
+	if !utils.FileExists(config.GetComposePath()) {
+		fmt.Println("❌ No Docker Compose file found.")
+		fmt.Println("   Run 'dockenv init' first to set up your environment.")
+		return nil, services.Service{}, fmt.Errorf("docker Compose file not found")
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return nil, services.Service{}, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	service, exists := services.GetService(serviceName)
+	if !exists || !utils.Contains(cfg.Services, serviceName) {
+		return nil, services.Service{}, fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+	}
+
+	if err := requireEngine(cmd); err != nil {
+		return nil, services.Service{}, err
+	}
+	if err := requireRunning(cmd, []string{serviceName}); err != nil {
+		return nil, services.Service{}, err
+	}
+	return cfg, service, nil
+}
+
+// requireRunning checks that the containers of the given Compose services
+// run.
+func requireRunning(cmd *cobra.Command, composeServices []string) error {
+	if runner.Recording() {
+		return nil
+	}
+
+	containers, err := docker.ComposeContainers(cmd.Context(), config.GetComposePath(), config.GetProjectName())
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, composeService := range composeServices {
+		if !serviceRunning(containers, composeService) {
+			fmt.Printf("❌ %s is not running.\n", composeService)
+			fmt.Printf("   Start it with: dockenv up %s\n", composeService)
+			cmd.SilenceUsage = true
+			return fmt.Errorf("service '%s' is not running", composeService)
+		}
+	}
+	return nil
+}
+
+// execTTY reports whether commands run in containers get a terminal, which
+// they only do when dockenv runs in one.
+func execTTY() bool {
+	return !noTTYFlag && host.IsTerminal(os.Stdin) && host.IsTerminal(os.Stdout)
+}
+
+// trimDashes drops the "--" separating the arguments of a command from the
+// service, which Cobra keeps when flags are not interspersed.
+func trimDashes(args []string) []string {
+	if len(args) > 0 && args[0] == "--" {
+		return args[1:]
+	}
+	return args
+}
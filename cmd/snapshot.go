@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/snapshot"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save and restore the data directories of services",
+	Long: `Keep copies of the data directories of services under the data path and
+jump between them in seconds, e.g. between a clean seed and a mid-migration
+state. The affected services are stopped while their data is copied and
+started again afterwards.
+
+On filesystems with reflinks, such as Btrfs and XFS, the copies share their
+blocks with the data until either changes. Snapshots need data directories
+on this machine, so they do not work with named volumes or remote engines.
+
+Examples:
+  dockenv snapshot create clean-seed          # Snapshot every service
+  dockenv snapshot create before-42 postgres  # Snapshot only PostgreSQL
+  dockenv snapshot list
+  dockenv snapshot restore clean-seed
+  dockenv snapshot delete before-42`,
+}
+
+var createSnapshotCmd = &cobra.Command{
+	Use:   "create <name> [service...]",
+	Short: "Snapshot the data of services",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runCreateSnapshot,
+}
+
+var listSnapshotsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots",
+	Args:  cobra.NoArgs,
+	RunE:  runListSnapshots,
+}
+
+var restoreSnapshotCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Replace the data of services with a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRestoreSnapshot,
+}
+
+var deleteSnapshotCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDeleteSnapshot,
+}
+
+var restoreSnapshotForceFlag bool
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+
+	snapshotCmd.AddCommand(createSnapshotCmd)
+	snapshotCmd.AddCommand(listSnapshotsCmd)
+	snapshotCmd.AddCommand(restoreSnapshotCmd)
+	snapshotCmd.AddCommand(deleteSnapshotCmd)
+
+	restoreSnapshotCmd.Flags().BoolVarP(&restoreSnapshotForceFlag, "force", "f", false, "Restore without confirmation")
+}
+
+func runCreateSnapshot(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := snapshot.ValidateName(name); err != nil {
+		return err
+	}
+
+	cfg, err := loadSnapshotConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	serviceNames := args[1:]
+	if len(serviceNames) == 0 {
+		serviceNames = cfg.Services
+	}
+	for _, serviceName := range serviceNames {
+		if !utils.Contains(cfg.Services, serviceName) {
+			return fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+		}
+	}
+
+	root := cfg.GetSnapshotPath()
+	if _, err := snapshot.Load(root, name); err == nil {
+		return fmt.Errorf("snapshot '%s' already exists; delete it first", name)
+	}
+
+	manifest := newSnapshotManifest(cfg, name, serviceNames)
+	if len(manifest.Dirs) == 0 {
+		fmt.Println("📋 No data to snapshot yet.")
+		fmt.Println("   Start the services with 'dockenv up' first.")
+		return nil
+	}
+
+	fmt.Printf("📸 Creating snapshot %s of %s...\n", name, strings.Join(manifest.Services, ", "))
+
+	// The directory is created here, so that it is ours to write the
+	// manifest into once the engine has copied the data
+	if !runner.Recording() {
+		if err := os.MkdirAll(snapshot.Dir(root, name), 0755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+	}
+
+	err = withServicesStopped(cmd, manifest.Dirs, func() error {
+		scriptArgs := append([]string{snapshotDataDir(name)}, manifest.Dirs...)
+		return docker.RunDataScript(cmd.Context(), cfg.DataPath, snapshot.CreateScript, scriptArgs...)
+	})
+	if err != nil {
+		// Leave no half-made snapshot behind
+		ctx, cancel := cleanupContext(cmd)
+		_ = docker.RunDataScript(ctx, cfg.DataPath, snapshot.DeleteScript, snapshotDataDir(name))
+		cancel()
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no snapshot was created.")
+		return nil
+	}
+
+	if err := manifest.Save(root); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Snapshot %s created!\n", name)
+	fmt.Printf("   Restore it with: dockenv snapshot restore %s\n", name)
+	return nil
+}
+
+func runListSnapshots(cmd *cobra.Command, args []string) error {
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manifests, err := snapshot.List(cfg.GetSnapshotPath())
+	if err != nil {
+		return err
+	}
+
+	if len(manifests) == 0 {
+		fmt.Println("📋 No snapshots.")
+		fmt.Println("   Create one with 'dockenv snapshot create <name>'.")
+		return nil
+	}
+
+	fmt.Println("📸 Snapshots:")
+	for _, manifest := range manifests {
+		project := ""
+		if manifest.Project != config.GetProjectName() {
+			project = fmt.Sprintf(" (project %s)", manifest.Project)
+		}
+		fmt.Printf("   %-20s %s  %s%s\n", manifest.Name, manifest.Created.Local().Format("2006-01-02 15:04"),
+			strings.Join(manifest.Services, ", "), project)
+	}
+	return nil
+}
+
+func runRestoreSnapshot(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := snapshot.ValidateName(name); err != nil {
+		return err
+	}
+
+	cfg, err := loadSnapshotConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := snapshot.Load(cfg.GetSnapshotPath(), name)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("snapshot '%s' not found; see 'dockenv snapshot list'", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := checkSnapshotVersions(cmd, cfg, manifest); err != nil {
+		return err
+	}
+
+	fmt.Printf("⏪ Restoring snapshot %s of %s (%s)\n", name, strings.Join(manifest.Services, ", "),
+		manifest.Created.Local().Format("2006-01-02 15:04"))
+
+	if !restoreSnapshotForceFlag && !runner.Recording() {
+		fmt.Println("⚠️  WARNING: This replaces the current data of these services!")
+		if !utils.PromptConfirm("Are you sure you want to continue?") {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	err = withServicesStopped(cmd, manifest.Dirs, func() error {
+		scriptArgs := append([]string{snapshotDataDir(name)}, manifest.Dirs...)
+		return docker.RunDataScript(cmd.Context(), cfg.DataPath, snapshot.RestoreScript, scriptArgs...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: nothing was restored.")
+		return nil
+	}
+
+	fmt.Printf("✅ Snapshot %s restored!\n", name)
+	return nil
+}
+
+func runDeleteSnapshot(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := snapshot.ValidateName(name); err != nil {
+		return err
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if !utils.FileExists(snapshot.Dir(cfg.GetSnapshotPath(), name)) {
+		return fmt.Errorf("snapshot '%s' not found; see 'dockenv snapshot list'", name)
+	}
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	// The copies belong to the users of the service images, so the engine
+	// removes them
+	if err := docker.RunDataScript(cmd.Context(), cfg.DataPath, snapshot.DeleteScript, snapshotDataDir(name)); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no snapshot was deleted.")
+		return nil
+	}
+
+	fmt.Printf("✅ Snapshot %s deleted.\n", name)
+	return nil
+}
+
+// newSnapshotManifest describes a snapshot of the data directories the given
+// services have. Services that never ran have no data yet and are left out.
+func newSnapshotManifest(cfg *config.Config, name string, serviceNames []string) *snapshot.Manifest {
+	manifest := &snapshot.Manifest{
+		Version:  snapshot.ManifestVersion,
+		Name:     name,
+		Created:  time.Now().UTC().Truncate(time.Second),
+		Project:  config.GetProjectName(),
+		Versions: make(map[string]string),
+	}
+
+	for _, serviceName := range serviceNames {
+		service, exists := services.GetService(serviceName)
+		if !exists {
+			continue
+		}
+		var dirs []string
+		for _, dir := range service.DataDirs() {
+			if utils.FileExists(filepath.Join(cfg.DataPath, dir)) {
+				dirs = append(dirs, dir)
+			}
+		}
+		if len(dirs) == 0 {
+			continue
+		}
+		manifest.Services = append(manifest.Services, serviceName)
+		manifest.Versions[serviceName] = service.ResolveVersion(cfg.Versions[serviceName])
+		manifest.Dirs = append(manifest.Dirs, dirs...)
+	}
+	return manifest
+}
+
+// checkSnapshotVersions verifies that the services of a snapshot are
+// configured and run the image version the snapshot was taken from.
+func checkSnapshotVersions(cmd *cobra.Command, cfg *config.Config, manifest *snapshot.Manifest) error {
+	for _, serviceName := range manifest.Services {
+		service, exists := services.GetService(serviceName)
+		if !exists || !utils.Contains(cfg.Services, serviceName) {
+			return fmt.Errorf("the snapshot contains '%s', which is not configured", serviceName)
+		}
+		if current := service.ResolveVersion(cfg.Versions[serviceName]); manifest.Versions[serviceName] != current {
+			fmt.Printf("❌ The snapshot holds %s %s data, but %s is configured.\n",
+				service.DisplayName, manifest.Versions[serviceName], current)
+			fmt.Printf("   Set 'versions: {%s: \"%s\"}' in %s to restore it.\n", serviceName, manifest.Versions[serviceName], config.ConfigFileName)
+			cmd.SilenceUsage = true
+			return fmt.Errorf("version mismatch for %s", serviceName)
+		}
+	}
+	return nil
+}
+
+// loadSnapshotConfig loads the configuration and checks that the engine is
+// usable and keeps the data in directories under the data path.
+func loadSnapshotConfig(cmd *cobra.Command) (*config.Config, error) {
+	if !utils.FileExists(config.GetComposePath()) {
+		fmt.Println("❌ No Docker Compose file found.")
+		fmt.Println("   Run 'dockenv init' first to set up your environment.")
+		return nil, fmt.Errorf("docker Compose file not found")
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to check Docker: %w", err)
+	}
+	if !dockerInfo.IsReady() {
+		fmt.Println("❌ Docker setup incomplete:")
+		fmt.Println(dockerInfo.GetInstallInstructions())
+		return nil, fmt.Errorf("docker setup required")
+	}
+
+	if dockerInfo.Remote() || cfg.UsesVolumes(false) {
+		fmt.Println("❌ Snapshots need the data in directories on this machine.")
+		fmt.Printf("   Set 'storage: %s' and use a local engine.\n", config.StorageBind)
+		cmd.SilenceUsage = true
+		return nil, fmt.Errorf("snapshots are not supported with named volumes")
+	}
+	return cfg, nil
+}
+
+// snapshotDataDir returns the directory of a snapshot relative to the data
+// path, as the data scripts take it.
+func snapshotDataDir(name string) string {
+	return config.SnapshotsDir + "/" + name
+}
+
+// withServicesStopped stops the Compose services, runs fn and starts again
+// the ones that were running. The data directories of the catalog services
+// are named after their Compose services, e.g. "kafka" and "zookeeper".
+func withServicesStopped(cmd *cobra.Command, composeServices []string, fn func() error) error {
+	composePath := config.GetComposePath()
+
+	running := composeServices
+	if !runner.Recording() {
+		containers, err := docker.ComposeContainers(cmd.Context(), composePath, config.GetProjectName())
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		running = nil
+		for _, container := range containers {
+			if container.Running() && utils.Contains(composeServices, container.Service) {
+				running = append(running, container.Service)
+			}
+		}
+	}
+
+	if len(running) > 0 {
+		fmt.Printf("⏸️  Stopping %s...\n", strings.Join(running, ", "))
+		if err := docker.ComposeStop(cmd.Context(), composePath, running...); err != nil {
+			return fmt.Errorf("failed to stop services: %w", err)
+		}
+	}
+
+	err := fn()
+
+	if len(running) > 0 {
+		fmt.Printf("▶️  Starting %s...\n", strings.Join(running, ", "))
+		ctx, cancel := cleanupContext(cmd)
+		defer cancel()
+		if startErr := docker.ComposeStart(ctx, composePath, running...); startErr != nil && err == nil {
+			err = fmt.Errorf("failed to start services: %w", startErr)
+		}
+	}
+	return err
+}
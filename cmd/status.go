@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/services"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
 
 	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v3"
 )
 
 var statusCmd = &cobra.Command{
@@ -16,16 +21,43 @@ var statusCmd = &cobra.Command{
 	Long: `Display the current status of all configured services.
 This shows which services are running, stopped, or have issues.
 
+The command exits with a non-zero status when any configured service is not
+running or not healthy, so it can be used in scripts.
+
 Examples:
-  dockenv status       # Show status of all services`,
+  dockenv status            # Show status of all services
+  dockenv status -o json    # Machine-readable status`,
 	RunE: runStatus,
 }
 
+var statusOutputFlag string
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVarP(&statusOutputFlag, "output", "o", "table", "Output format: table, json or yaml")
+}
+
+// serviceStatus is the state of a configured service's container.
+type serviceStatus struct {
+	Service   string               `json:"service" yaml:"service"`
+	Container string               `json:"container,omitempty" yaml:"container,omitempty"`
+	State     string               `json:"state" yaml:"state"`
+	Health    string               `json:"health,omitempty" yaml:"health,omitempty"`
+	Uptime    string               `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	Ports     []docker.PortBinding `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Healthy   bool                 `json:"healthy" yaml:"healthy"`
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
+	switch statusOutputFlag {
+	case "table":
+	case "json", "yaml":
+		return runStatusStructured(cmd)
+	default:
+		return fmt.Errorf("invalid output format: %s (expected table, json or yaml)", statusOutputFlag)
+	}
+
 	// Check if Docker Compose file exists
 	composePath := config.GetComposePath()
 	if !utils.FileExists(composePath) {
@@ -49,41 +81,194 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Println("📊 Service Status:")
 	fmt.Printf("   Configuration: %s\n", config.GetConfigPath())
 	fmt.Printf("   Compose file: %s\n", composePath)
-	fmt.Printf("   Data directory: %s\n", cfg.DataPath)
+	if cfg.UsesVolumes(docker.CurrentContext().Remote()) {
+		fmt.Println("   Data: named volumes")
+	} else {
+		fmt.Printf("   Data directory: %s\n", cfg.DataPath)
+	}
 	fmt.Println()
 
 	// Check Docker
-	dockerInfo, err := docker.CheckDocker()
+	dockerInfo, err := docker.CheckDocker(cmd.Context())
 	if err != nil {
-		fmt.Printf("❌ Docker check failed: %v\n", err)
-		return nil
+		return fmt.Errorf("failed to check Docker: %w", err)
 	}
 
 	if !dockerInfo.IsReady() {
 		fmt.Println("❌ Docker not ready:")
 		fmt.Println(dockerInfo.GetInstallInstructions())
-		return nil
+		cmd.SilenceUsage = true
+		return fmt.Errorf("docker setup required")
 	}
 
 	fmt.Println("🐳 Docker Status:")
 	fmt.Printf("   %s\n", dockerInfo.DockerVersion)
 	fmt.Printf("   %s\n", dockerInfo.ComposeVersion)
+	fmt.Printf("   Runtime: %s\n", dockerInfo.Runtime)
+	fmt.Printf("   Context: %s\n", dockerInfo.Context)
+	if dockerInfo.Remote() {
+		fmt.Printf("   Host: %s (remote)\n", dockerInfo.Context.Hostname())
+	}
+	if dockerInfo.Rootless {
+		fmt.Println("   Mode: rootless")
+	}
+	for _, warning := range dockerInfo.Warnings() {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
 	fmt.Println()
 
 	// Show configured services
 	fmt.Println("🎯 Configured Services:")
 	for _, serviceName := range cfg.Services {
-		port := cfg.Ports[serviceName]
-		fmt.Printf("   %-12s (port %d)\n", serviceName, port)
+		service, exists := services.GetService(serviceName)
+		if !exists {
+			fmt.Printf("   %-12s (unknown service)\n", serviceName)
+			continue
+		}
+
+		resources := cfg.Resources[serviceName]
+		limits := ""
+		if resources.Memory != "" {
+			limits += ", memory " + resources.Memory
+		}
+		if resources.CPUs > 0 {
+			limits += fmt.Sprintf(", cpus %g", resources.CPUs)
+		}
+		fmt.Printf("   %-12s (%s%s)\n", serviceName, describePorts(service, service.HostPorts(cfg.Ports[serviceName])), limits)
 	}
 	fmt.Println()
 
+	warnMemoryBudget(cfg)
+	warnExposedPorts(cfg)
+
 	// Show container status
 	fmt.Println("📦 Container Status:")
-	if err := docker.ComposeStatus(composePath); err != nil {
-		fmt.Printf("   Failed to get status: %v\n", err)
-		fmt.Println("   Services may not be running. Try 'dockenv up' to start them.")
+	containers, err := docker.ComposeContainers(cmd.Context(), composePath, config.GetProjectName())
+	if err != nil {
+		if cmd.Context().Err() != nil {
+			return fmt.Errorf("failed to get status: %w", err)
+		}
+		// Fall back to the runtime's own listing
+		if err := docker.ComposeStatus(cmd.Context(), composePath); err != nil {
+			fmt.Printf("   Failed to get status: %v\n", err)
+			fmt.Println("   Services may not be running. Try 'dockenv up' to start them.")
+		}
+		return nil
+	}
+
+	statuses := collectServiceStatus(cfg, containers)
+	printServiceStatus(statuses)
+
+	return checkServiceStatus(cmd, statuses)
+}
+
+// runStatusStructured prints the container status only, as JSON or YAML.
+func runStatusStructured(cmd *cobra.Command) error {
+	composePath := config.GetComposePath()
+	if !utils.FileExists(composePath) {
+		return fmt.Errorf("docker Compose file not found")
+	}
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	containers, err := docker.ComposeContainers(cmd.Context(), composePath, config.GetProjectName())
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+
+	statuses := collectServiceStatus(cfg, containers)
+
+	var data []byte
+	if statusOutputFlag == "json" {
+		data, err = json.MarshalIndent(statuses, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(statuses)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	os.Stdout.Write(data)
+
+	return checkServiceStatus(cmd, statuses)
+}
+
+// collectServiceStatus matches the containers to the configured services.
+// Services without a container are reported as not created.
+func collectServiceStatus(cfg *config.Config, containers []docker.Container) []serviceStatus {
+	byService := make(map[string]docker.Container)
+	for _, container := range containers {
+		byService[container.Service] = container
+	}
+
+	statuses := make([]serviceStatus, 0, len(cfg.Services))
+	for _, serviceName := range cfg.Services {
+		container, exists := byService[serviceName]
+		if !exists {
+			statuses = append(statuses, serviceStatus{Service: serviceName, State: "not created"})
+			continue
+		}
+
+		statuses = append(statuses, serviceStatus{
+			Service:   serviceName,
+			Container: container.Name,
+			State:     container.State,
+			Health:    container.Health,
+			Uptime:    container.Uptime,
+			Ports:     container.Ports,
+			Healthy:   container.Healthy(),
+		})
+	}
+	return statuses
+}
+
+func printServiceStatus(statuses []serviceStatus) {
+	fmt.Printf("      %-14s %-12s %-10s %-16s %s\n", "SERVICE", "STATE", "HEALTH", "UPTIME", "PORTS")
+	for _, status := range statuses {
+		icon := "🔴"
+		if status.Healthy {
+			icon = "🟢"
+		} else if status.Health == "starting" {
+			icon = "🟡"
+		} else if status.State == "not created" {
+			icon = "⚪"
+		}
+
+		var ports []string
+		for _, port := range status.Ports {
+			ports = append(ports, fmt.Sprintf("%s:%d->%d", port.HostIP, port.HostPort, port.ContainerPort))
+		}
+
+		health := status.Health
+		if health == "" {
+			health = "-"
+		}
+		uptime := status.Uptime
+		if uptime == "" {
+			uptime = "-"
+		}
+
+		fmt.Printf("   %s %-14s %-12s %-10s %-16s %s\n", icon, status.Service, status.State, health, uptime, strings.Join(ports, ", "))
 	}
+	fmt.Println()
+}
 
+// checkServiceStatus fails when any service is not running and healthy, so
+// that status exits non-zero. Usage is not printed for this error.
+func checkServiceStatus(cmd *cobra.Command, statuses []serviceStatus) error {
+	var failing []string
+	for _, status := range statuses {
+		if !status.Healthy {
+			failing = append(failing, status.Service)
+		}
+	}
+
+	if len(failing) > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("services not running or healthy: %s", strings.Join(failing, ", "))
+	}
 	return nil
 }
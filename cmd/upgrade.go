@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/lock"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade <service>",
+	Short: "Move a service to a newer image version",
+	Long: `Move a service to a newer image tag, regenerate the Docker Compose file
+and recreate its container.
+
+By default the service moves to the newest tag within its current major
+version. A new major version may not be able to read the data of the old one,
+so stateful services only cross a major version where the catalog defines a
+migration path for it, one major version at a time.
+
+Examples:
+  dockenv upgrade postgres             # 15 -> newest 15.x
+  dockenv upgrade redis --major        # Newest Redis, if the data migrates
+  dockenv upgrade mysql --to 8.4       # A specific tag`,
+	Args: cobra.ExactArgs(1),
+	RunE: runUpgrade,
+}
+
+var (
+	upgradeToFlag    string
+	upgradeMajorFlag bool
+)
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVar(&upgradeToFlag, "to", "", "Upgrade to this tag")
+	upgradeCmd.Flags().BoolVar(&upgradeMajorFlag, "major", false, "Upgrade to the newest tag, even across major versions")
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	serviceName := args[0]
+
+	cfg, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !utils.Contains(cfg.Services, serviceName) {
+		return fmt.Errorf("service '%s' not configured. Available services: %v", serviceName, cfg.Services)
+	}
+	service, exists := services.GetService(serviceName)
+	if !exists {
+		return fmt.Errorf("unknown service: %s", serviceName)
+	}
+
+	current := service.ResolveVersion(cfg.Versions[serviceName])
+	currentVersion, ok := services.ParseVersion(current)
+	if !ok && upgradeToFlag == "" {
+		fmt.Printf("❌ %s uses the floating tag '%s', which has no newer version.\n", service.DisplayName, current)
+		fmt.Println("   Choose a tag with --to, or pin it with 'dockenv lock update'.")
+		cmd.SilenceUsage = true
+		return fmt.Errorf("no version to upgrade from")
+	}
+
+	target := upgradeToFlag
+	if target == "" {
+		tags, _ := versionCandidates(cmd.Context(), service)
+		wanted, latest := services.LatestVersions(current, tags)
+		target = wanted
+		if upgradeMajorFlag {
+			target = latest
+		}
+		if target == "" {
+			fmt.Printf("✅ %s %s is the newest known version within its major version.\n", service.DisplayName, current)
+			if latest != "" {
+				fmt.Printf("   %s is available; upgrade with 'dockenv upgrade %s --major'.\n", latest, serviceName)
+			}
+			return nil
+		}
+	}
+	if target == current {
+		fmt.Printf("✅ %s already uses %s.\n", service.DisplayName, current)
+		return nil
+	}
+
+	if err := checkUpgradePath(cmd, service, currentVersion, ok, target); err != nil {
+		return err
+	}
+
+	fmt.Printf("⬆️  Upgrading %s: %s -> %s\n", service.DisplayName, current, target)
+
+	if target == service.Version {
+		delete(cfg.Versions, serviceName)
+	} else {
+		cfg.Versions[serviceName] = target
+	}
+
+	if dryRunFlag {
+		return printPlan(cfg)
+	}
+
+	if err := requireEngine(cmd); err != nil {
+		return err
+	}
+
+	// A locked service is pinned to the new tag's digest right away, so that
+	// the lock file stays in step with the configuration
+	lockPath := config.GetLockPath()
+	lockFile, err := lock.Load(lockPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		required, err := serviceImages(cfg)
+		if err != nil {
+			return err
+		}
+		if err := resolveImages(cmd.Context(), lockFile, required, []string{serviceName}); err != nil {
+			return err
+		}
+		if !runner.Recording() {
+			if err := lockFile.Save(lockPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writeProjectFiles(cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("🔄 Recreating %s...\n", serviceName)
+	if err := docker.ComposeUp(cmd.Context(), config.GetComposePath(), serviceName); err != nil {
+		return fmt.Errorf("failed to recreate %s: %w", serviceName, err)
+	}
+
+	if runner.Recording() {
+		fmt.Println("🔍 Dry run: no containers were recreated.")
+		return nil
+	}
+
+	fmt.Printf("✅ %s upgraded to %s!\n", service.DisplayName, target)
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  dockenv logs %s   # Check that it started cleanly\n", serviceName)
+	return nil
+}
+
+// checkUpgradePath refuses downgrades, and major upgrades of stateful
+// services without a migration path. Defined migrations print their note.
+func checkUpgradePath(cmd *cobra.Command, service services.Service, current services.Version, versioned bool, target string) error {
+	targetVersion, ok := services.ParseVersion(target)
+	if !versioned || !ok {
+		// Moving from or to a floating tag cannot be checked
+		return nil
+	}
+
+	if targetVersion.Compare(current) < 0 {
+		fmt.Printf("❌ %s is older than %s; dockenv does not downgrade services.\n", target, current.Tag)
+		cmd.SilenceUsage = true
+		return fmt.Errorf("downgrade of %s is not supported", service.Name)
+	}
+
+	from, to := current.Major(), targetVersion.Major()
+	if from == to || !service.Stateful() {
+		return nil
+	}
+
+	migration, found := service.FindMigration(from, to)
+	if !found {
+		fmt.Printf("❌ %s %d cannot read the data of %s %d in place.\n", service.DisplayName, to, service.DisplayName, from)
+		if _, next := service.FindMigration(from, from+1); next && to > from+1 {
+			fmt.Printf("   Upgrade one major version at a time: dockenv upgrade %s --to <%d.x tag>\n", service.Name, from+1)
+		} else {
+			fmt.Println("   Dump the data, remove the service, and restore the dump into the new version.")
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("no migration path for %s from %d to %d", service.Name, from, to)
+	}
+
+	fmt.Printf("⚠️  %s %d -> %d: %s\n", service.DisplayName, from, to, migration.Note)
+	return nil
+}
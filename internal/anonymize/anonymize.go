@@ -0,0 +1,693 @@
+// Package anonymize rewrites personal data in SQL dumps while they stream,
+// replacing the values of configured columns with deterministic fakes. It
+// understands the INSERT statements of MySQL and PostgreSQL dumps and the
+// COPY data of PostgreSQL dumps.
+package anonymize
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Dialect is the SQL dialect of a dump, which decides how its strings are
+// quoted and escaped.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+)
+
+// Dialects holds the dialect of the SQL dumps the services load.
+var Dialects = map[string]Dialect{
+	"mysql":    MySQL,
+	"postgres": Postgres,
+}
+
+// LookupDialect returns the dialect of the SQL dumps a service loads.
+func LookupDialect(serviceName string) (Dialect, bool) {
+	dialect, exists := Dialects[serviceName]
+	return dialect, exists
+}
+
+// Rule replaces the values of a column with a fake.
+type Rule struct {
+	// Table may be qualified by a schema, e.g. public.users; otherwise it
+	// matches the table in any schema
+	Table  string
+	Column string
+	Fake   string
+}
+
+func (r Rule) String() string {
+	return r.Table + "." + r.Column
+}
+
+// ParseRules parses rules configured as "table.column: fake", sorted by
+// column.
+func ParseRules(configured map[string]string) ([]Rule, error) {
+	var rules []Rule
+	for column, fake := range configured {
+		dot := strings.LastIndex(column, ".")
+		if dot <= 0 || dot == len(column)-1 {
+			return nil, fmt.Errorf("invalid anonymize rule %q: use table.column", column)
+		}
+		// YAML reads an unquoted null as empty
+		if fake == "" {
+			fake = Null
+		}
+		if _, exists := Fakes[fake]; !exists && fake != Null {
+			return nil, fmt.Errorf("invalid anonymize rule %s: unknown fake %q (available: %s)",
+				column, fake, strings.Join(FakeNames(), ", "))
+		}
+		rules = append(rules, Rule{Table: column[:dot], Column: column[dot+1:], Fake: fake})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].String() < rules[j].String()
+	})
+	return rules, nil
+}
+
+// FakeNames returns the fakes rules can use, sorted.
+func FakeNames() []string {
+	names := []string{Null}
+	for name := range Fakes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matchesTable reports whether the rule applies to a table named by its
+// parts, e.g. [public users]. Names are compared without regard to case.
+func (r Rule) matchesTable(name []string) bool {
+	parts := strings.Split(r.Table, ".")
+	if len(parts) > len(name) {
+		return false
+	}
+	for i := 1; i <= len(parts); i++ {
+		if !strings.EqualFold(parts[len(parts)-i], name[len(name)-i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// chunkSize is how much of the dump is read at least at a time.
+const chunkSize = 64 * 1024
+
+// Reader reads a SQL dump with the values of the columns its rules match
+// replaced. Everything else passes through unchanged.
+type Reader struct {
+	src     *bufio.Reader
+	dialect Dialect
+	rules   []Rule
+	mac     hash.Hash
+
+	// buf holds the dump read but not processed yet, which always ends
+	// with a line break unless the dump ended
+	buf []byte
+	eof bool
+	out bytes.Buffer
+	err error
+
+	// columns holds the columns of the tables that rules match, by name
+	columns map[string][]string
+	// copying is set within the data of a COPY statement, whose columns
+	// copyRules holds the rules of. The data starts after the rest of the
+	// statement's line, which copyLineEnd is set until.
+	copying     bool
+	copyLineEnd bool
+	copyRules   map[int]Rule
+
+	matches map[Rule]int
+}
+
+// NewReader returns a reader that anonymizes a dump of a dialect. The fakes
+// are derived from the values keyed with salt, which keeps them from being
+// matched against guessed values.
+func NewReader(r io.Reader, dialect Dialect, rules []Rule, salt string) *Reader {
+	return &Reader{
+		src:     bufio.NewReaderSize(r, chunkSize),
+		dialect: dialect,
+		rules:   rules,
+		mac:     hmac.New(sha256.New, []byte(salt)),
+		columns: make(map[string][]string),
+		matches: make(map[Rule]int),
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for r.out.Len() < len(p) && r.err == nil {
+		r.err = r.next()
+	}
+	if r.out.Len() > 0 {
+		return r.out.Read(p)
+	}
+	return 0, r.err
+}
+
+// Rules returns the rules of the reader.
+func (r *Reader) Rules() []Rule {
+	return r.rules
+}
+
+// Matches returns how many values each rule replaced so far.
+func (r *Reader) Matches() map[Rule]int {
+	return r.matches
+}
+
+// next processes the next statement, or line of COPY data.
+func (r *Reader) next() error {
+	if r.copying {
+		return r.copyLine()
+	}
+
+	end, err := r.statement()
+	if err != nil {
+		return err
+	}
+	if end == 0 {
+		return io.EOF
+	}
+	err = r.rewrite(r.buf[:end])
+	r.buf = r.buf[end:]
+	return err
+}
+
+// fill appends at least min bytes of the dump to the buffer, up to the end
+// of a line.
+func (r *Reader) fill(min int) error {
+	for n := 0; !r.eof; {
+		line, err := r.src.ReadSlice('\n')
+		r.buf = append(r.buf, line...)
+		n += len(line)
+		switch {
+		case err == io.EOF:
+			r.eof = true
+		case err == bufio.ErrBufferFull:
+		case err != nil:
+			return fmt.Errorf("failed to read dump: %w", err)
+		case n >= min:
+			return nil
+		}
+	}
+	return nil
+}
+
+// statement returns the length of the statement at the start of the buffer,
+// up to and including its semicolon, reading as much of the dump as it
+// takes.
+func (r *Reader) statement() (int, error) {
+	pos := 0
+	for {
+		tok, next, status := r.dialect.scan(r.buf, pos, r.eof)
+		if status == scanToken {
+			if tok.kind == tokenSymbol && r.buf[tok.start] == ';' {
+				return next, nil
+			}
+			pos = next
+			continue
+		}
+		if r.eof {
+			return len(r.buf), nil
+		}
+
+		// A cut-off string is scanned again once the rest is read, so read
+		// ahead by as much again to do that a few times at most
+		pos = next
+		min := chunkSize
+		if status == scanMore && len(r.buf) > min {
+			min = len(r.buf)
+		}
+		if err := r.fill(min); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// rewrite writes a statement to the output, replacing the values it inserts
+// into columns with rules.
+func (r *Reader) rewrite(stmt []byte) error {
+	p := &parser{dialect: r.dialect, data: stmt, tokens: r.dialect.tokenize(stmt)}
+
+	var err error
+	switch p.word() {
+	case "INSERT", "REPLACE":
+		p.i++
+		stmt, err = r.rewriteInsert(p)
+	case "CREATE":
+		p.i++
+		r.learnColumns(p)
+	case "COPY":
+		p.i++
+		err = r.startCopy(p)
+	}
+	if err != nil {
+		return err
+	}
+	r.out.Write(stmt)
+	return nil
+}
+
+// tableRules returns the rules that apply to a table.
+func (r *Reader) tableRules(name []string) []Rule {
+	var rules []Rule
+	for _, rule := range r.rules {
+		if rule.matchesTable(name) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// columnRules returns the rules by the positions of the columns they match.
+func columnRules(columns []string, rules []Rule) map[int]Rule {
+	matched := make(map[int]Rule)
+	for i, column := range columns {
+		for _, rule := range rules {
+			if strings.EqualFold(rule.Column, column) {
+				matched[i] = rule
+			}
+		}
+	}
+	return matched
+}
+
+// tableColumns returns the columns a table was created with in the dump.
+// A schema-qualified name also finds a table created without one.
+func (r *Reader) tableColumns(name []string) ([]string, error) {
+	if columns, exists := r.columns[tableKey(name)]; exists {
+		return columns, nil
+	}
+	if columns, exists := r.columns[tableKey(name[len(name)-1:])]; exists {
+		return columns, nil
+	}
+	return nil, fmt.Errorf("cannot anonymize %s: its rows do not name their columns and the dump does not create it first; "+
+		"dump it with complete inserts (mysqldump --complete-insert, pg_dump --column-inserts)", strings.Join(name, "."))
+}
+
+func tableKey(name []string) string {
+	return strings.ToLower(strings.Join(name, "."))
+}
+
+// constraints start the items of CREATE TABLE statements that are not
+// columns.
+var constraints = map[Dialect]map[string]bool{
+	MySQL: {
+		"CONSTRAINT": true, "PRIMARY": true, "KEY": true, "INDEX": true, "UNIQUE": true,
+		"FOREIGN": true, "CHECK": true, "FULLTEXT": true, "SPATIAL": true,
+	},
+	Postgres: {
+		"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "FOREIGN": true,
+		"CHECK": true, "EXCLUDE": true, "LIKE": true,
+	},
+}
+
+// learnColumns records the columns of a table with rules from its CREATE
+// TABLE statement, for the rows inserted without naming their columns.
+func (r *Reader) learnColumns(p *parser) {
+	for p.word() == "TEMPORARY" || p.word() == "TEMP" || p.word() == "UNLOGGED" ||
+		p.word() == "GLOBAL" || p.word() == "LOCAL" {
+		p.i++
+	}
+	if p.word() != "TABLE" {
+		return
+	}
+	p.i++
+	if p.word() == "IF" {
+		p.i += 3
+	}
+	name := p.name()
+	if name == nil || len(r.tableRules(name)) == 0 || !p.symbol('(') {
+		return
+	}
+	p.i++
+
+	var columns []string
+	item := true
+	for depth := 0; p.i < len(p.tokens); p.i++ {
+		if item && depth == 0 {
+			item = false
+			word := p.word()
+			// A PostgreSQL column may be named exclude
+			if constraints[r.dialect][word] && !(word == "EXCLUDE" && p.peekIdent()) {
+				continue
+			}
+			if column, ok := p.ident(); ok {
+				columns = append(columns, column)
+				p.i--
+				continue
+			}
+		}
+		switch {
+		case p.symbol('('):
+			depth++
+		case p.symbol(')'):
+			if depth == 0 {
+				r.columns[tableKey(name)] = columns
+				r.columns[tableKey(name[len(name)-1:])] = columns
+				return
+			}
+			depth--
+		case p.symbol(',') && depth == 0:
+			item = true
+		}
+	}
+}
+
+// rewriteInsert returns an INSERT statement with the values of columns with
+// rules replaced.
+func (r *Reader) rewriteInsert(p *parser) ([]byte, error) {
+	for p.word() == "IGNORE" || p.word() == "LOW_PRIORITY" || p.word() == "DELAYED" || p.word() == "HIGH_PRIORITY" {
+		p.i++
+	}
+	if p.word() == "INTO" {
+		p.i++
+	}
+	name := p.name()
+	if name == nil {
+		return p.data, nil
+	}
+	rules := r.tableRules(name)
+	if len(rules) == 0 {
+		return p.data, nil
+	}
+
+	var columns []string
+	if p.symbol('(') {
+		columns = p.columnList()
+	} else {
+		var err error
+		if columns, err = r.tableColumns(name); err != nil {
+			return nil, err
+		}
+	}
+	if p.word() != "VALUES" && p.word() != "VALUE" {
+		return nil, fmt.Errorf("cannot anonymize %s: only INSERT ... VALUES statements are supported", strings.Join(name, "."))
+	}
+	p.i++
+
+	targets := columnRules(columns, rules)
+	var rewritten bytes.Buffer
+	copied := 0
+	replace := func(first, last, column int) {
+		rule, exists := targets[column]
+		if !exists || first >= last {
+			return
+		}
+		value, ok := p.value(first, last)
+		if !ok {
+			return
+		}
+		start, end := p.tokens[first].start, p.tokens[last-1].end
+		rewritten.Write(p.data[copied:start])
+		if rule.Fake == Null {
+			rewritten.WriteString("NULL")
+		} else {
+			rewritten.WriteString(r.dialect.quote(r.fake(rule, value)))
+		}
+		copied = end
+		r.matches[rule]++
+	}
+
+	for p.symbol('(') {
+		p.i++
+		column, first := 0, p.i
+		for depth := 0; p.i < len(p.tokens); p.i++ {
+			if p.symbol('(') {
+				depth++
+			} else if p.symbol(')') {
+				if depth == 0 {
+					replace(first, p.i, column)
+					break
+				}
+				depth--
+			} else if p.symbol(',') && depth == 0 {
+				replace(first, p.i, column)
+				column, first = column+1, p.i+1
+			}
+		}
+		p.i++
+		if !p.symbol(',') {
+			break
+		}
+		p.i++
+	}
+
+	if copied == 0 {
+		return p.data, nil
+	}
+	rewritten.Write(p.data[copied:])
+	return rewritten.Bytes(), nil
+}
+
+// fake returns the fake a rule replaces a value with.
+func (r *Reader) fake(rule Rule, value string) string {
+	r.mac.Reset()
+	io.WriteString(r.mac, value)
+	return Fakes[rule.Fake](r.mac.Sum(nil), value)
+}
+
+// startCopy switches to reading the data that follows a COPY ... FROM
+// stdin statement.
+func (r *Reader) startCopy(p *parser) error {
+	name := p.name()
+	var columns []string
+	if p.symbol('(') {
+		columns = p.columnList()
+	}
+	if p.word() != "FROM" {
+		return nil
+	}
+	p.i++
+	if p.word() != "STDIN" {
+		return nil
+	}
+
+	r.copying, r.copyLineEnd, r.copyRules = true, true, nil
+	rules := r.tableRules(name)
+	if len(rules) == 0 {
+		return nil
+	}
+	if columns == nil {
+		var err error
+		if columns, err = r.tableColumns(name); err != nil {
+			return err
+		}
+	}
+	r.copyRules = columnRules(columns, rules)
+	return nil
+}
+
+// copyLine processes a line of COPY data. Its fields are separated by tabs
+// and escaped with backslashes, and \N stands for NULL.
+func (r *Reader) copyLine() error {
+	end := bytes.IndexByte(r.buf, '\n')
+	if end < 0 && !r.eof {
+		if err := r.fill(1); err != nil {
+			return err
+		}
+		end = bytes.IndexByte(r.buf, '\n')
+	}
+	if len(r.buf) == 0 {
+		return io.EOF
+	}
+	if end < 0 {
+		end = len(r.buf) - 1
+	}
+	line := r.buf[:end+1]
+	r.buf = r.buf[end+1:]
+
+	switch {
+	case r.copyLineEnd:
+		r.copyLineEnd = false
+	case bytes.Equal(bytes.TrimRight(line, "\r\n"), []byte(`\.`)):
+		r.copying = false
+	case len(r.copyRules) > 0:
+		fields := bytes.Split(bytes.TrimSuffix(line, []byte("\n")), []byte("\t"))
+		for i, field := range fields {
+			rule, exists := r.copyRules[i]
+			if !exists || string(field) == `\N` {
+				continue
+			}
+			if rule.Fake == Null {
+				fields[i] = []byte(`\N`)
+			} else {
+				fields[i] = []byte(escapeCopy(r.fake(rule, unescapeCopy(field))))
+			}
+			r.matches[rule]++
+		}
+		r.out.Write(bytes.Join(fields, []byte("\t")))
+		if line[len(line)-1] == '\n' {
+			r.out.WriteByte('\n')
+		}
+		return nil
+	}
+	r.out.Write(line)
+	return nil
+}
+
+// unescapeCopy decodes a field of COPY data.
+func unescapeCopy(field []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = field[i]; {
+		case c >= '0' && c <= '7':
+			n := 0
+			for j := 0; j < 3 && i < len(field) && field[i] >= '0' && field[i] <= '7'; j++ {
+				n = n*8 + int(field[i]-'0')
+				i++
+			}
+			i--
+			b.WriteByte(byte(n))
+		case c == 'x' && i+1 < len(field) && isHex(field[i+1]):
+			n := 0
+			for j := 0; j < 2 && i+1 < len(field) && isHex(field[i+1]); j++ {
+				i++
+				n = n*16 + hexValue(field[i])
+			}
+			b.WriteByte(byte(n))
+		default:
+			b.WriteByte(unescape(c))
+		}
+	}
+	return b.String()
+}
+
+// escapeCopy encodes a value as a field of COPY data.
+func escapeCopy(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(value)
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func hexValue(c byte) int {
+	switch {
+	case isDigit(c):
+		return int(c - '0')
+	case c >= 'a':
+		return int(c-'a') + 10
+	}
+	return int(c-'A') + 10
+}
+
+// parser walks the tokens of a statement.
+type parser struct {
+	dialect Dialect
+	data    []byte
+	tokens  []token
+	i       int
+}
+
+func (p *parser) text(t token) []byte {
+	return p.data[t.start:t.end]
+}
+
+// word returns the keyword at the current token in upper case, if it is
+// one.
+func (p *parser) word() string {
+	if p.i >= len(p.tokens) || p.tokens[p.i].kind != tokenWord {
+		return ""
+	}
+	return strings.ToUpper(string(p.text(p.tokens[p.i])))
+}
+
+// symbol reports whether the current token is the symbol c.
+func (p *parser) symbol(c byte) bool {
+	return p.i < len(p.tokens) && p.tokens[p.i].kind == tokenSymbol && p.data[p.tokens[p.i].start] == c
+}
+
+// ident returns the identifier at the current token and moves past it.
+func (p *parser) ident() (string, bool) {
+	if p.i >= len(p.tokens) {
+		return "", false
+	}
+	switch t := p.tokens[p.i]; t.kind {
+	case tokenWord:
+		p.i++
+		return string(p.text(t)), true
+	case tokenIdent:
+		p.i++
+		return p.dialect.unquote(p.text(t)), true
+	}
+	return "", false
+}
+
+// peekIdent reports whether the token after the current one is an
+// identifier, as the type after a column's name is.
+func (p *parser) peekIdent() bool {
+	if p.i+1 >= len(p.tokens) {
+		return false
+	}
+	kind := p.tokens[p.i+1].kind
+	return (kind == tokenWord && !strings.EqualFold(string(p.text(p.tokens[p.i+1])), "USING")) || kind == tokenIdent
+}
+
+// name returns the parts of a possibly qualified name and moves past it.
+func (p *parser) name() []string {
+	var parts []string
+	for {
+		part, ok := p.ident()
+		if !ok {
+			return parts
+		}
+		parts = append(parts, part)
+		if !p.symbol('.') {
+			return parts
+		}
+		p.i++
+	}
+}
+
+// columnList returns the columns of a parenthesized list and moves past it.
+func (p *parser) columnList() []string {
+	var columns []string
+	for p.i++; p.i < len(p.tokens); p.i++ {
+		if p.symbol(')') {
+			p.i++
+			break
+		}
+		if column, ok := p.ident(); ok {
+			columns = append(columns, column)
+			p.i--
+		}
+	}
+	return columns
+}
+
+// value returns the value of the tokens [first, last) of an inserted row:
+// the content of a string, possibly with a charset introducer before or a
+// cast after it, or the text of anything else. NULL and DEFAULT have no
+// value.
+func (p *parser) value(first, last int) (string, bool) {
+	tokens := p.tokens[first:last]
+	if len(tokens) == 1 && tokens[0].kind == tokenWord {
+		switch strings.ToUpper(string(p.text(tokens[0]))) {
+		case "NULL", "DEFAULT":
+			return "", false
+		}
+	}
+	if tokens[0].kind == tokenString {
+		return p.dialect.unquote(p.text(tokens[0])), true
+	}
+	if len(tokens) > 1 && tokens[0].kind == tokenWord && p.data[tokens[0].start] == '_' && tokens[1].kind == tokenString {
+		return p.dialect.unquote(p.text(tokens[1])), true
+	}
+	return string(p.data[tokens[0].start:tokens[len(tokens)-1].end]), true
+}
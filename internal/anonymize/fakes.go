@@ -0,0 +1,57 @@
+package anonymize
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Null is the rule that replaces values with NULL rather than a fake.
+const Null = "null"
+
+// Fake returns the replacement of a value from its keyed digest, which
+// makes equal values get equal fakes, e.g. an email address in two tables.
+type Fake func(sum []byte, value string) string
+
+// Fakes holds the fakes rules can replace values with, by name.
+var Fakes = map[string]Fake{
+	"email": func(sum []byte, value string) string {
+		return fmt.Sprintf("%s.%s.%s@example.com", strings.ToLower(pick(firstNames, sum[0])),
+			strings.ToLower(pick(lastNames, sum[1])), hex.EncodeToString(sum[2:7]))
+	},
+	"name": func(sum []byte, value string) string {
+		return pick(firstNames, sum[0]) + " " + pick(lastNames, sum[1])
+	},
+	"phone": func(sum []byte, value string) string {
+		// 555 numbers are reserved for fiction
+		n := binary.BigEndian.Uint32(sum[2:6]) % 10000000
+		return fmt.Sprintf("+1-555-%03d-%04d", n/10000, n%10000)
+	},
+	"hash": func(sum []byte, value string) string {
+		// Cut to the length of the value, so that it fits the column
+		digest := hex.EncodeToString(sum)
+		if len(value) < len(digest) {
+			digest = digest[:len(value)]
+		}
+		return digest
+	},
+}
+
+var firstNames = []string{
+	"Alex", "Amara", "Ana", "Ben", "Carlos", "Chen", "Dana", "Elif",
+	"Emma", "Farid", "Grace", "Hana", "Ivan", "Jamal", "Julia", "Kai",
+	"Lena", "Leo", "Maya", "Mohammed", "Nia", "Noah", "Olga", "Omar",
+	"Priya", "Ravi", "Sara", "Sofia", "Tom", "Yara", "Yuki", "Zoe",
+}
+
+var lastNames = []string{
+	"Adams", "Ahmed", "Baker", "Costa", "Diaz", "Evans", "Fischer", "Garcia",
+	"Hansen", "Ito", "Jensen", "Khan", "Kim", "Lopez", "Martin", "Meyer",
+	"Nguyen", "Novak", "Okafor", "Patel", "Quinn", "Rossi", "Santos", "Schmidt",
+	"Silva", "Tanaka", "Torres", "Usman", "Wang", "Weber", "Yilmaz", "Zhang",
+}
+
+func pick(names []string, b byte) string {
+	return names[int(b)%len(names)]
+}
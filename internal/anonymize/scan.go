@@ -0,0 +1,262 @@
+package anonymize
+
+import (
+	"bytes"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	// tokenWord is a keyword or an unquoted identifier
+	tokenWord tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind       tokenKind
+	start, end int
+}
+
+type scanStatus int
+
+const (
+	scanToken scanStatus = iota
+	// scanEnd means only whitespace and comments are left
+	scanEnd
+	// scanMore means the data ends within a string or comment
+	scanMore
+)
+
+// scan returns the token at data[pos:], skipping whitespace and comments,
+// and the position after it. data must end with a line break unless atEOF is
+// set, so that only strings and comments can be cut off; at EOF they end
+// with the data.
+func (d Dialect) scan(data []byte, pos int, atEOF bool) (token, int, scanStatus) {
+	for pos < len(data) {
+		c := data[pos]
+		next := byte(0)
+		if pos+1 < len(data) {
+			next = data[pos+1]
+		}
+		lineStart := pos == 0 || data[pos-1] == '\n'
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			pos++
+		case c == '-' && next == '-', c == '#' && d == MySQL, c == '\\' && d == Postgres && lineStart:
+			// Comments, and psql meta-commands such as \connect, run to the
+			// end of the line
+			end := bytes.IndexByte(data[pos:], '\n')
+			if end < 0 {
+				if !atEOF {
+					return token{}, pos, scanMore
+				}
+				return token{}, len(data), scanEnd
+			}
+			pos += end + 1
+		case c == '/' && next == '*':
+			end := bytes.Index(data[pos+2:], []byte("*/"))
+			if end < 0 {
+				if !atEOF {
+					return token{}, pos, scanMore
+				}
+				return token{}, len(data), scanEnd
+			}
+			pos += end + 4
+		default:
+			return d.scanToken(data, pos, atEOF)
+		}
+	}
+	return token{}, pos, scanEnd
+}
+
+func (d Dialect) scanToken(data []byte, pos int, atEOF bool) (token, int, scanStatus) {
+	quoted := func(kind tokenKind, open int, quote byte, backslash bool) (token, int, scanStatus) {
+		end, ok := scanQuoted(data, open+1, quote, backslash)
+		if !ok && !atEOF {
+			return token{}, pos, scanMore
+		}
+		return token{kind: kind, start: pos, end: end}, end, scanToken
+	}
+
+	c := data[pos]
+	switch {
+	case c == '\'':
+		return quoted(tokenString, pos, '\'', d == MySQL)
+	case c == '"':
+		return quoted(tokenIdent, pos, '"', d == MySQL)
+	case c == '`' && d == MySQL:
+		return quoted(tokenIdent, pos, '`', false)
+	case c == '$' && d == Postgres:
+		if tag := dollarTag(data[pos:]); tag != nil {
+			end := bytes.Index(data[pos+len(tag):], tag)
+			if end < 0 {
+				if !atEOF {
+					return token{}, pos, scanMore
+				}
+				return token{kind: tokenString, start: pos, end: len(data)}, len(data), scanToken
+			}
+			end = pos + len(tag) + end + len(tag)
+			return token{kind: tokenString, start: pos, end: end}, end, scanToken
+		}
+	case isWordStart(c):
+		// A single letter before a quote prefixes a string, as in E'\n' or
+		// X'ff'
+		if pos+1 < len(data) && data[pos+1] == '\'' && strings.IndexByte("EeNnXxBb", c) >= 0 {
+			return quoted(tokenString, pos+1, '\'', d == MySQL || c == 'E' || c == 'e')
+		}
+		end := pos + 1
+		for end < len(data) && isWordPart(data[end]) {
+			end++
+		}
+		return token{kind: tokenWord, start: pos, end: end}, end, scanToken
+	case isDigit(c) || (c == '.' && pos+1 < len(data) && isDigit(data[pos+1])):
+		end := pos + 1
+		for end < len(data) {
+			if isWordPart(data[end]) || data[end] == '.' {
+				end++
+			} else if (data[end] == '+' || data[end] == '-') && (data[end-1] == 'e' || data[end-1] == 'E') {
+				end++
+			} else {
+				break
+			}
+		}
+		return token{kind: tokenNumber, start: pos, end: end}, end, scanToken
+	}
+	return token{kind: tokenSymbol, start: pos, end: pos + 1}, pos + 1, scanToken
+}
+
+// scanQuoted returns the position after the closing quote of a string or
+// identifier whose content starts at pos. A doubled quote stands for itself,
+// as does any byte after a backslash if backslash is set.
+func scanQuoted(data []byte, pos int, quote byte, backslash bool) (int, bool) {
+	for pos < len(data) {
+		switch data[pos] {
+		case '\\':
+			if backslash {
+				pos++
+			}
+		case quote:
+			if pos+1 < len(data) && data[pos+1] == quote {
+				pos++
+			} else {
+				return pos + 1, true
+			}
+		}
+		pos++
+	}
+	return len(data), false
+}
+
+// dollarTag returns the tag of a PostgreSQL dollar-quoted string that data
+// starts with, such as $$ or $body$.
+func dollarTag(data []byte) []byte {
+	for i := 1; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '$':
+			return data[:i+1]
+		case isWordStart(c), i > 1 && isDigit(c):
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || isDigit(c) || c == '$'
+}
+
+// tokenize returns the tokens of a complete statement.
+func (d Dialect) tokenize(data []byte) []token {
+	var tokens []token
+	for pos := 0; ; {
+		tok, next, status := d.scan(data, pos, true)
+		if status != scanToken {
+			return tokens
+		}
+		tokens = append(tokens, tok)
+		pos = next
+	}
+}
+
+// unquote returns the content of a string or quoted identifier token.
+func (d Dialect) unquote(text []byte) string {
+	if text[0] == '$' {
+		tag := dollarTag(text)
+		if len(text) < 2*len(tag) {
+			return ""
+		}
+		return string(text[len(tag) : len(text)-len(tag)])
+	}
+
+	backslash := d == MySQL && text[0] != '`'
+	if text[0] != '\'' && text[0] != '"' && text[0] != '`' {
+		// A prefixed string
+		backslash = backslash || text[0] == 'E' || text[0] == 'e'
+		text = text[1:]
+	}
+	quote := text[0]
+	text = text[1:]
+	if len(text) > 0 && text[len(text)-1] == quote {
+		text = text[:len(text)-1]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == quote && i+1 < len(text) && text[i+1] == quote:
+			i++
+		case c == '\\' && backslash && i+1 < len(text):
+			i++
+			c = unescape(text[i])
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// unescape returns the byte a backslash escape stands for.
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'v':
+		return '\v'
+	case '0':
+		return 0
+	case 'Z':
+		return 0x1a
+	}
+	return c
+}
+
+// quote returns a string literal of value.
+func (d Dialect) quote(value string) string {
+	value = strings.ReplaceAll(value, "'", "''")
+	if d == MySQL {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + value + "'"
+}
@@ -0,0 +1,275 @@
+// Package backup reads and writes logical backups: a gzip-compressed tar
+// file holding a manifest followed by one dump per service, made with the
+// service's native dump tool inside its container.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// ManifestName is the first entry of a backup archive
+	ManifestName = "dockenv-backup.json"
+
+	ManifestVersion = 1
+
+	// Extension is the file extension of backup archives
+	Extension = ".tar.gz"
+)
+
+// Manifest describes the contents of a backup archive.
+type Manifest struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Project  string    `json:"project"`
+	Services []Dump    `json:"services"`
+}
+
+// Dump is the dump of one service in a backup archive.
+type Dump struct {
+	Service string `json:"service"`
+	// Version is the image tag the service ran when it was dumped
+	Version string `json:"version"`
+	Tool    string `json:"tool"`
+	// File is the entry of the dump in the archive
+	File string `json:"file"`
+}
+
+// Method describes how the dump tool of a service is run in its container.
+type Method struct {
+	Tool string
+	// File is the name of the dump, e.g. "dump.sql"
+	File string
+	// Dump is a shell script that writes the dump to standard output
+	Dump string
+	// Restore is a shell script that reads the dump from standard input.
+	// When it is empty the dump is copied to RestorePath instead, while
+	// the service is stopped.
+	Restore     string
+	RestorePath string
+}
+
+// Methods holds the dump methods of the services that support logical
+// backups. The scripts read credentials from the container's environment.
+var Methods = map[string]Method{
+	"postgres": {
+		Tool:    "pg_dumpall",
+		File:    "dump.sql",
+		Dump:    `exec pg_dumpall --clean --if-exists -U "$POSTGRES_USER"`,
+		Restore: `exec psql -q -U "$POSTGRES_USER" -d postgres`,
+	},
+	"mysql": {
+		Tool:    "mysqldump",
+		File:    "dump.sql",
+		Dump:    `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" exec mysqldump -uroot --all-databases --single-transaction --routines --triggers --events`,
+		Restore: `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" exec mysql -uroot`,
+	},
+	"mongodb": {
+		Tool: "mongodump",
+		File: "dump.archive",
+		Dump: `exec mongodump --quiet --archive -u "$MONGO_INITDB_ROOT_USERNAME" -p "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin`,
+		Restore: `exec mongorestore --quiet --archive --drop -u "$MONGO_INITDB_ROOT_USERNAME" -p "$MONGO_INITDB_ROOT_PASSWORD" ` +
+			`--authenticationDatabase admin`,
+	},
+	"redis": {
+		Tool: "BGSAVE",
+		File: "dump.rdb",
+		// LASTSAVE has a resolution of one second, so wait for it to move
+		// past the time before the save started
+		Dump: `before=$(redis-cli LASTSAVE); sleep 1; redis-cli BGSAVE >/dev/null; ` +
+			`while [ "$(redis-cli LASTSAVE)" = "$before" ]; do sleep 1; done; exec cat /data/dump.rdb`,
+		RestorePath: "/data/dump.rdb",
+	},
+	"rabbitmq": {
+		Tool:    "rabbitmqctl export_definitions",
+		File:    "definitions.json",
+		Dump:    `exec rabbitmqctl -q export_definitions -`,
+		Restore: `exec rabbitmqctl -q import_definitions`,
+	},
+}
+
+// LookupMethod returns the dump method of a service.
+func LookupMethod(serviceName string) (Method, bool) {
+	method, exists := Methods[serviceName]
+	return method, exists
+}
+
+// FileName returns the name of a backup archive of a project made at
+// created, e.g. "shop-20240501-120000.tar.gz".
+func FileName(project string, created time.Time) string {
+	return project + "-" + created.Format("20060102-150405") + Extension
+}
+
+// Find returns the dump of a service.
+func (m *Manifest) Find(serviceName string) (Dump, bool) {
+	for _, dump := range m.Services {
+		if dump.Service == serviceName {
+			return dump, true
+		}
+	}
+	return Dump{}, false
+}
+
+// ServiceNames returns the services in the archive.
+func (m *Manifest) ServiceNames() []string {
+	names := make([]string, 0, len(m.Services))
+	for _, dump := range m.Services {
+		names = append(names, dump.Service)
+	}
+	return names
+}
+
+// Write writes a backup archive of the manifest and its dumps to w. The
+// dump files are read from dir under the name of each dump's File.
+func Write(w io.Writer, manifest *Manifest, dir string) error {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0644,
+		Size:    int64(len(manifestData)),
+		ModTime: manifest.Created,
+	}); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	for _, dump := range manifest.Services {
+		if err := writeFile(tw, filepath.Join(dir, filepath.FromSlash(dump.File)), dump.File, manifest.Created); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+func writeFile(tw *tar.Writer, file, name string, modTime time.Time) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    info.Size(),
+		ModTime: modTime,
+	}); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// Read reads a backup archive from r, returning its manifest. The dumps are
+// extracted into dir unless dir is empty, in which case only the manifest is
+// read.
+func Read(r io.Reader, dir string) (*Manifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a dockenv backup")
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != ManifestName {
+		return nil, fmt.Errorf("not a dockenv backup")
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse backup manifest: %w", err)
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	if dir == "" {
+		return &manifest, nil
+	}
+
+	expected := make(map[string]bool, len(manifest.Services))
+	for _, dump := range manifest.Services {
+		if !filepath.IsLocal(filepath.FromSlash(dump.File)) {
+			return nil, fmt.Errorf("invalid dump file in backup: %s", dump.File)
+		}
+		expected[dump.File] = true
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		// Only the dumps named in the manifest are extracted
+		if !expected[header.Name] {
+			continue
+		}
+		delete(expected, header.Name)
+
+		if err := extractFile(tr, filepath.Join(dir, filepath.FromSlash(header.Name))); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(expected) > 0 {
+		var missing []string
+		for name := range expected {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("backup is missing dumps: %v", missing)
+	}
+
+	return &manifest, nil
+}
+
+func extractFile(r io.Reader, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to extract dump: %w", err)
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to extract dump: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to extract dump: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,150 @@
+// Package bundle reads and writes offline image bundles: a tar file holding
+// the engine's archive of every image a project needs, together with a
+// manifest describing the configuration the bundle was made for.
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// ManifestName and ImagesName are the entries of a bundle, in order
+	ManifestName = "dockenv-bundle.json"
+	ImagesName   = "images.tar"
+
+	ManifestVersion = 1
+)
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Project  string    `json:"project"`
+	Services []string  `json:"services"`
+	Images   []string  `json:"images"`
+	// ComposeDigest is the digest of the Compose file the images were
+	// taken from, which ties the bundle to the configuration
+	ComposeDigest string `json:"compose_digest"`
+}
+
+// Digest returns the sha256 digest of data, e.g. "sha256:9f86d0...".
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Missing returns the images of required that are not in the bundle.
+func (m *Manifest) Missing(required []string) []string {
+	included := make(map[string]bool, len(m.Images))
+	for _, image := range m.Images {
+		included[image] = true
+	}
+
+	var missing []string
+	for _, image := range required {
+		if !included[image] {
+			missing = append(missing, image)
+		}
+	}
+	return missing
+}
+
+// Write writes a bundle of the manifest and the image archive at
+// imagesPath to w.
+func Write(w io.Writer, manifest *Manifest, imagesPath string) error {
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	images, err := os.Open(imagesPath)
+	if err != nil {
+		return fmt.Errorf("failed to open image archive: %w", err)
+	}
+	defer images.Close()
+
+	info, err := images.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read image archive: %w", err)
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0644,
+		Size:    int64(len(manifestData)),
+		ModTime: manifest.Created,
+	}); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(manifestData); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ImagesName,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: manifest.Created,
+	}); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := io.Copy(tw, images); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Read reads a bundle from r, returning its manifest. The image archive is
+// extracted to imagesPath unless imagesPath is empty, in which case only
+// the manifest is read.
+func Read(r io.Reader, imagesPath string) (*Manifest, error) {
+	tr := tar.NewReader(r)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != ManifestName {
+		return nil, fmt.Errorf("not a dockenv image bundle")
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+	if imagesPath == "" {
+		return &manifest, nil
+	}
+
+	header, err = tr.Next()
+	if err != nil || header.Name != ImagesName {
+		return nil, fmt.Errorf("bundle has no image archive")
+	}
+
+	images, err := os.Create(imagesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image archive: %w", err)
+	}
+	defer images.Close()
+
+	if _, err := io.Copy(images, tr); err != nil {
+		return nil, fmt.Errorf("failed to extract image archive: %w", err)
+	}
+	if err := images.Close(); err != nil {
+		return nil, fmt.Errorf("failed to extract image archive: %w", err)
+	}
+
+	return &manifest, nil
+}
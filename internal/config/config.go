@@ -4,23 +4,117 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const (
 	ConfigFileName  = "dockenv.yaml"
 	ComposeFileName = "docker-compose.dockenv.yaml"
 	EnvFileName     = ".env"
+	LockFileName    = "dockenv.lock"
 	SystemdService  = "dockenv.service"
 	DefaultDataPath = "/var/lib/dockenv"
+	DefaultHostIP   = "127.0.0.1"
+
+	// DefaultBindAddress keeps published ports off the network unless a
+	// project or service explicitly opts in
+	DefaultBindAddress = "127.0.0.1"
+	ExposeBindAddress  = "0.0.0.0"
+
+	// Storage values select where service data is kept: directories under
+	// the data path, or named volumes managed by the engine
+	StorageBind   = "bind"
+	StorageVolume = "volume"
+
+	// BackupsDir and SnapshotsDir are the directories under the data path
+	// backups and snapshots are kept in
+	BackupsDir   = "backups"
+	SnapshotsDir = "snapshots"
+
+	// ProjectDir holds per-project dockenv files such as custom templates
+	// and the init scripts of services
+	ProjectDir   = ".dockenv"
+	TemplatesDir = ProjectDir + "/templates"
+	InitDir      = ProjectDir + "/init"
+
+	// LegacyTemplatesDir is the deprecated location of custom templates,
+	// still read when TemplatesDir has no template for a service
+	LegacyTemplatesDir = "templates"
 )
 
 type Config struct {
-	Version  string            `yaml:"version"`
-	Services []string          `yaml:"services"`
-	Ports    map[string]int    `yaml:"ports,omitempty"`
-	Env      map[string]string `yaml:"env,omitempty"`
-	Volumes  map[string]string `yaml:"volumes,omitempty"`
-	DataPath string            `yaml:"data_path,omitempty"`
+	Version       string                  `yaml:"version"`
+	Services      []string                `yaml:"services"`
+	Ports         map[string]ServicePorts `yaml:"ports,omitempty"`
+	Versions      map[string]string       `yaml:"versions,omitempty"`
+	Env           map[string]string       `yaml:"env,omitempty"`
+	Volumes       map[string]string       `yaml:"volumes,omitempty"`
+	Resources     map[string]Resources    `yaml:"resources,omitempty"`
+	Secrets       map[string]string       `yaml:"secrets,omitempty"`
+	BindAddress   string                  `yaml:"bind_address,omitempty"`
+	BindAddresses map[string]string       `yaml:"bind_addresses,omitempty"`
+	Runtime       string                  `yaml:"runtime,omitempty"`
+	DataPath      string                  `yaml:"data_path,omitempty"`
+	Storage       string                  `yaml:"storage,omitempty"`
+	BranchData    bool                    `yaml:"branch_data,omitempty"`
+	Anonymize     Anonymize               `yaml:"anonymize,omitempty"`
+}
+
+// Anonymize configures how personal data in SQL dumps is replaced when they
+// are imported. Rules map table.column to the fake its values are replaced
+// with.
+type Anonymize struct {
+	Salt  string            `yaml:"salt,omitempty"`
+	Rules map[string]string `yaml:"rules,omitempty"`
+}
+
+// GetBindAddress returns the host address the ports of a service are
+// published on: the service's own setting, then the project's, then loopback.
+func (c *Config) GetBindAddress(serviceName string) string {
+	if address := c.BindAddresses[serviceName]; address != "" {
+		return address
+	}
+	if c.BindAddress != "" {
+		return c.BindAddress
+	}
+	return DefaultBindAddress
+}
+
+// GetPublishAddress returns the address the ports of a service are published
+// on by an engine on this machine or a remote one. Without a configured
+// address a remote engine publishes on all its interfaces, as its loopback
+// cannot be reached from here.
+func (c *Config) GetPublishAddress(serviceName string, remote bool) string {
+	if remote && c.BindAddresses[serviceName] == "" && c.BindAddress == "" {
+		return ExposeBindAddress
+	}
+	return c.GetBindAddress(serviceName)
+}
+
+// UsesVolumes reports whether service data is kept in named volumes rather
+// than under the data path. Unless storage is configured, remote engines use
+// volumes, as the data path names a directory on this machine.
+func (c *Config) UsesVolumes(remote bool) bool {
+	return c.Storage == StorageVolume || (c.Storage == "" && remote)
+}
+
+// GetBackupPath returns the directory backups are written to.
+func (c *Config) GetBackupPath() string {
+	return filepath.Join(c.DataPath, BackupsDir)
+}
+
+// GetSnapshotPath returns the directory snapshots are kept in.
+func (c *Config) GetSnapshotPath() string {
+	return filepath.Join(c.DataPath, SnapshotsDir)
+}
+
+// GetRuntime returns the Compose runtime to use: DOCKENV_RUNTIME if set, the
+// configured runtime otherwise. Empty means detect one.
+func (c *Config) GetRuntime() string {
+	if runtime := os.Getenv("DOCKENV_RUNTIME"); runtime != "" {
+		return runtime
+	}
+	return c.Runtime
 }
 
 func GetConfigPath() string {
@@ -36,10 +130,43 @@ func GetConfigPath() string {
 	return filepath.Join(homeDir, ".config", "dockenv", ConfigFileName)
 }
 
+// GetLockPath returns the path of the lock file, which is kept next to the
+// configuration it pins.
+func GetLockPath() string {
+	return filepath.Join(filepath.Dir(GetConfigPath()), LockFileName)
+}
+
 func GetComposePath() string {
 	return "./" + ComposeFileName
 }
 
+// GetProjectName returns the Compose project name for the current directory,
+// honouring COMPOSE_PROJECT_NAME the same way Compose does.
+func GetProjectName() string {
+	if name := os.Getenv("COMPOSE_PROJECT_NAME"); name != "" {
+		return name
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "dockenv"
+	}
+
+	// Compose lowercases the directory name and drops unsupported characters
+	var name strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(cwd)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			name.WriteRune(r)
+		}
+	}
+
+	projectName := strings.TrimLeft(name.String(), "_-")
+	if projectName == "" {
+		return "dockenv"
+	}
+	return projectName
+}
+
 func GetDataPath() string {
 	if dataPath := os.Getenv("DOCKENV_DATA"); dataPath != "" {
 		return dataPath
@@ -0,0 +1,32 @@
+package config
+
+import (
+	yaml "gopkg.in/yaml.v3"
+)
+
+// LegacyPortName is the key a bare port number from an older configuration
+// (e.g. "mysql: 3306") is stored under until it is mapped to the service's
+// primary port.
+const LegacyPortName = "default"
+
+// ServicePorts maps a service's named ports to host ports, e.g.
+// {"amqp": 5672, "management": 25672} for RabbitMQ.
+type ServicePorts map[string]int
+
+func (p *ServicePorts) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var port int
+		if err := node.Decode(&port); err != nil {
+			return err
+		}
+		*p = ServicePorts{LegacyPortName: port}
+		return nil
+	}
+
+	var ports map[string]int
+	if err := node.Decode(&ports); err != nil {
+		return err
+	}
+	*p = ports
+	return nil
+}
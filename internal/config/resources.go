@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Resources holds the per-service limits rendered into the compose file.
+type Resources struct {
+	Memory string  `yaml:"memory,omitempty"`
+	CPUs   float64 `yaml:"cpus,omitempty"`
+}
+
+// MinMemory is the smallest memory limit accepted; containers fail to start
+// with less.
+const MinMemory = 4 << 20
+
+var memoryUnits = map[string]int64{"k": 1 << 10, "m": 1 << 20, "g": 1 << 30}
+
+// ParseMemory converts a memory size such as "512m", "2g" or "1.5GiB" into
+// bytes. A plain number is interpreted as megabytes and a trailing "b" alone
+// as bytes. Sizes below MinMemory are rejected.
+func ParseMemory(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+
+	multiplier := int64(1 << 20)
+	if trimmed, found := strings.CutSuffix(s, "ib"); found {
+		s = trimmed
+	} else if trimmed, found := strings.CutSuffix(s, "b"); found {
+		s = trimmed
+		multiplier = 1
+	}
+	if unit, exists := memoryUnits[s[max(0, len(s)-1):]]; exists {
+		multiplier = unit
+		s = s[:len(s)-1]
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(number > 0) {
+		return 0, fmt.Errorf("invalid memory size: %s (expected e.g. 512m or 2g)", value)
+	}
+
+	bytes := int64(number * float64(multiplier))
+	if bytes < MinMemory {
+		return 0, fmt.Errorf("memory size %s is below the minimum of %s", value, FormatMemory(MinMemory))
+	}
+	return bytes, nil
+}
+
+// FormatMemory renders a byte count in the largest whole unit, e.g. "2g".
+// Sizes below a megabyte are rounded up to whole kilobytes, so they never
+// render as zero.
+func FormatMemory(bytes int64) string {
+	switch {
+	case bytes >= 1<<30 && bytes%(1<<30) == 0:
+		return fmt.Sprintf("%dg", bytes>>30)
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%dm", bytes>>20)
+	default:
+		return fmt.Sprintf("%dk", max(1, (bytes+1<<10-1)>>10))
+	}
+}
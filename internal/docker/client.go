@@ -0,0 +1,408 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+)
+
+// Client talks to the Docker Engine API over a unix socket. Podman serves a
+// compatible API on its own socket, so the same client works for both.
+type Client struct {
+	socketPath string
+	http       *http.Client
+}
+
+// EngineVersion is the engine's reply to GET /version.
+type EngineVersion struct {
+	Version    string `json:"Version"`
+	APIVersion string `json:"ApiVersion"`
+	Os         string `json:"Os"`
+	Arch       string `json:"Arch"`
+	Components []struct {
+		Name string `json:"Name"`
+	} `json:"Components"`
+}
+
+// Product returns the engine's name, e.g. "Docker" or "Podman".
+func (v EngineVersion) Product() string {
+	for _, component := range v.Components {
+		if strings.HasPrefix(component.Name, "Podman") {
+			return "Podman"
+		}
+	}
+	return "Docker"
+}
+
+// Event is a container event of the engine's event stream.
+type Event struct {
+	// Action is e.g. start, die or "health_status: healthy"
+	Action    string
+	Container string
+	Service   string
+	Time      time.Time
+}
+
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+
+	return &Client{
+		socketPath: socketPath,
+		http:       &http.Client{Transport: transport},
+	}
+}
+
+// NewClientFromEnv returns a client for the socket of the current runtime's
+// engine. Endpoints other than unix sockets, e.g. ssh:// or tcp:// from
+// DOCKER_HOST or a Docker context, are not supported by the client; callers
+// fall back to the CLI, which is also used in recording mode so that queries
+// show up in the record.
+func NewClientFromEnv() (*Client, error) {
+	if runner.Recording() {
+		return nil, fmt.Errorf("the engine API is not used in dry-run mode")
+	}
+
+	engine := "docker"
+	if rt, err := CurrentRuntime(); err == nil {
+		engine = rt.Engine()
+	}
+
+	socketPath := SocketPath(engine)
+	if socketPath == "" {
+		c, _ := ResolveContext(engine)
+		return nil, fmt.Errorf("unsupported engine host: %s", c.Host)
+	}
+	if !SocketAvailable(socketPath) {
+		return nil, fmt.Errorf("engine socket not found: %s", socketPath)
+	}
+	return NewClient(socketPath), nil
+}
+
+func (c *Client) SocketPath() string {
+	return c.socketPath
+}
+
+// Ping checks that the engine answers on the socket.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.get(ctx, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *Client) Version(ctx context.Context) (*EngineVersion, error) {
+	var version EngineVersion
+	if err := c.getJSON(ctx, "/version", nil, &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// apiContainer is an entry of GET /containers/json.
+type apiContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Ports  []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// ListContainers returns the containers of a Compose project, including
+// stopped ones, sorted by service.
+func (c *Client) ListContainers(ctx context.Context, project string) ([]Container, error) {
+	entries, err := c.listContainers(ctx, map[string][]string{"label": {ProjectLabel + "=" + project}})
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]Container, 0, len(entries))
+	for _, entry := range entries {
+		container := Container{
+			Service: entry.Labels[ServiceLabel],
+			State:   entry.State,
+			Status:  entry.Status,
+			Health:  parseHealth(entry.Status),
+			Uptime:  parseUptime(entry.Status),
+		}
+		if len(entry.Names) > 0 {
+			container.Name = strings.TrimPrefix(entry.Names[0], "/")
+		}
+		for _, port := range entry.Ports {
+			if port.PublicPort == 0 {
+				continue
+			}
+			container.Ports = append(container.Ports, PortBinding{
+				HostIP:        port.IP,
+				HostPort:      port.PublicPort,
+				ContainerPort: port.PrivatePort,
+				Protocol:      port.Type,
+			})
+		}
+		containers = append(containers, container)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Service < containers[j].Service
+	})
+	return containers, nil
+}
+
+// PublishedPorts returns the host ports bound by all containers. Stopped
+// containers are inspected for their configured bindings, which they claim
+// again once started.
+func (c *Client) PublishedPorts(ctx context.Context) ([]PublishedPort, error) {
+	entries, err := c.listContainers(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var ports []PublishedPort
+	for _, entry := range entries {
+		var details struct {
+			Name       string `json:"Name"`
+			HostConfig struct {
+				PortBindings map[string][]struct {
+					HostPort string `json:"HostPort"`
+				} `json:"PortBindings"`
+			} `json:"HostConfig"`
+		}
+		if err := c.getJSON(ctx, "/containers/"+entry.ID+"/json", nil, &details); err != nil {
+			return nil, err
+		}
+
+		for _, bindings := range details.HostConfig.PortBindings {
+			for _, binding := range bindings {
+				var hostPort int
+				if _, err := fmt.Sscan(binding.HostPort, &hostPort); err != nil || hostPort == 0 {
+					continue
+				}
+				ports = append(ports, PublishedPort{
+					HostPort:  hostPort,
+					Container: strings.TrimPrefix(details.Name, "/"),
+					Project:   entry.Labels[ProjectLabel],
+				})
+			}
+		}
+	}
+	return ports, nil
+}
+
+// ContainerHealth returns the health check state of a container: healthy,
+// unhealthy or starting, or an empty string without a health check.
+func (c *Client) ContainerHealth(ctx context.Context, container string) (string, error) {
+	var details struct {
+		State struct {
+			Health *struct {
+				Status string `json:"Status"`
+			} `json:"Health"`
+		} `json:"State"`
+	}
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(container)+"/json", nil, &details); err != nil {
+		return "", err
+	}
+
+	if details.State.Health == nil {
+		return "", nil
+	}
+	return details.State.Health.Status, nil
+}
+
+// Events streams the container events of a Compose project to handle until
+// handle returns false, the context is cancelled or the stream ends.
+func (c *Client) Events(ctx context.Context, project string, handle func(Event) bool) error {
+	filters, err := json.Marshal(map[string][]string{
+		"type":  {"container"},
+		"label": {ProjectLabel + "=" + project},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.get(ctx, "/events", url.Values{"filters": {string(filters)}})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Action string `json:"Action"`
+			Actor  struct {
+				Attributes map[string]string `json:"Attributes"`
+			} `json:"Actor"`
+			TimeNano int64 `json:"timeNano"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to read engine events: %w", err)
+		}
+
+		event := Event{
+			Action:    message.Action,
+			Container: message.Actor.Attributes["name"],
+			Service:   message.Actor.Attributes[ServiceLabel],
+			Time:      time.Unix(0, message.TimeNano),
+		}
+		if !handle(event) {
+			return nil
+		}
+	}
+}
+
+func (c *Client) listContainers(ctx context.Context, filters map[string][]string) ([]apiContainer, error) {
+	query := url.Values{"all": {"1"}}
+	if len(filters) > 0 {
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(encoded))
+	}
+
+	var entries []apiContainer
+	if err := c.getJSON(ctx, "/containers/json", query, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode engine response for %s: %w", path, err)
+	}
+	return nil
+}
+
+// get performs a request against the engine. Error responses are turned into
+// errors carrying the engine's message.
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	// The host is ignored; requests always go to the socket
+	target := "http://engine" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach engine at %s: %w", c.socketPath, runner.Err(ctx, err))
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Message == "" {
+			body.Message = resp.Status
+		}
+		return nil, fmt.Errorf("engine API %s: %s", path, body.Message)
+	}
+
+	return resp, nil
+}
+
+// WaitHealthy waits until the containers of the given services are running
+// and pass their health checks, re-checking on every project event. It fails
+// as soon as one of them exits or turns unhealthy.
+func (c *Client) WaitHealthy(ctx context.Context, project string, services []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan Event)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- c.Events(ctx, project, func(event Event) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	for {
+		healthy, err := c.servicesHealthy(ctx, project, services)
+		if err != nil || healthy {
+			return err
+		}
+
+		// Events trigger a re-check; the ticker covers events sent before
+		// the stream was established
+		select {
+		case <-events:
+		case err := <-streamErr:
+			if err == nil {
+				err = fmt.Errorf("engine event stream closed")
+			}
+			return err
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Client) servicesHealthy(ctx context.Context, project string, services []string) (bool, error) {
+	containers, err := c.ListContainers(ctx, project)
+	if err != nil {
+		return false, err
+	}
+
+	byService := make(map[string]Container)
+	for _, container := range containers {
+		byService[container.Service] = container
+	}
+
+	healthy := true
+	for _, service := range services {
+		container, exists := byService[service]
+		switch {
+		case !exists:
+			healthy = false
+		case container.State == "exited" || container.State == "dead":
+			return false, fmt.Errorf("%s exited: %s", service, container.Status)
+		case container.Health == "unhealthy":
+			return false, fmt.Errorf("%s is unhealthy", service)
+		case !container.Healthy():
+			healthy = false
+		}
+	}
+	return healthy, nil
+}
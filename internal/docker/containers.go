@@ -0,0 +1,330 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+)
+
+// ServiceLabel is the label Compose puts on a container with its service name.
+const ServiceLabel = "com.docker.compose.service"
+
+// PortBinding is a container port published on the host.
+type PortBinding struct {
+	HostIP        string `json:"host_ip" yaml:"host_ip"`
+	HostPort      int    `json:"host_port" yaml:"host_port"`
+	ContainerPort int    `json:"container_port" yaml:"container_port"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+}
+
+// Container is a container of a Compose project.
+type Container struct {
+	Name    string `json:"name" yaml:"name"`
+	Service string `json:"service" yaml:"service"`
+	State   string `json:"state" yaml:"state"`
+	// Health is healthy, unhealthy or starting, and empty for containers
+	// without a health check
+	Health string        `json:"health,omitempty" yaml:"health,omitempty"`
+	Status string        `json:"status" yaml:"status"`
+	Uptime string        `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	Ports  []PortBinding `json:"ports,omitempty" yaml:"ports,omitempty"`
+}
+
+// Running reports whether the container is up.
+func (c Container) Running() bool {
+	return c.State == "running"
+}
+
+// Healthy reports whether the container is running and, if it has a health
+// check, passing it.
+func (c Container) Healthy() bool {
+	return c.Running() && (c.Health == "" || c.Health == "healthy")
+}
+
+// ComposeContainers returns the containers of the project defined by the
+// Compose file, including stopped ones, sorted by service. It asks the engine
+// API and falls back to compose ps, then to the engine's own listing for
+// runtimes whose ps has no JSON output.
+func ComposeContainers(ctx context.Context, file, project string) ([]Container, error) {
+	if client, err := NewClientFromEnv(); err == nil {
+		apiCtx, cancel := context.WithTimeout(ctx, apiTimeout)
+		defer cancel()
+		if containers, err := client.ListContainers(apiCtx, project); err == nil {
+			return containers, nil
+		}
+	}
+
+	ctx, cancel := runner.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	containers, err := composePS(ctx, file)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if containers, err = listContainers(ctx, project); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Service < containers[j].Service
+	})
+	return containers, nil
+}
+
+func composePS(ctx context.Context, file string) ([]Container, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"-f", file, "ps", "--format", "json"}
+	// podman-compose lists stopped containers by default and rejects -a
+	if rt.Name() != "podman-compose" {
+		args = append(args, "-a")
+	}
+
+	out, err := rt.Command(ctx, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s ps failed: %w", rt.Name(), err)
+	}
+	return ParseContainers(out)
+}
+
+func listContainers(ctx context.Context, project string) ([]Container, error) {
+	out, err := engineCommand(ctx, "ps", "-a", "--filter", "label="+ProjectLabel+"="+project, "--format", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return ParseContainers(out)
+}
+
+// ParseContainers parses compose ps or engine ps output in JSON format.
+// Compose prints service, health and publishers directly, one object per line
+// or, in older versions, as an array. docker ps prints names, labels and ports
+// as strings, podman ps as lists and maps. All of these are accepted.
+func ParseContainers(data []byte) ([]Container, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var raw []rawContainer
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse container list: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var entry rawContainer
+			if err := decoder.Decode(&entry); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to parse container list: %w", err)
+			}
+			raw = append(raw, entry)
+		}
+	}
+
+	containers := make([]Container, 0, len(raw))
+	for _, entry := range raw {
+		container, err := entry.container()
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// rawContainer holds the fields of all supported formats; fields whose type
+// differs between formats are decoded later.
+type rawContainer struct {
+	Name       string          `json:"Name"`
+	Service    string          `json:"Service"`
+	Health     string          `json:"Health"`
+	Publishers []rawPublisher  `json:"Publishers"`
+	Names      json.RawMessage `json:"Names"`
+	Labels     json.RawMessage `json:"Labels"`
+	Ports      json.RawMessage `json:"Ports"`
+	State      string          `json:"State"`
+	Status     string          `json:"Status"`
+}
+
+// rawPublisher is a published port as printed by compose ps.
+type rawPublisher struct {
+	URL           string `json:"URL"`
+	TargetPort    int    `json:"TargetPort"`
+	PublishedPort int    `json:"PublishedPort"`
+	Protocol      string `json:"Protocol"`
+}
+
+func (r rawContainer) container() (Container, error) {
+	container := Container{
+		Name:    r.Name,
+		Service: r.Service,
+		State:   strings.ToLower(r.State),
+		Health:  r.Health,
+		Status:  r.Status,
+		Uptime:  parseUptime(r.Status),
+	}
+	if container.Health == "" {
+		container.Health = parseHealth(r.Status)
+	}
+
+	// Names: "a,b" (docker ps) or ["a", "b"] (podman ps)
+	if container.Name == "" && len(r.Names) > 0 {
+		var names []string
+		var name string
+		if err := json.Unmarshal(r.Names, &names); err == nil && len(names) > 0 {
+			container.Name = names[0]
+		} else if err := json.Unmarshal(r.Names, &name); err == nil {
+			container.Name, _, _ = strings.Cut(name, ",")
+		}
+	}
+
+	// Labels: "k=v,k=v" (docker ps) or {"k": "v"} (podman ps)
+	if container.Service == "" && len(r.Labels) > 0 {
+		labels := make(map[string]string)
+		var labelString string
+		if err := json.Unmarshal(r.Labels, &labels); err != nil {
+			if err := json.Unmarshal(r.Labels, &labelString); err == nil {
+				for _, pair := range strings.Split(labelString, ",") {
+					if key, value, found := strings.Cut(pair, "="); found {
+						labels[key] = value
+					}
+				}
+			}
+		}
+		container.Service = labels[ServiceLabel]
+	}
+
+	// Compose lists unpublished ports with a zero published port
+	for _, publisher := range r.Publishers {
+		if publisher.PublishedPort == 0 {
+			continue
+		}
+		container.Ports = append(container.Ports, PortBinding{
+			HostIP:        publisher.URL,
+			HostPort:      publisher.PublishedPort,
+			ContainerPort: publisher.TargetPort,
+			Protocol:      publisher.Protocol,
+		})
+	}
+
+	// Ports: "127.0.0.1:3306->3306/tcp" (docker ps) or a list (podman ps)
+	var portString string
+	if len(r.Publishers) > 0 || len(r.Ports) == 0 || string(r.Ports) == "null" {
+		return container, nil
+	}
+	if err := json.Unmarshal(r.Ports, &portString); err == nil {
+		container.Ports = ParsePortString(portString)
+	} else if err := json.Unmarshal(r.Ports, &container.Ports); err != nil {
+		return container, fmt.Errorf("failed to parse ports of %s: %w", container.Name, err)
+	}
+
+	return container, nil
+}
+
+// parseHealth extracts the health state from a status such as
+// "Up 5 minutes (healthy)".
+func parseHealth(status string) string {
+	for _, health := range []string{"unhealthy", "healthy", "health: starting"} {
+		if strings.Contains(status, "("+health+")") {
+			return strings.TrimPrefix(health, "health: ")
+		}
+	}
+	return ""
+}
+
+// parseUptime extracts the uptime from a status such as
+// "Up 5 minutes (healthy)"; it is empty for stopped containers.
+func parseUptime(status string) string {
+	uptime, found := strings.CutPrefix(status, "Up ")
+	if !found {
+		return ""
+	}
+	if index := strings.Index(uptime, " ("); index >= 0 {
+		uptime = uptime[:index]
+	}
+	return strings.TrimSpace(uptime)
+}
+
+// ParsePortString parses Docker's port summary, e.g.
+// "127.0.0.1:3306->3306/tcp, [::1]:3306->3306/tcp, 33060/tcp". Ports that
+// are not published are skipped.
+func ParsePortString(ports string) []PortBinding {
+	var bindings []PortBinding
+	for _, entry := range strings.Split(ports, ",") {
+		host, target, found := strings.Cut(strings.TrimSpace(entry), "->")
+		if !found {
+			continue
+		}
+
+		separator := strings.LastIndex(host, ":")
+		if separator < 0 {
+			continue
+		}
+		hostPort, err := strconv.Atoi(host[separator+1:])
+		if err != nil {
+			continue
+		}
+
+		containerPort, protocol, _ := strings.Cut(target, "/")
+		port, err := strconv.Atoi(containerPort)
+		if err != nil {
+			continue
+		}
+
+		bindings = append(bindings, PortBinding{
+			HostIP:        strings.Trim(host[:separator], "[]"),
+			HostPort:      hostPort,
+			ContainerPort: port,
+			Protocol:      protocol,
+		})
+	}
+	return bindings
+}
+
+// RemoveContainers removes containers, stopping them first if they run.
+func RemoveContainers(ctx context.Context, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	cmd := engineCommand(ctx, append([]string{"rm", "-f"}, names...)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove containers: %w", err)
+	}
+	return nil
+}
+
+// RemoveVolume removes a named volume, which no container may use. A volume
+// that does not exist is left alone, as Compose only creates volumes when a
+// service first starts.
+func RemoveVolume(ctx context.Context, name string) error {
+	if !VolumeExists(ctx, name) {
+		return nil
+	}
+	cmd := engineCommand(ctx, "volume", "rm", name)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// VolumeExists reports whether a named volume exists.
+func VolumeExists(ctx context.Context, name string) bool {
+	return engineCommand(ctx, "volume", "inspect", name).Run() == nil
+}
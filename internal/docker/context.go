@@ -0,0 +1,164 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// DefaultContext is the name of the Docker context that talks to the local
+// engine, or to DOCKER_HOST when set.
+const DefaultContext = "default"
+
+// Context is the engine endpoint the CLI talks to, as chosen by DOCKER_HOST
+// or the active Docker context.
+type Context struct {
+	Name string
+	// Host is the endpoint, e.g. unix:///var/run/docker.sock or
+	// ssh://user@devvm
+	Host string
+}
+
+// ResolveContext returns the endpoint of the given engine the way its CLI
+// picks it. For Docker that is DOCKER_HOST, then the context named by
+// DOCKER_CONTEXT or by currentContext in the CLI configuration; for Podman
+// it is CONTAINER_HOST. The local socket is used otherwise.
+func ResolveContext(engine string) (Context, error) {
+	local := Context{Name: DefaultContext, Host: "unix://" + localSocketPath(engine)}
+
+	if engine == "podman" {
+		if host := os.Getenv("CONTAINER_HOST"); host != "" {
+			return Context{Name: DefaultContext, Host: host}, nil
+		}
+		return local, nil
+	}
+
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return Context{Name: DefaultContext, Host: host}, nil
+	}
+
+	name := os.Getenv("DOCKER_CONTEXT")
+	if name == "" {
+		name = currentDockerContext()
+	}
+	if name == "" || name == DefaultContext {
+		return local, nil
+	}
+
+	host, err := dockerContextHost(name)
+	if err != nil {
+		return local, err
+	}
+	return Context{Name: name, Host: host}, nil
+}
+
+// CurrentContext returns the endpoint of the current runtime's engine. A
+// context that cannot be read is reported as the local engine; the CLI
+// reports the actual error when it is run.
+func CurrentContext() Context {
+	engine := "docker"
+	if rt, err := CurrentRuntime(); err == nil {
+		engine = rt.Engine()
+	}
+
+	c, _ := ResolveContext(engine)
+	return c
+}
+
+// Remote reports whether the engine runs on another machine, i.e. whether
+// paths and published ports refer to that machine rather than this one.
+func (c Context) Remote() bool {
+	return c.Hostname() != ""
+}
+
+// Hostname returns the machine the engine runs on, or an empty string for
+// an engine on this machine.
+func (c Context) Hostname() string {
+	u, err := url.Parse(c.Host)
+	if err != nil {
+		return ""
+	}
+
+	switch u.Scheme {
+	case "ssh", "tcp", "http", "https":
+	default:
+		return ""
+	}
+
+	hostname := u.Hostname()
+	if hostname == "" || hostname == "localhost" {
+		return ""
+	}
+	if ip := net.ParseIP(hostname); ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return hostname
+}
+
+func (c Context) String() string {
+	return fmt.Sprintf("%s (%s)", c.Name, c.Host)
+}
+
+// dockerConfigDir returns the Docker CLI configuration directory.
+func dockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(homeDir, ".docker")
+}
+
+// currentDockerContext returns the context selected with 'docker context
+// use', or an empty string if there is none.
+func currentDockerContext() string {
+	data, err := os.ReadFile(filepath.Join(dockerConfigDir(), "config.json"))
+	if err != nil {
+		return ""
+	}
+
+	var cliConfig struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &cliConfig); err != nil {
+		return ""
+	}
+	return cliConfig.CurrentContext
+}
+
+// dockerContextHost reads the engine endpoint of a named context from its
+// metadata, which the CLI stores under a digest of the name.
+func dockerContextHost(name string) (string, error) {
+	digest := sha256.Sum256([]byte(name))
+	metaPath := filepath.Join(dockerConfigDir(), "contexts", "meta", hex.EncodeToString(digest[:]), "meta.json")
+
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("docker context %q not found", name)
+		}
+		return "", fmt.Errorf("failed to read docker context %q: %w", name, err)
+	}
+
+	var meta struct {
+		Endpoints map[string]struct {
+			Host string `json:"Host"`
+		} `json:"Endpoints"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", fmt.Errorf("failed to parse docker context %q: %w", name, err)
+	}
+
+	host := meta.Endpoints["docker"].Host
+	if host == "" {
+		return "", fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+	return host, nil
+}
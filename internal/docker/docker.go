@@ -1,58 +1,136 @@
 package docker
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/runner"
 )
 
+// apiTimeout bounds engine API requests made for quick checks.
+const apiTimeout = 3 * time.Second
+
 type DockerInfo struct {
 	DockerInstalled        bool
 	DockerComposeInstalled bool
 	DockerVersion          string
 	ComposeVersion         string
 	DockerRunning          bool
+
+	// Runtime is the selected Compose backend and Engine the container CLI
+	// it drives; empty when no backend is available
+	Runtime string
+	Engine  string
+
+	// Context is the endpoint the engine CLI talks to
+	Context Context
+
+	SocketPath      string
+	SocketAvailable bool
+	// Rootless is set for Podman running without root privileges
+	Rootless bool
 }
 
-func CheckDocker() (*DockerInfo, error) {
-	info := &DockerInfo{}
+// CheckDocker reports the state of the Compose runtime and its engine. The
+// engine checks are bounded by probeTimeout as well as by ctx.
+func CheckDocker(ctx context.Context) (*DockerInfo, error) {
+	if runner.Recording() {
+		return recordingInfo()
+	}
 
-	// Check if Docker is installed
-	if dockerCmd, err := exec.LookPath("docker"); err == nil && dockerCmd != "" {
-		info.DockerInstalled = true
+	info := &DockerInfo{Engine: "docker"}
 
-		// Get Docker version
-		if out, err := exec.Command("docker", "--version").Output(); err == nil {
-			info.DockerVersion = strings.TrimSpace(string(out))
+	// Select the Compose backend
+	if rt, err := CurrentRuntime(); err == nil {
+		info.Runtime = rt.Name()
+		info.Engine = rt.Engine()
+		info.DockerComposeInstalled = true
+		info.ComposeVersion, _ = rt.Version(ctx)
+	} else if runtimePreference != "" {
+		rt, exists := GetRuntime(runtimePreference)
+		if !exists {
+			return nil, err
 		}
+		info.Runtime = rt.Name()
+		info.Engine = rt.Engine()
+	}
 
-		// Check if Docker daemon is running
-		if err := exec.Command("docker", "info").Run(); err == nil {
+	info.Context, _ = ResolveContext(info.Engine)
+	info.SocketPath = SocketPath(info.Engine)
+	info.SocketAvailable = SocketAvailable(info.SocketPath)
+
+	// Ask the engine over its socket, which avoids forking the CLI
+	if client, err := NewClientFromEnv(); err == nil {
+		apiCtx, cancel := context.WithTimeout(ctx, apiTimeout)
+		defer cancel()
+		if version, err := client.Version(apiCtx); err == nil {
+			info.DockerInstalled = true
 			info.DockerRunning = true
+			info.DockerVersion = fmt.Sprintf("%s version %s (API %s)", version.Product(), version.Version, version.APIVersion)
+			info.Rootless = info.Engine == "podman" && os.Geteuid() != 0
+			return info, nil
 		}
 	}
 
-	// Check if Docker Compose is installed
-	if composeCmd, err := exec.LookPath("docker-compose"); err == nil && composeCmd != "" {
-		info.DockerComposeInstalled = true
+	ctx, cancel := runner.WithTimeout(ctx, probeTimeout)
+	defer cancel()
 
-		// Get Docker Compose version
-		if out, err := exec.Command("docker-compose", "--version").Output(); err == nil {
-			info.ComposeVersion = strings.TrimSpace(string(out))
+	// Check if the container engine is installed
+	if engineCmd, err := exec.LookPath(info.Engine); err == nil && engineCmd != "" {
+		info.DockerInstalled = true
+
+		// Get engine version
+		if out, err := runner.Command(ctx, info.Engine, "--version").Output(); err == nil {
+			info.DockerVersion = strings.TrimSpace(string(out))
 		}
-	} else {
-		// Check for docker compose (newer syntax)
-		if err := exec.Command("docker", "compose", "version").Run(); err == nil {
-			info.DockerComposeInstalled = true
-			if out, err := exec.Command("docker", "compose", "version").Output(); err == nil {
-				info.ComposeVersion = strings.TrimSpace(string(out))
-			}
+
+		// Check if the engine is running
+		if info.Engine == "podman" {
+			info.DockerRunning, info.Rootless = podmanInfo(ctx)
+		} else if err := runner.Command(ctx, info.Engine, "info").Run(); err == nil {
+			info.DockerRunning = true
 		}
 	}
 
+	// A hung daemon or an interrupt must not be reported as a missing engine
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
 	return info, nil
 }
 
+// recordingInfo reports a ready runtime in recording mode, where no engine
+// is needed.
+func recordingInfo() (*DockerInfo, error) {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	c, _ := ResolveContext(rt.Engine())
+	return &DockerInfo{
+		DockerInstalled:        true,
+		DockerComposeInstalled: true,
+		DockerRunning:          true,
+		DockerVersion:          rt.Engine() + " (dry run, commands are printed instead of run)",
+		ComposeVersion:         rt.Name() + " (dry run)",
+		Runtime:                rt.Name(),
+		Engine:                 rt.Engine(),
+		Context:                c,
+		SocketPath:             SocketPath(rt.Engine()),
+	}, nil
+}
+
+// Remote reports whether the engine runs on another machine.
+func (d *DockerInfo) Remote() bool {
+	return d.Context.Remote()
+}
+
 func (d *DockerInfo) IsReady() bool {
 	return d.DockerInstalled && d.DockerComposeInstalled && d.DockerRunning
 }
@@ -60,72 +138,179 @@ func (d *DockerInfo) IsReady() bool {
 func (d *DockerInfo) GetInstallInstructions() string {
 	var instructions []string
 
-	if !d.DockerInstalled {
+	if d.Engine == "podman" {
+		if !d.DockerInstalled {
+			instructions = append(instructions, "Podman is not installed. Please install Podman from https://podman.io/docs/installation")
+		} else if !d.DockerRunning {
+			instructions = append(instructions, "Podman is not working. Run 'podman info' for details.")
+		}
+	} else if !d.DockerInstalled {
 		instructions = append(instructions, "Docker is not installed. Please install Docker from https://docs.docker.com/get-docker/")
 	} else if !d.DockerRunning {
 		instructions = append(instructions, "Docker daemon is not running. Please start Docker.")
 	}
 
 	if !d.DockerComposeInstalled {
-		instructions = append(instructions, "Docker Compose is not installed. Please install Docker Compose from https://docs.docker.com/compose/install/")
+		if d.Runtime != "" {
+			instructions = append(instructions, fmt.Sprintf("The configured runtime %s is not installed. Install it or remove 'runtime' from the configuration.", d.Runtime))
+		} else {
+			instructions = append(instructions, "Docker Compose is not installed. Please install Docker Compose from https://docs.docker.com/compose/install/")
+		}
 	}
 
 	if len(instructions) == 0 {
+		if d.Engine == "podman" {
+			return "Podman and its Compose runtime are properly installed and running!"
+		}
 		return "Docker and Docker Compose are properly installed and running!"
 	}
 
 	return strings.Join(instructions, "\n")
 }
 
-func RunCompose(args ...string) error {
-	// Try docker compose first (newer syntax)
-	cmd := exec.Command("docker", append([]string{"compose"}, args...)...)
+// Warnings returns problems that do not stop dockenv from running but may
+// break individual features.
+func (d *DockerInfo) Warnings() []string {
+	var warnings []string
+
+	// podman compose hands the Compose file to a provider that talks to the
+	// Podman API socket, which is not enabled by default. Remote engines
+	// are reached without a local socket.
+	if d.Runtime == "podman" && !d.SocketAvailable && !d.Remote() {
+		enable := "systemctl --user enable --now podman.socket"
+		if !d.Rootless {
+			enable = "sudo systemctl enable --now podman.socket"
+		}
+		warnings = append(warnings, fmt.Sprintf("Podman socket not found at %s. Enable it with: %s", d.SocketPath, enable))
+	}
+
+	return warnings
+}
+
+// RunCompose runs Compose with the given arguments through the current
+// runtime. Failures are reported as they come from that backend; the command
+// is never retried with another one. When ctx ends, Compose is signalled and
+// the error is the context's cause, e.g. a *runner.TimeoutError.
+func RunCompose(ctx context.Context, args ...string) error {
+	rt, err := CurrentRuntime()
+	if err != nil {
+		return err
+	}
+
+	cmd := rt.Command(ctx, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	if err := cmd.Run(); err != nil {
-		// Fallback to docker-compose
-		cmd = exec.Command("docker-compose", args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		return cmd.Run()
+		return fmt.Errorf("%s failed: %w", rt.Name(), err)
 	}
 
 	return nil
 }
 
-func ComposeUp(file string, services ...string) error {
+func ComposeUp(ctx context.Context, file string, services ...string) error {
 	args := []string{"-f", file, "up", "-d"}
 	if len(services) > 0 {
 		args = append(args, services...)
 	}
-	return RunCompose(args...)
+	return RunCompose(ctx, args...)
 }
 
-func ComposeDown(file string) error {
-	return RunCompose("-f", file, "down")
+func ComposeDown(ctx context.Context, file string) error {
+	return RunCompose(ctx, "-f", file, "down")
 }
 
-func ComposeRestart(file string, services ...string) error {
+func ComposeRestart(ctx context.Context, file string, services ...string) error {
 	args := []string{"-f", file, "restart"}
 	if len(services) > 0 {
 		args = append(args, services...)
 	}
-	return RunCompose(args...)
+	return RunCompose(ctx, args...)
+}
+
+func ComposeStop(ctx context.Context, file string, services ...string) error {
+	args := []string{"-f", file, "stop"}
+	if len(services) > 0 {
+		args = append(args, services...)
+	}
+	return RunCompose(ctx, args...)
+}
+
+func ComposeStart(ctx context.Context, file string, services ...string) error {
+	args := []string{"-f", file, "start"}
+	if len(services) > 0 {
+		args = append(args, services...)
+	}
+	return RunCompose(ctx, args...)
 }
 
-func ComposeStatus(file string) error {
-	return RunCompose("-f", file, "ps")
+func ComposeStatus(ctx context.Context, file string) error {
+	return RunCompose(ctx, "-f", file, "ps")
 }
 
-func ComposeLogs(file string, services ...string) error {
-	args := []string{"-f", file, "logs", "-f"}
+// ComposeLogs shows the logs of the given services, following them if
+// requested. An empty tail shows the complete logs.
+func ComposeLogs(ctx context.Context, file string, follow bool, tail string, services ...string) error {
+	args := []string{"-f", file, "logs"}
+	if follow {
+		args = append(args, "-f")
+	}
+	if tail != "" {
+		args = append(args, "--tail", tail)
+	}
+	// podman-compose only prefixes lines with the container name on request,
+	// which docker compose always does
+	if rt, err := CurrentRuntime(); err == nil && rt.Name() == "podman-compose" {
+		args = append(args, "--names")
+	}
 	if len(services) > 0 {
 		args = append(args, services...)
 	}
-	return RunCompose(args...)
+	return RunCompose(ctx, args...)
+}
+
+func ComposeValidate(ctx context.Context, file string) error {
+	return RunCompose(ctx, "-f", file, "config", "--quiet")
+}
+
+// HelperImage is run for work on the engine's machine that the service
+// images are not suited for, such as checking and copying data directories.
+// It carries GNU cp, which clones files instead of copying their blocks on
+// filesystems with reflinks such as Btrfs and XFS.
+const HelperImage = "debian:stable-slim"
+
+// CheckDataPath checks that a directory exists on the machine the engine runs
+// on, which for a remote engine is not this one. The engine validates the
+// source of a --mount bind before it starts the container.
+func CheckDataPath(ctx context.Context, engine string, path string) error {
+	mount := fmt.Sprintf("type=bind,source=%s,target=/data,readonly", path)
+	out, err := runner.Command(ctx, engine, "run", "--rm", "--mount", mount, HelperImage, "true").CombinedOutput()
+	if err != nil {
+		if detail := strings.TrimSpace(string(out)); detail != "" {
+			return fmt.Errorf("%w: %s", err, detail)
+		}
+		return err
+	}
+	return nil
 }
 
-func ComposeValidate(file string) error {
-	return RunCompose("-f", file, "config", "--quiet")
+// RunDataScript runs a shell script as root in a throwaway container with
+// the data path mounted at /data, as the data directories belong to the
+// users of the service images. args are passed to the script as $1, $2...
+func RunDataScript(ctx context.Context, dataPath, script string, args ...string) error {
+	cmdArgs := []string{"run", "--rm", "--mount", "type=bind,source=" + dataPath + ",target=/data"}
+	// Podman labels data directories privately for their own containers
+	if rt, err := CurrentRuntime(); err == nil && rt.Engine() == "podman" {
+		cmdArgs = append(cmdArgs, "--security-opt", "label=disable")
+	}
+	cmdArgs = append(cmdArgs, HelperImage, "sh", "-c", script, "sh")
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := engineCommand(ctx, cmdArgs...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run data script: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// ExecScript runs a shell script in a running container. The script's
+// standard input is connected to stdin if it is not nil, its standard output
+// to stdout. args are passed to the script as $1, $2...
+func ExecScript(ctx context.Context, container string, stdin io.Reader, stdout io.Writer, script string, args ...string) error {
+	cmdArgs := []string{"exec"}
+	if stdin != nil {
+		cmdArgs = append(cmdArgs, "-i")
+	}
+	cmdArgs = append(cmdArgs, container, "sh", "-c", script, "sh")
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := engineCommand(ctx, cmdArgs...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run in %s: %w", container, err)
+	}
+	return nil
+}
+
+// CopyToContainer copies a local file to path in a container, which may be
+// stopped.
+func CopyToContainer(ctx context.Context, file, container, path string) error {
+	cmd := engineCommand(ctx, "cp", file, container+":"+path)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to copy to %s: %w", container, err)
+	}
+	return nil
+}
+
+// Exec runs a command in a running container, connected to dockenv's
+// standard streams. tty gives the command a terminal. env holds variables
+// set in the container by value, which stays off the engine's command line.
+// A failing command's *exec.ExitError is returned unwrapped, so that its
+// exit status can be passed on.
+func Exec(ctx context.Context, container string, tty bool, env map[string]string, command ...string) error {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-e", name)
+	}
+	args = append(args, container)
+	args = append(args, command...)
+
+	cmd := engineCommand(ctx, args...)
+	cmd.Env = os.Environ()
+	for _, name := range names {
+		cmd.Env = append(cmd.Env, name+"="+env[name])
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// CopyStreamToContainer writes size bytes from r to the file target in a
+// container, which may be stopped. The file is readable by every user, as
+// it belongs to root in the container.
+func CopyStreamToContainer(ctx context.Context, r io.Reader, size int64, container, target string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:    path.Base(target),
+			Mode:    0644,
+			Size:    size,
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = io.CopyN(tw, r, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	// The engine extracts the archive into the directory
+	cmd := engineCommand(ctx, "cp", "-", container+":"+path.Dir(target))
+	cmd.Stdin = pr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	// Unblock the writer if the engine stopped reading early
+	pr.CloseWithError(io.ErrClosedPipe)
+	if err != nil {
+		return fmt.Errorf("failed to copy to %s: %w", container, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ComposeImages returns the images referenced by a Compose file, sorted and
+// without duplicates.
+func ComposeImages(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %w", err)
+	}
+	return ParseComposeImages(data)
+}
+
+// ParseComposeImages returns the images of the services in Compose file
+// content. Services that are built rather than pulled have no image and are
+// skipped.
+func ParseComposeImages(data []byte) ([]string, error) {
+	var compose struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var images []string
+	for _, service := range compose.Services {
+		if service.Image == "" || seen[service.Image] {
+			continue
+		}
+		seen[service.Image] = true
+		images = append(images, service.Image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// PullImages fetches the images of a Compose file through the current
+// runtime.
+func PullImages(ctx context.Context, file string) error {
+	return RunCompose(ctx, "-f", file, "pull")
+}
+
+// SaveImages writes the given images to a single archive at path.
+func SaveImages(ctx context.Context, path string, images []string) error {
+	args := []string{"save", "-o", path}
+	// Podman writes one image per archive unless asked otherwise
+	if rt, err := CurrentRuntime(); err == nil && rt.Engine() == "podman" {
+		args = append(args, "--multi-image-archive")
+	}
+	args = append(args, images...)
+
+	cmd := engineCommand(ctx, args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	return nil
+}
+
+// LoadImages imports the images of an archive written by SaveImages.
+func LoadImages(ctx context.Context, path string) error {
+	cmd := engineCommand(ctx, "load", "-i", path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	return nil
+}
+
+// MissingImages returns the given images that the engine does not have.
+func MissingImages(ctx context.Context, images []string) ([]string, error) {
+	var missing []string
+	for _, image := range images {
+		err := engineCommand(ctx, "image", "inspect", image).Run()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+		case errors.As(err, &exitErr):
+			missing = append(missing, image)
+		default:
+			return nil, fmt.Errorf("failed to inspect image %s: %w", image, err)
+		}
+	}
+	return missing, nil
+}
+
+// PullImage fetches a single image with the engine, bypassing any pin in the
+// Compose file.
+func PullImage(ctx context.Context, image string) error {
+	cmd := engineCommand(ctx, "pull", image)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", image, err)
+	}
+	return nil
+}
+
+// ImageDigest returns the registry digest of a local image, e.g.
+// "sha256:9f86d0...". Images that were built locally have none.
+func ImageDigest(ctx context.Context, image string) (string, error) {
+	out, err := engineCommand(ctx, "image", "inspect", "--format", "{{json .RepoDigests}}", image).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+
+	var repoDigests []string
+	if err := json.Unmarshal(out, &repoDigests); err != nil {
+		return "", fmt.Errorf("failed to parse digests of %s: %w", image, err)
+	}
+	return RepoDigest(image, repoDigests)
+}
+
+// RepoDigest picks the digest of image's repository from the image's
+// repo digests, which may name other repositories the image was pulled from.
+func RepoDigest(image string, repoDigests []string) (string, error) {
+	repository := normalizeRepository(image)
+	for _, repoDigest := range repoDigests {
+		name, digest, found := strings.Cut(repoDigest, "@")
+		if found && normalizeRepository(name) == repository {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("no registry digest for %s", image)
+}
+
+// normalizeRepository returns the repository of an image reference without
+// tag or digest and in the long form Podman reports, e.g.
+// "docker.io/library/redis" for "redis:7-alpine".
+func normalizeRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+
+	// The first component is a registry if it looks like a host name
+	first, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		image = "docker.io/" + image
+	}
+	if strings.Count(image, "/") == 1 && strings.HasPrefix(image, "docker.io/") {
+		image = "docker.io/library/" + strings.TrimPrefix(image, "docker.io/")
+	}
+	return image
+}
+
+// LocalImageTags returns the tags of the images of a repository the engine
+// has, e.g. "15" and "15.6" for "postgres".
+func LocalImageTags(ctx context.Context, repository string) ([]string, error) {
+	out, err := engineCommand(ctx, "images", "--format", "{{.Tag}}", repository).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images of %s: %w", repository, err)
+	}
+
+	var tags []string
+	for _, tag := range strings.Fields(string(out)) {
+		if tag != "<none>" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
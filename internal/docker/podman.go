@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+)
+
+const (
+	defaultDockerSocket = "/var/run/docker.sock"
+	rootfulPodmanSocket = "/run/podman/podman.sock"
+)
+
+// SocketPath returns the API socket of the given engine. DOCKER_HOST,
+// CONTAINER_HOST and the Docker context are honoured when they point at a
+// unix socket; other endpoints, e.g. ssh:// or tcp://, have no local socket
+// and an empty path is returned.
+func SocketPath(engine string) string {
+	c, err := ResolveContext(engine)
+	if err != nil {
+		return localSocketPath(engine)
+	}
+	if !strings.HasPrefix(c.Host, "unix://") {
+		return ""
+	}
+	return strings.TrimPrefix(c.Host, "unix://")
+}
+
+// localSocketPath returns the default API socket of the given engine.
+// Rootless Podman listens below XDG_RUNTIME_DIR.
+func localSocketPath(engine string) string {
+	if engine != "podman" {
+		return defaultDockerSocket
+	}
+	if os.Geteuid() == 0 {
+		return rootfulPodmanSocket
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(runtimeDir, "podman", "podman.sock")
+}
+
+// SocketAvailable reports whether path exists and is a unix socket.
+func SocketAvailable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// podmanInfo checks that Podman works and whether it runs rootless.
+func podmanInfo(ctx context.Context) (running bool, rootless bool) {
+	out, err := runner.Command(ctx, "podman", "info", "--format", "{{.Host.Security.Rootless}}").Output()
+	if err != nil {
+		return false, false
+	}
+	return true, strings.TrimSpace(string(out)) == "true"
+}
+
+// ChownInUserNamespace hands a data directory to a container user under
+// rootless Podman. owner is uid:gid as seen inside the container, which maps
+// to a subordinate id on the host, so a plain chown cannot be used.
+func ChownInUserNamespace(ctx context.Context, owner, path string) error {
+	out, err := runner.Command(ctx, "podman", "unshare", "chown", owner, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to chown %s to %s: %s: %w", path, owner, strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// OwnerInUserNamespace returns the uid:gid owning path as seen by containers
+// of rootless Podman.
+func OwnerInUserNamespace(ctx context.Context, path string) (string, error) {
+	out, err := runner.Command(ctx, "podman", "unshare", "stat", "-c", "%u:%g", path).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s in the Podman user namespace: %w", path, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+)
+
+// ProjectLabel is the label Compose puts on every container of a project.
+const ProjectLabel = "com.docker.compose.project"
+
+// inspectPortsFormat prints one line per container with its name, Compose
+// project and the host ports it binds, stopped containers included.
+const inspectPortsFormat = `{{.Name}}	{{index .Config.Labels "` + ProjectLabel + `"}}	` +
+	`{{range $port, $bindings := .HostConfig.PortBindings}}{{range $bindings}}{{.HostPort}} {{end}}{{end}}`
+
+// PublishedPort is a host port bound by a container.
+type PublishedPort struct {
+	HostPort  int
+	Container string
+	Project   string
+}
+
+// ListPublishedPorts returns the host ports of all containers, including
+// stopped ones, which claim their ports again once started.
+func ListPublishedPorts(ctx context.Context) ([]PublishedPort, error) {
+	if client, err := NewClientFromEnv(); err == nil {
+		apiCtx, cancel := context.WithTimeout(ctx, apiTimeout)
+		defer cancel()
+		if ports, err := client.PublishedPorts(apiCtx); err == nil {
+			return ports, nil
+		}
+	}
+
+	ctx, cancel := runner.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	out, err := engineCommand(ctx, "ps", "-aq").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := append([]string{"inspect", "--format", inspectPortsFormat}, ids...)
+	out, err = engineCommand(ctx, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect containers: %w", err)
+	}
+
+	return ParsePublishedPorts(string(out)), nil
+}
+
+// ParsePublishedPorts parses the output of docker inspect with
+// inspectPortsFormat.
+func ParsePublishedPorts(output string) []PublishedPort {
+	var ports []PublishedPort
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+
+		container := strings.TrimPrefix(fields[0], "/")
+		for _, value := range strings.Fields(fields[2]) {
+			port, err := strconv.Atoi(value)
+			if err != nil || port == 0 {
+				continue
+			}
+			ports = append(ports, PublishedPort{
+				HostPort:  port,
+				Container: container,
+				Project:   fields[1],
+			})
+		}
+	}
+	return ports
+}
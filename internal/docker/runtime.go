@@ -0,0 +1,279 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+)
+
+// probeTimeout bounds CLI commands that only read state, so a hung daemon
+// cannot block dockenv forever.
+const probeTimeout = 30 * time.Second
+
+// Runtime is a container backend that runs Compose commands. It is selected
+// once per process, either by the runtime config key or by detection, and all
+// Compose commands go through it.
+type Runtime interface {
+	// Name is the value of the runtime config key selecting this backend
+	Name() string
+	// Engine is the container CLI the backend drives, docker or podman
+	Engine() string
+	// Available and Version run local probes bounded by probeTimeout as
+	// well as by ctx
+	Available(ctx context.Context) bool
+	Version(ctx context.Context) (string, error)
+	// Command returns the command running Compose with the given arguments,
+	// which is signalled when ctx ends
+	Command(ctx context.Context, args ...string) *runner.Cmd
+}
+
+// composeV2 is the Docker Compose v2 CLI plugin, invoked as docker compose.
+type composeV2 struct{}
+
+func (composeV2) Name() string   { return "compose" }
+func (composeV2) Engine() string { return "docker" }
+
+func (r composeV2) Available(ctx context.Context) bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	_, err := cachedOutput(ctx, r, "version")
+	return err == nil
+}
+
+func (r composeV2) Version(ctx context.Context) (string, error) {
+	return cachedOutput(ctx, r, "version")
+}
+
+func (composeV2) Command(ctx context.Context, args ...string) *runner.Cmd {
+	return runner.Command(ctx, "docker", append([]string{"compose"}, args...)...)
+}
+
+// composeV1 is the standalone docker-compose binary.
+type composeV1 struct{}
+
+func (composeV1) Name() string   { return "docker-compose" }
+func (composeV1) Engine() string { return "docker" }
+
+func (composeV1) Available(context.Context) bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	_, err := exec.LookPath("docker-compose")
+	return err == nil
+}
+
+func (r composeV1) Version(ctx context.Context) (string, error) {
+	return cachedOutput(ctx, r, "--version")
+}
+
+func (composeV1) Command(ctx context.Context, args ...string) *runner.Cmd {
+	return runner.Command(ctx, "docker-compose", args...)
+}
+
+// podmanComposeV2 is Podman's built-in podman compose wrapper, which delegates
+// to an external Compose provider over the Podman socket.
+type podmanComposeV2 struct{}
+
+func (podmanComposeV2) Name() string   { return "podman" }
+func (podmanComposeV2) Engine() string { return "podman" }
+
+func (r podmanComposeV2) Available(ctx context.Context) bool {
+	if _, err := exec.LookPath("podman"); err != nil {
+		return false
+	}
+	_, err := cachedOutput(ctx, r, "version")
+	return err == nil
+}
+
+func (r podmanComposeV2) Version(ctx context.Context) (string, error) {
+	return cachedOutput(ctx, r, "version")
+}
+
+func (podmanComposeV2) Command(ctx context.Context, args ...string) *runner.Cmd {
+	return runner.Command(ctx, "podman", append([]string{"compose"}, args...)...)
+}
+
+// podmanCompose runs Compose files against Podman via podman-compose.
+type podmanCompose struct{}
+
+func (podmanCompose) Name() string   { return "podman-compose" }
+func (podmanCompose) Engine() string { return "podman" }
+
+func (podmanCompose) Available(context.Context) bool {
+	_, err := exec.LookPath("podman-compose")
+	return err == nil
+}
+
+func (r podmanCompose) Version(ctx context.Context) (string, error) {
+	return cachedOutput(ctx, r, "--version")
+}
+
+func (podmanCompose) Command(ctx context.Context, args ...string) *runner.Cmd {
+	return runner.Command(ctx, "podman-compose", args...)
+}
+
+// RecordRuntime is the runtime name that selects recording mode, in which
+// every command is printed instead of run.
+const RecordRuntime = "record"
+
+// Runtimes lists the supported backends in detection order.
+var Runtimes = []Runtime{composeV2{}, composeV1{}, podmanComposeV2{}, podmanCompose{}}
+
+var (
+	runtimePreference string
+	activeRuntime     Runtime
+	// probeContext bounds the probes that select the backend on first use
+	probeContext = context.Background()
+)
+
+func GetRuntime(name string) (Runtime, bool) {
+	for _, rt := range Runtimes {
+		if rt.Name() == name {
+			return rt, true
+		}
+	}
+	return nil, false
+}
+
+func GetRuntimeNames() []string {
+	names := make([]string, 0, len(Runtimes))
+	for _, rt := range Runtimes {
+		names = append(names, rt.Name())
+	}
+	return names
+}
+
+// DetectRuntime returns the first installed backend in detection order.
+func DetectRuntime(ctx context.Context) (Runtime, error) {
+	for _, rt := range Runtimes {
+		if rt.Available(ctx) {
+			return rt, nil
+		}
+	}
+	return nil, fmt.Errorf("no container runtime found (tried %s)", strings.Join(GetRuntimeNames(), ", "))
+}
+
+// SelectRuntime returns the named backend, or detects one if name is empty.
+func SelectRuntime(ctx context.Context, name string) (Runtime, error) {
+	if name == "" {
+		return DetectRuntime(ctx)
+	}
+
+	rt, exists := GetRuntime(name)
+	if !exists {
+		return nil, fmt.Errorf("unknown runtime: %s. Available runtimes: %s",
+			name, strings.Join(GetRuntimeNames(), ", "))
+	}
+	if !rt.Available(ctx) {
+		return nil, fmt.Errorf("runtime %s is not installed", name)
+	}
+	return rt, nil
+}
+
+// SetRuntime sets the backend used by this process by name; an empty name
+// selects it by detection. The backend is resolved on first use, with its
+// probes bounded by ctx. RecordRuntime switches to recording mode with the
+// detected backend.
+func SetRuntime(ctx context.Context, name string) {
+	if name == RecordRuntime {
+		runner.Record(os.Stderr)
+		name = ""
+	}
+	runtimePreference = name
+	activeRuntime = nil
+	probeContext = ctx
+}
+
+// CurrentRuntime returns the backend used by this process, selecting it on
+// the first call.
+func CurrentRuntime() (Runtime, error) {
+	if activeRuntime == nil {
+		rt, err := selectRuntime()
+		if err != nil {
+			return nil, err
+		}
+		activeRuntime = rt
+	}
+	return activeRuntime, nil
+}
+
+// selectRuntime resolves the preferred backend. In recording mode nothing is
+// probed, as nothing needs to be installed; without a preference commands are
+// recorded as docker compose would run them.
+func selectRuntime() (Runtime, error) {
+	if !runner.Recording() {
+		return SelectRuntime(probeContext, runtimePreference)
+	}
+	if runtimePreference == "" {
+		return composeV2{}, nil
+	}
+	if rt, exists := GetRuntime(runtimePreference); exists {
+		return rt, nil
+	}
+	return SelectRuntime(probeContext, runtimePreference)
+}
+
+// EngineFor returns the container CLI behind the named runtime, detecting
+// the runtime if name is empty. It defaults to docker.
+func EngineFor(name string) string {
+	if rt, exists := GetRuntime(name); exists {
+		return rt.Engine()
+	}
+	if rt, err := CurrentRuntime(); err == nil {
+		return rt.Engine()
+	}
+	return "docker"
+}
+
+// engineCommand runs the container CLI of the current runtime, falling back
+// to docker when no runtime is available.
+func engineCommand(ctx context.Context, args ...string) *runner.Cmd {
+	engine := "docker"
+	if rt, err := CurrentRuntime(); err == nil {
+		engine = rt.Engine()
+	}
+	return runner.Command(ctx, engine, args...)
+}
+
+// versionOutputs caches version commands, which detection and CheckDocker
+// would otherwise both run.
+var versionOutputs = make(map[string]versionOutput)
+
+type versionOutput struct {
+	out string
+	err error
+}
+
+// cachedOutput runs a version command of rt, bounded by probeTimeout as well
+// as by ctx. Failures because ctx ended are not cached.
+func cachedOutput(ctx context.Context, rt Runtime, args ...string) (string, error) {
+	ctx, cancel := runner.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	cmd := rt.Command(ctx, args...)
+	key := strings.Join(cmd.Args, " ")
+	if cached, exists := versionOutputs[key]; exists {
+		return cached.out, cached.err
+	}
+
+	out, err := commandOutput(cmd)
+	if ctx.Err() != nil {
+		return "", context.Cause(ctx)
+	}
+	versionOutputs[key] = versionOutput{out, err}
+	return out, err
+}
+
+func commandOutput(cmd *runner.Cmd) (string, error) {
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,263 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/systemd"
+)
+
+// Minimum versions dockenv is tested with.
+const (
+	MinDockerVersion        = "20.10.0"
+	MinPodmanVersion        = "4.0.0"
+	MinComposeVersion       = "2.0.0"
+	MinPodmanComposeVersion = "1.0.0"
+)
+
+// Free disk space thresholds for the data directory.
+const (
+	DiskSpaceWarn = 10 << 30
+	DiskSpaceFail = 2 << 30
+)
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// ExtractVersion returns the first version number in a version banner such
+// as "Docker version 24.0.7, build afdd53b".
+func ExtractVersion(banner string) string {
+	return versionPattern.FindString(banner)
+}
+
+// CompareVersions compares two dotted version numbers, returning -1, 0 or 1.
+// Missing components count as zero.
+func CompareVersions(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func checkMinimum(name, banner, minimum, hint string) Result {
+	version := ExtractVersion(banner)
+	if version == "" {
+		return Warn(name, fmt.Sprintf("could not determine version from %q", banner), "")
+	}
+	if CompareVersions(version, minimum) < 0 {
+		return Fail(name, fmt.Sprintf("version %s is older than the required %s", version, minimum), hint)
+	}
+	return Pass(name, "version "+version)
+}
+
+// CheckEngine checks that the container engine is installed, running and
+// recent enough.
+func CheckEngine(info *docker.DockerInfo) Result {
+	if info.Engine == "podman" {
+		switch {
+		case !info.DockerInstalled:
+			return Fail("engine", "Podman is not installed", "Install Podman: https://podman.io/docs/installation")
+		case !info.DockerRunning:
+			return Fail("engine", "Podman is not working", "Run 'podman info' to see the error")
+		}
+		return checkMinimum("engine", info.DockerVersion, MinPodmanVersion, "Upgrade Podman from your distribution or https://podman.io")
+	}
+
+	switch {
+	case !info.DockerInstalled:
+		return Fail("engine", "Docker is not installed", "Install Docker: https://docs.docker.com/get-docker/")
+	case !info.DockerRunning:
+		return Fail("engine", "Docker daemon is not running", "Start it with: sudo systemctl start docker")
+	}
+	return checkMinimum("engine", info.DockerVersion, MinDockerVersion, "Upgrade Docker: https://docs.docker.com/engine/install/")
+}
+
+// CheckCompose checks the selected Compose runtime and its version.
+func CheckCompose(info *docker.DockerInfo) Result {
+	if !info.DockerComposeInstalled {
+		if info.Runtime != "" {
+			return Fail("compose", fmt.Sprintf("configured runtime %s is not installed", info.Runtime),
+				"Install it or remove 'runtime' from the configuration")
+		}
+		return Fail("compose", "no Compose runtime found", "Install Docker Compose: https://docs.docker.com/compose/install/")
+	}
+
+	switch info.Runtime {
+	case "compose":
+		return checkMinimum("compose", info.ComposeVersion, MinComposeVersion, "Upgrade the Docker Compose plugin")
+	case "docker-compose":
+		return Warn("compose", fmt.Sprintf("standalone docker-compose %s is deprecated", ExtractVersion(info.ComposeVersion)),
+			"Install the Compose v2 plugin: https://docs.docker.com/compose/install/")
+	case "podman-compose":
+		return checkMinimum("compose", info.ComposeVersion, MinPodmanComposeVersion, "Upgrade with: pip install --upgrade podman-compose")
+	}
+	return Pass("compose", fmt.Sprintf("%s %s", info.Runtime, ExtractVersion(info.ComposeVersion)))
+}
+
+// SocketAccess describes the engine socket as seen by the current user.
+type SocketAccess struct {
+	Path     string
+	Exists   bool
+	Writable bool
+	// InGroup reports membership in the docker group
+	InGroup bool
+}
+
+// CheckSocket checks that the current user can talk to the engine socket.
+// Engines reached over ssh:// or tcp:// have no local socket to check.
+func CheckSocket(info *docker.DockerInfo, access SocketAccess) Result {
+	if host := info.Context.Host; host != "" && !strings.HasPrefix(host, "unix://") {
+		return Pass("socket", "engine at "+info.Context.String())
+	}
+
+	if info.Engine == "podman" {
+		switch {
+		case !access.Exists && info.Runtime == "podman":
+			return Fail("socket", "Podman socket not found at "+access.Path, "Enable it with: systemctl --user enable --now podman.socket")
+		case !access.Exists:
+			return Warn("socket", "Podman socket not found at "+access.Path,
+				"Enable it for faster status checks: systemctl --user enable --now podman.socket")
+		case !access.Writable:
+			return Fail("socket", "no permission to use "+access.Path, "Check the ownership of the socket")
+		}
+		return Pass("socket", access.Path)
+	}
+
+	switch {
+	case !access.Exists:
+		return Fail("socket", "Docker socket not found at "+access.Path, "Start Docker or point DOCKER_HOST at its socket")
+	case access.Writable:
+		return Pass("socket", access.Path)
+	case !access.InGroup:
+		return Fail("socket", "no permission to use "+access.Path,
+			"Add yourself to the docker group: sudo usermod -aG docker $USER, then log out and back in")
+	}
+	return Fail("socket", "no permission to use "+access.Path+" although you are in the docker group",
+		"Log out and back in, or run 'newgrp docker', to pick up the group membership")
+}
+
+// CheckRootless reports the engine mode. Rootless engines cannot publish
+// ports below 1024 by default.
+func CheckRootless(info *docker.DockerInfo, hostPorts []int) Result {
+	if !info.Rootless {
+		return Pass("rootless", "engine runs as root")
+	}
+
+	var privileged []string
+	for _, port := range hostPorts {
+		if port < 1024 {
+			privileged = append(privileged, strconv.Itoa(port))
+		}
+	}
+	if len(privileged) > 0 {
+		return Warn("rootless", "rootless mode cannot publish privileged ports: "+strings.Join(privileged, ", "),
+			"Use ports above 1024 with --port, or lower net.ipv4.ip_unprivileged_port_start")
+	}
+	return Pass("rootless", "engine runs rootless")
+}
+
+// CheckDiskSpace checks the free space on the filesystem of the data path.
+func CheckDiskSpace(path string, free uint64) Result {
+	message := fmt.Sprintf("%.1f GiB free under %s", float64(free)/(1<<30), path)
+	hint := "Free up space or move the data with --data-path"
+	switch {
+	case free < DiskSpaceFail:
+		return Fail("disk space", message, hint)
+	case free < DiskSpaceWarn:
+		return Warn("disk space", message, hint)
+	}
+	return Pass("disk space", message)
+}
+
+// CheckDataOwner compares the owner of a data directory with the user the
+// image writes its data as. Only the uid is compared. owner is empty for
+// directories that do not exist yet.
+func CheckDataOwner(path, owner, expected string, rootless bool) Result {
+	name := "data " + filepath.Base(path)
+	if owner == "" {
+		return Pass(name, path+" not created yet")
+	}
+
+	ownerUID, _, _ := strings.Cut(owner, ":")
+	expectedUID, _, _ := strings.Cut(expected, ":")
+	if ownerUID == expectedUID {
+		return Pass(name, path+" owned by "+owner)
+	}
+
+	hint := fmt.Sprintf("sudo chown -R %s %s", expected, path)
+	if rootless {
+		hint = fmt.Sprintf("podman unshare chown -R %s %s", expected, path)
+	}
+	return Warn(name, fmt.Sprintf("%s owned by %s, the container writes as %s", path, owner, expected), hint)
+}
+
+// CheckStaleContainers finds containers of the project whose service is no
+// longer configured.
+func CheckStaleContainers(configured []string, containers []docker.Container) Result {
+	known := make(map[string]bool)
+	for _, service := range configured {
+		known[service] = true
+	}
+
+	var stale []string
+	for _, container := range containers {
+		if !known[container.Service] {
+			stale = append(stale, fmt.Sprintf("%s (%s)", container.Name, container.State))
+		}
+	}
+	sort.Strings(stale)
+
+	if len(stale) > 0 {
+		return Warn("stale containers", strings.Join(stale, ", "), "Remove them with: dockenv up --remove-orphans")
+	}
+	return Pass("stale containers", "none")
+}
+
+// CheckUnit checks that the installed autostart unit still matches the
+// project and runtime. unit is nil when autostart is not set up.
+func CheckUnit(unit *systemd.Unit, engine string) Result {
+	if unit == nil {
+		return Pass("autostart", "not enabled")
+	}
+
+	reinstall := "Run 'dockenv autostart disable' and 'dockenv autostart enable' from the project directory"
+
+	composeFile := filepath.Join(unit.WorkingDirectory, config.ComposeFileName)
+	if _, err := os.Stat(composeFile); err != nil {
+		return Fail("autostart", fmt.Sprintf("unit starts %s, which has no %s", unit.WorkingDirectory, config.ComposeFileName), reinstall)
+	}
+
+	if binary, _, _ := strings.Cut(unit.ExecStart, " "); binary != "" {
+		if _, err := os.Stat(binary); err != nil {
+			return Fail("autostart", "unit runs missing binary "+binary, "Reinstall dockenv or fix ExecStart in "+systemd.ServiceFilePath)
+		}
+	}
+
+	for _, required := range unit.Requires {
+		if required == "docker.service" && engine == "podman" {
+			return Warn("autostart", "unit requires docker.service but the runtime uses Podman", reinstall)
+		}
+	}
+
+	return Pass("autostart", "starts "+unit.WorkingDirectory)
+}
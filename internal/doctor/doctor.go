@@ -0,0 +1,77 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a single check with a hint on how to fix it.
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+func Pass(name, message string) Result {
+	return Result{Name: name, Status: StatusPass, Message: message}
+}
+
+func Warn(name, message, hint string) Result {
+	return Result{Name: name, Status: StatusWarn, Message: message, Hint: hint}
+}
+
+func Fail(name, message, hint string) Result {
+	return Result{Name: name, Status: StatusFail, Message: message, Hint: hint}
+}
+
+type Report struct {
+	Checks []Result `json:"checks"`
+}
+
+func (r *Report) Add(results ...Result) {
+	r.Checks = append(r.Checks, results...)
+}
+
+// Count returns the number of checks with the given status.
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Report) Failed() bool {
+	return r.Count(StatusFail) > 0
+}
+
+// Print writes the report in human-readable form.
+func (r *Report) Print(w io.Writer) {
+	icons := map[Status]string{
+		StatusPass: "✅",
+		StatusWarn: "⚠️ ",
+		StatusFail: "❌",
+	}
+
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "%s %-18s %s\n", icons[check.Status], check.Name, check.Message)
+		if check.Hint != "" {
+			fmt.Fprintf(w, "   💡 %s\n", check.Hint)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n",
+		r.Count(StatusPass), r.Count(StatusWarn), r.Count(StatusFail))
+}
@@ -0,0 +1,92 @@
+// Package git reads the little dockenv needs from a git repository, the
+// checked-out branch and the hooks directory, without running git.
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FindDir returns the git directory of the repository containing start,
+// following the .git file of worktrees and submodules.
+func FindDir(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil {
+			if info.IsDir() {
+				return dotGit, nil
+			}
+			return readGitFile(dotGit)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("not a git repository: %s", start)
+		}
+		dir = parent
+	}
+}
+
+// readGitFile resolves a .git file of the form "gitdir: <path>".
+func readGitFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	gitDir, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !found {
+		return "", fmt.Errorf("invalid .git file: %s", file)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(file), gitDir)
+	}
+	return gitDir, nil
+}
+
+// CurrentBranch returns the branch checked out in a git directory. It fails
+// for a detached HEAD, which belongs to no branch.
+func CurrentBranch(gitDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	return ParseHead(string(data))
+}
+
+// ParseHead returns the branch named by the content of a HEAD file, e.g.
+// "main" for "ref: refs/heads/main".
+func ParseHead(head string) (string, error) {
+	ref, found := strings.CutPrefix(strings.TrimSpace(head), "ref:")
+	if !found {
+		return "", fmt.Errorf("HEAD is detached")
+	}
+
+	branch, found := strings.CutPrefix(strings.TrimSpace(ref), "refs/heads/")
+	if !found || branch == "" {
+		return "", fmt.Errorf("HEAD does not name a branch: %s", strings.TrimSpace(ref))
+	}
+	return branch, nil
+}
+
+// HooksDir returns the hooks directory of a git directory. Worktrees share
+// the hooks of the main repository.
+func HooksDir(gitDir string) string {
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		return filepath.Join(common, "hooks")
+	}
+	return filepath.Join(gitDir, "hooks")
+}
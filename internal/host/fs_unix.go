@@ -0,0 +1,56 @@
+//go:build !windows
+
+package host
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// FreeDiskSpace returns the bytes available to unprivileged users on the
+// filesystem holding path. Missing paths are resolved to their closest
+// existing parent, so it can be used before a data directory is created.
+func FreeDiskSpace(path string) (uint64, error) {
+	path = existingParent(path)
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to read free disk space of %s: %w", path, err)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+// FileOwner returns the numeric owner and group of a file.
+func FileOwner(path string) (int, int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("file ownership not available for %s", path)
+	}
+	return int(stat.Uid), int(stat.Gid), nil
+}
+
+// Writable reports whether the current user may write to path.
+func Writable(path string) bool {
+	// 2 is W_OK
+	return syscall.Access(path, 2) == nil
+}
+
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
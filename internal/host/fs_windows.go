@@ -0,0 +1,17 @@
+//go:build windows
+
+package host
+
+import "fmt"
+
+func FreeDiskSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("free disk space is not supported on Windows")
+}
+
+func FileOwner(path string) (int, int, error) {
+	return 0, 0, fmt.Errorf("file ownership is not supported on Windows")
+}
+
+func Writable(path string) bool {
+	return true
+}
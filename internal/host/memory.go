@@ -0,0 +1,47 @@
+package host
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const meminfoPath = "/proc/meminfo"
+
+// TotalMemory returns the host's total memory in bytes as reported by
+// /proc/meminfo. It fails on systems without procfs.
+func TotalMemory() (int64, error) {
+	file, err := os.Open(meminfoPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read host memory: %w", err)
+	}
+	defer file.Close()
+
+	return ParseMemTotal(file)
+}
+
+// ParseMemTotal extracts the MemTotal entry from meminfo-formatted input.
+func ParseMemTotal(r io.Reader) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+
+		kilobytes, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal value: %s", fields[1])
+		}
+		return kilobytes * 1024, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read meminfo: %w", err)
+	}
+
+	return 0, fmt.Errorf("MemTotal not found in meminfo")
+}
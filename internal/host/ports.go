@@ -0,0 +1,122 @@
+package host
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// tcpListen is the socket state of a listening socket in /proc/net/tcp.
+const tcpListen = "0A"
+
+var procNetTCP = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+// PortInUse reports whether another socket already holds the TCP port on the
+// given address. Addresses the host cannot bind at all are not reported as in
+// use.
+func PortInUse(address string, port int) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return errors.Is(err, syscall.EADDRINUSE)
+	}
+	listener.Close()
+	return false
+}
+
+// FindFreePort returns the first port after start that is neither reserved
+// nor in use on the given address.
+func FindFreePort(address string, start int, reserved map[int]bool) (int, error) {
+	for port := start + 1; port <= 65535; port++ {
+		if reserved[port] || PortInUse(address, port) {
+			continue
+		}
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port found after %d", start)
+}
+
+// PortOwner describes the process listening on a TCP port, e.g.
+// "mysqld (pid 1234)". It returns an empty string when the owner cannot be
+// determined, for instance without procfs or permission to read other
+// users' processes.
+func PortOwner(port int) string {
+	inodes := make(map[string]bool)
+	for _, path := range procNetTCP {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		found, err := ParseListenInodes(file, port)
+		file.Close()
+		if err != nil {
+			continue
+		}
+		for _, inode := range found {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+
+	fds, err := filepath.Glob("/proc/[0-9]*/fd/*")
+	if err != nil {
+		return ""
+	}
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		if !inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			continue
+		}
+
+		pidDir := filepath.Dir(filepath.Dir(fd))
+		pid := filepath.Base(pidDir)
+		comm, err := os.ReadFile(filepath.Join(pidDir, "comm"))
+		if err != nil {
+			return fmt.Sprintf("pid %s", pid)
+		}
+		return fmt.Sprintf("%s (pid %s)", strings.TrimSpace(string(comm)), pid)
+	}
+
+	return ""
+}
+
+// ParseListenInodes returns the socket inodes listening on the given port in
+// /proc/net/tcp-formatted input.
+func ParseListenInodes(r io.Reader, port int) ([]string, error) {
+	var inodes []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+
+		_, hexPort, found := strings.Cut(fields[1], ":")
+		if !found {
+			continue
+		}
+		localPort, err := strconv.ParseInt(hexPort, 16, 32)
+		if err != nil || int(localPort) != port {
+			continue
+		}
+		inodes = append(inodes, fields[9])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read socket table: %w", err)
+	}
+
+	return inodes, nil
+}
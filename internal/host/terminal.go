@@ -0,0 +1,9 @@
+package host
+
+import "os"
+
+// IsTerminal reports whether f is a terminal rather than a pipe or a file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,237 @@
+// Package importer loads dump files into services. It detects the format of
+// a dump, decompresses it while streaming and knows the tool each service
+// loads a format with.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// Format is the format of a dump, after decompression.
+type Format string
+
+const (
+	FormatSQL          Format = "sql"
+	FormatPGCustom     Format = "pg-custom"
+	FormatMongoArchive Format = "mongo-archive"
+	FormatRDB          Format = "rdb"
+)
+
+// Descriptions name the formats for people.
+var Descriptions = map[Format]string{
+	FormatSQL:          "SQL",
+	FormatPGCustom:     "PostgreSQL custom format",
+	FormatMongoArchive: "MongoDB archive",
+	FormatRDB:          "Redis RDB",
+}
+
+// sniffSize is how much of a dump is looked at to detect its format.
+const sniffSize = 512
+
+var (
+	gzipMagic         = []byte{0x1f, 0x8b}
+	pgCustomMagic     = []byte("PGDMP")
+	mongoArchiveMagic = []byte{0x6d, 0xe2, 0x99, 0x81}
+	rdbMagic          = []byte("REDIS")
+)
+
+// Detect returns the format of a dump from its first bytes, which must not
+// be compressed. Anything that reads as text is taken for SQL.
+func Detect(header []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(header, pgCustomMagic):
+		return FormatPGCustom, nil
+	case bytes.HasPrefix(header, mongoArchiveMagic):
+		return FormatMongoArchive, nil
+	case bytes.HasPrefix(header, rdbMagic):
+		return FormatRDB, nil
+	case len(header) > 0 && isText(header):
+		return FormatSQL, nil
+	}
+	return "", fmt.Errorf("unknown dump format")
+}
+
+// isText reports whether data is UTF-8 text without control characters
+// other than whitespace. A multi-byte character cut off at the end is fine.
+func isText(data []byte) bool {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size <= 1 {
+			return len(data) < utf8.UTFMax && !utf8.FullRune(data)
+		}
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+		data = data[size:]
+	}
+	return true
+}
+
+// Dump is an open dump file, read decompressed.
+type Dump struct {
+	Format     Format
+	Compressed bool
+	// Size is the size of the file, which the progress counts towards
+	Size int64
+
+	file     *os.File
+	progress *Progress
+	reader   io.Reader
+}
+
+// Open opens a dump file and detects its format. Reads from the dump are
+// reported to progress, which may be nil, in bytes of the file.
+func Open(path string, progress *Progress) (*Dump, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open dump: %w", err)
+	}
+
+	dump := &Dump{Size: info.Size(), file: file, progress: progress}
+	var reader io.Reader = file
+	if progress != nil {
+		reader = progress.Reader(file)
+	}
+
+	buffered := bufio.NewReaderSize(reader, 64*1024)
+	header, _ := buffered.Peek(sniffSize)
+	if bytes.HasPrefix(header, gzipMagic) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read compressed dump: %w", err)
+		}
+		dump.Compressed = true
+		buffered = bufio.NewReaderSize(gz, 64*1024)
+		header, _ = buffered.Peek(sniffSize)
+	}
+	dump.reader = buffered
+
+	dump.Format, err = Detect(header)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+	return dump, nil
+}
+
+// Read reads the decompressed dump.
+func (d *Dump) Read(p []byte) (int, error) {
+	return d.reader.Read(p)
+}
+
+func (d *Dump) Close() error {
+	return d.file.Close()
+}
+
+// Description names the format of the dump, e.g. "SQL, gzip-compressed".
+func (d *Dump) Description() string {
+	description := Descriptions[d.Format]
+	if d.Compressed {
+		description += ", gzip-compressed"
+	}
+	return description
+}
+
+// Method describes how a service loads dumps.
+type Method struct {
+	// Scripts holds, by format, a shell script that runs in the service's
+	// container and loads a dump from standard input into database $1
+	Scripts map[Format]string
+	// Replace holds, by format, the path of the data file a dump replaces
+	// while the service is stopped
+	Replace map[Format]string
+	// DatabaseVar names the configuration's environment variable holding
+	// the default database, for services that have databases
+	DatabaseVar string
+	// CreateDatabase is a shell script that creates database $1 if it does
+	// not exist
+	CreateDatabase string
+}
+
+// Methods holds the dump formats the services load. The scripts read
+// credentials from the container's environment and connect as
+// administrators, as dumps commonly create roles, schemas and extensions.
+var Methods = map[string]Method{
+	"postgres": {
+		Scripts: map[Format]string{
+			FormatSQL: `exec psql -q -v ON_ERROR_STOP=1 -U "$POSTGRES_USER" -d "$1"`,
+			// Roles of the dumped server do not exist here
+			FormatPGCustom: `exec pg_restore --no-owner --no-privileges -U "$POSTGRES_USER" -d "$1"`,
+		},
+		DatabaseVar: "DB_DATABASE",
+		CreateDatabase: `exists=$(psql -U "$POSTGRES_USER" -d postgres -tA -v name="$1" <<'SQL'
+SELECT 1 FROM pg_database WHERE datname = :'name'
+SQL
+)
+[ "$exists" = 1 ] || exec createdb -U "$POSTGRES_USER" "$1"`,
+	},
+	"mysql": {
+		Scripts: map[Format]string{
+			FormatSQL: `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" exec mysql -uroot "$1"`,
+		},
+		DatabaseVar: "DB_DATABASE",
+		// The configured user only has access to its own database
+		// otherwise
+		CreateDatabase: "MYSQL_PWD=\"$MYSQL_ROOT_PASSWORD\" exec mysql -uroot -e " +
+			"\"CREATE DATABASE IF NOT EXISTS \\`$1\\`; GRANT ALL ON \\`$1\\`.* TO '$MYSQL_USER'@'%'\"",
+	},
+	"mongodb": {
+		// An archive names its own databases, which are created as they are
+		// restored
+		Scripts: map[Format]string{
+			FormatMongoArchive: `exec mongorestore --quiet --archive -u "$MONGO_INITDB_ROOT_USERNAME" ` +
+				`-p "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin`,
+		},
+	},
+	"redis": {
+		Replace: map[Format]string{
+			FormatRDB: "/data/dump.rdb",
+		},
+	},
+}
+
+// LookupMethod returns how a service loads dumps.
+func LookupMethod(serviceName string) (Method, bool) {
+	method, exists := Methods[serviceName]
+	return method, exists
+}
+
+// Formats returns the formats a service loads, sorted.
+func (m Method) Formats() []Format {
+	var formats []Format
+	for format := range m.Scripts {
+		formats = append(formats, format)
+	}
+	for format := range m.Replace {
+		formats = append(formats, format)
+	}
+	sort.Slice(formats, func(i, j int) bool {
+		return formats[i] < formats[j]
+	})
+	return formats
+}
+
+var validDatabase = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+// ValidateDatabase checks that a database name needs no quoting in the
+// scripts' SQL.
+func ValidateDatabase(name string) error {
+	if !validDatabase.MatchString(name) {
+		return fmt.Errorf("invalid database name %q: use letters, digits, '_' and '-'", name)
+	}
+	return nil
+}
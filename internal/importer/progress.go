@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often the progress line is redrawn.
+const progressInterval = 250 * time.Millisecond
+
+// Progress draws a line with the bytes read of a known total, their share
+// and the rate, redrawing it in place on a terminal.
+type Progress struct {
+	out     io.Writer
+	total   int64
+	started time.Time
+
+	mu      sync.Mutex
+	read    int64
+	printed time.Time
+}
+
+// NewProgress returns a progress line for total bytes, drawn on out.
+func NewProgress(out io.Writer, total int64) *Progress {
+	return &Progress{out: out, total: total, started: time.Now()}
+}
+
+// Reader returns a reader that reports what it reads from r.
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	return &progressReader{r: r, progress: p}
+}
+
+type progressReader struct {
+	r        io.Reader
+	progress *Progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.progress.add(int64(n))
+	return n, err
+}
+
+func (p *Progress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.read += n
+	if now := time.Now(); now.Sub(p.printed) >= progressInterval {
+		p.printed = now
+		p.draw(now)
+	}
+}
+
+// Done draws the final state of the line and ends it.
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.draw(time.Now())
+	fmt.Fprintln(p.out)
+}
+
+func (p *Progress) draw(now time.Time) {
+	line := fmt.Sprintf("   %s / %s", FormatBytes(p.read), FormatBytes(p.total))
+	if p.total > 0 {
+		line += fmt.Sprintf("  %3d%%", p.read*100/p.total)
+	}
+	if elapsed := now.Sub(p.started).Seconds(); elapsed >= 1 {
+		line += fmt.Sprintf("  %s/s", FormatBytes(int64(float64(p.read)/elapsed)))
+	}
+	// Clear what is left of a longer line drawn before
+	fmt.Fprintf(p.out, "\r%-50s", line)
+}
+
+// FormatBytes formats a size with a binary unit, e.g. "1.5 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,149 @@
+// Package lock reads and writes dockenv.lock, which pins the images of the
+// configured services to the digests they resolved to, so that everyone
+// starting the project runs the same image versions.
+package lock
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	Version = 1
+
+	header = "# Generated by dockenv. Update with 'dockenv lock update'.\n"
+)
+
+// File is the content of dockenv.lock.
+type File struct {
+	Version int `yaml:"version"`
+	// Services holds the images of each service, sorted by reference
+	Services map[string][]Image `yaml:"services"`
+}
+
+// Image is an image reference as written in the templates, e.g.
+// "redis:7-alpine", and the digest it resolved to.
+type Image struct {
+	Image  string `yaml:"image"`
+	Digest string `yaml:"digest"`
+}
+
+// New returns an empty lock file.
+func New() *File {
+	return &File{Version: Version, Services: make(map[string][]Image)}
+}
+
+// Load reads the lock file at path. The error satisfies os.IsNotExist when
+// the project has no lock file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported lock file version %d in %s", f.Version, path)
+	}
+	if f.Services == nil {
+		f.Services = make(map[string][]Image)
+	}
+	return &f, nil
+}
+
+// Save writes the lock file to path.
+func (f *File) Save(path string) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+
+	if err := os.WriteFile(path, append([]byte(header), data...), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Digest returns the digest an image of a service is pinned to.
+func (f *File) Digest(serviceName, image string) (string, bool) {
+	for _, locked := range f.Services[serviceName] {
+		if locked.Image == image && locked.Digest != "" {
+			return locked.Digest, true
+		}
+	}
+	return "", false
+}
+
+// Pins maps the images of each service to their references pinned to the
+// locked digests. Images without a digest are left out.
+func (f *File) Pins() map[string]map[string]string {
+	pins := make(map[string]map[string]string, len(f.Services))
+	for serviceName, images := range f.Services {
+		for _, locked := range images {
+			if locked.Digest == "" {
+				continue
+			}
+			if pins[serviceName] == nil {
+				pins[serviceName] = make(map[string]string)
+			}
+			pins[serviceName][locked.Image] = Pin(locked.Image, locked.Digest)
+		}
+	}
+	return pins
+}
+
+// Stale returns the services whose entries do not match the images the
+// configuration needs, including locked services that are no longer
+// configured. required maps each configured service to its images.
+func (f *File) Stale(required map[string][]string) []string {
+	var stale []string
+	for serviceName, images := range required {
+		var locked []string
+		for _, image := range f.Services[serviceName] {
+			if image.Digest == "" {
+				locked = nil
+				break
+			}
+			locked = append(locked, image.Image)
+		}
+		if !equal(locked, images) {
+			stale = append(stale, serviceName)
+		}
+	}
+	for serviceName := range f.Services {
+		if _, exists := required[serviceName]; !exists {
+			stale = append(stale, serviceName)
+		}
+	}
+
+	sort.Strings(stale)
+	return stale
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Pin returns the image reference pinned to digest, keeping the tag for
+// readability, e.g. "redis:7-alpine@sha256:...".
+func Pin(image, digest string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	return image + "@" + digest
+}
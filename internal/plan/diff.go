@@ -0,0 +1,145 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type diffOp struct {
+	kind opKind
+	line string
+}
+
+// UnifiedDiff returns a unified diff turning before into after, using fromName
+// and toName as file labels. It returns an empty string when both are equal.
+func UnifiedDiff(fromName, toName string, before, after []byte) string {
+	ops := diffLines(splitLines(before), splitLines(after))
+
+	// Line numbers (0-based) in before/after at the start of each op
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	var changes []int
+	for i, op := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		switch op.kind {
+		case opEqual:
+			aPos[i+1]++
+			bPos[i+1]++
+		case opDelete:
+			aPos[i+1]++
+			changes = append(changes, i)
+		case opInsert:
+			bPos[i+1]++
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", fromName)
+	fmt.Fprintf(&sb, "+++ %s\n", toName)
+
+	for c := 0; c < len(changes); {
+		start := max(changes[c]-diffContext, 0)
+		end := changes[c] + 1
+
+		// Merge changes whose context overlaps into a single hunk
+		for c < len(changes) && changes[c] <= end+2*diffContext {
+			end = changes[c] + 1
+			c++
+		}
+		end = min(end+diffContext, len(ops))
+
+		aCount := aPos[end] - aPos[start]
+		bCount := bPos[end] - bPos[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aPos[start], aCount), hunkRange(bPos[start], bCount))
+
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case opEqual:
+				sb.WriteString(" ")
+			case opDelete:
+				sb.WriteString("-")
+			case opInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(op.line)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// diffLines computes a line-level edit script using the longest common
+// subsequence. Generated files are small, so the quadratic table is fine.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{opDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{opInsert, b[j]})
+	}
+
+	return ops
+}
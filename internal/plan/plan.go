@@ -0,0 +1,225 @@
+package plan
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+)
+
+type ActionKind string
+
+const (
+	ActionCreate   ActionKind = "create"
+	ActionRecreate ActionKind = "recreate"
+	ActionRemove   ActionKind = "remove"
+)
+
+// FileChange holds the current and planned content of a generated file.
+type FileChange struct {
+	Path   string
+	Before []byte
+	After  []byte
+	Exists bool
+}
+
+func (f FileChange) Changed() bool {
+	return !f.Exists || !bytes.Equal(f.Before, f.After)
+}
+
+// Diff returns the unified diff for the change, or an empty string if the
+// file is unchanged.
+func (f FileChange) Diff() string {
+	fromName := f.Path
+	if !f.Exists {
+		fromName = "/dev/null"
+	}
+	return UnifiedDiff(fromName, f.Path, f.Before, f.After)
+}
+
+// Action is a container operation that would follow applying the plan.
+type Action struct {
+	Kind    ActionKind
+	Service string
+}
+
+type Plan struct {
+	Files   []FileChange
+	Actions []Action
+}
+
+// Build renders the config, compose and .env files for cfg in memory and
+// compares them with what is currently on disk. Nothing is written.
+func Build(cfg *config.Config, env templates.Environment) (*Plan, error) {
+	p := &Plan{}
+
+	configData, err := utils.MarshalConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	configBefore, err := p.addFile(config.GetConfigPath(), configData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys the configuration no longer sets, e.g. those of removed services,
+	// are dropped from the env files
+	var previous config.Config
+	if err := yaml.Unmarshal(configBefore, &previous); err != nil {
+		return nil, fmt.Errorf("failed to parse current config: %w", err)
+	}
+	stale := utils.StaleEnvKeys(&previous, cfg)
+
+	composeData, err := templates.RenderDockerCompose(cfg, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render Docker Compose file: %w", err)
+	}
+	composeBefore, err := p.addFile(config.GetComposePath(), composeData)
+	if err != nil {
+		return nil, err
+	}
+
+	envVars := templates.EnvVars(cfg, env)
+	envData, err := utils.RenderEnvFile(config.EnvFileName, envVars, stale)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.addFile(config.EnvFileName, envData); err != nil {
+		return nil, err
+	}
+
+	// .env.example is only maintained when the project already has one
+	if utils.FileExists(".env.example") {
+		exampleData, err := utils.RenderEnvFile(".env.example", utils.ExampleEnvVars(envVars), stale)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.addFile(".env.example", exampleData); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Actions, err = containerActions(composeBefore, composeData)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// addFile records the planned content for path and returns its current
+// content, which is empty if the file does not exist yet.
+func (p *Plan) addFile(path string, after []byte) ([]byte, error) {
+	change := FileChange{Path: path, After: after}
+
+	before, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		change.Before = before
+		change.Exists = true
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	p.Files = append(p.Files, change)
+	return change.Before, nil
+}
+
+// HasChanges reports whether applying the plan would modify anything.
+func (p *Plan) HasChanges() bool {
+	for _, file := range p.Files {
+		if file.Changed() {
+			return true
+		}
+	}
+	return len(p.Actions) > 0
+}
+
+// Print writes the file diffs and container actions in a human-readable form.
+func (p *Plan) Print(w io.Writer) {
+	if !p.HasChanges() {
+		fmt.Fprintln(w, "✅ No changes. Generated files are up to date.")
+		return
+	}
+
+	fmt.Fprintln(w, "📋 Planned file changes:")
+	for _, file := range p.Files {
+		if !file.Changed() {
+			fmt.Fprintf(w, "   %s (unchanged)\n", file.Path)
+			continue
+		}
+		fmt.Fprintln(w)
+		if diff := file.Diff(); diff != "" {
+			fmt.Fprint(w, diff)
+		} else {
+			fmt.Fprintf(w, "   %s (new, empty)\n", file.Path)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "🐳 Container actions:")
+	if len(p.Actions) == 0 {
+		fmt.Fprintln(w, "   none")
+	}
+	for _, action := range p.Actions {
+		symbol := "~"
+		switch action.Kind {
+		case ActionCreate:
+			symbol = "+"
+		case ActionRemove:
+			symbol = "-"
+		}
+		fmt.Fprintf(w, "   %s %-9s %s\n", symbol, action.Kind, action.Service)
+	}
+}
+
+type composeServices struct {
+	Services map[string]interface{} `yaml:"services"`
+}
+
+// containerActions compares the service definitions of two compose files and
+// returns what Compose would do when the new file is applied.
+func containerActions(before, after []byte) ([]Action, error) {
+	var oldCompose, newCompose composeServices
+	if err := yaml.Unmarshal(before, &oldCompose); err != nil {
+		return nil, fmt.Errorf("failed to parse current compose file: %w", err)
+	}
+	if err := yaml.Unmarshal(after, &newCompose); err != nil {
+		return nil, fmt.Errorf("failed to parse planned compose file: %w", err)
+	}
+
+	var actions []Action
+	for _, name := range sortedKeys(newCompose.Services) {
+		oldService, exists := oldCompose.Services[name]
+		switch {
+		case !exists:
+			actions = append(actions, Action{Kind: ActionCreate, Service: name})
+		case !reflect.DeepEqual(oldService, newCompose.Services[name]):
+			actions = append(actions, Action{Kind: ActionRecreate, Service: name})
+		}
+	}
+	for _, name := range sortedKeys(oldCompose.Services) {
+		if _, exists := newCompose.Services[name]; !exists {
+			actions = append(actions, Action{Kind: ActionRemove, Service: name})
+		}
+	}
+
+	return actions, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
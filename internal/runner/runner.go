@@ -0,0 +1,178 @@
+// Package runner runs external commands bound to a context. Ending the
+// context signals the child so it can shut down cleanly instead of killing
+// it, and errors tell timeouts and interrupts apart from command failures.
+// In recording mode commands are printed instead of run.
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WaitDelay is how long a signalled child may take to exit before it is
+// killed.
+const WaitDelay = 10 * time.Second
+
+// TimeoutError is the cause of a context that ran out of time.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// InterruptedError is the cause of a context ended by a signal to dockenv.
+type InterruptedError struct {
+	Signal os.Signal
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted by signal: %s", e.Signal)
+}
+
+// WithTimeout returns a context that ends with a *TimeoutError after
+// timeout. A timeout of zero or less never expires.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeoutCause(parent, timeout, &TimeoutError{Timeout: timeout})
+}
+
+// WithSignals returns a context that ends with an *InterruptedError on the
+// first SIGINT or SIGTERM. Later signals get their default behaviour, so a
+// second Ctrl+C still exits at once.
+func WithSignals(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(&InterruptedError{Signal: sig})
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+// Cmd is an external command bound to a context. Its methods return the
+// context's cause, e.g. a *TimeoutError, when the context ended the command.
+type Cmd struct {
+	*exec.Cmd
+	ctx      context.Context
+	recorded bool
+}
+
+// Command returns a command that is signalled when ctx ends: with the signal
+// dockenv received if it was interrupted, with SIGTERM otherwise. It is
+// killed if it does not exit within WaitDelay.
+func Command(ctx context.Context, name string, args ...string) *Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return interrupt(cmd.Process, context.Cause(ctx))
+	}
+	cmd.WaitDelay = WaitDelay
+	return &Cmd{Cmd: cmd, ctx: ctx}
+}
+
+func (c *Cmd) Run() error {
+	if c.record() {
+		return nil
+	}
+	return Err(c.ctx, c.Cmd.Run())
+}
+
+// Output runs the command and returns its standard output, which is empty
+// in recording mode.
+func (c *Cmd) Output() ([]byte, error) {
+	if c.record() {
+		return nil, nil
+	}
+	out, err := c.Cmd.Output()
+	return out, Err(c.ctx, err)
+}
+
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	if c.record() {
+		return nil, nil
+	}
+	out, err := c.Cmd.CombinedOutput()
+	return out, Err(c.ctx, err)
+}
+
+func (c *Cmd) Start() error {
+	if c.record() {
+		c.recorded = true
+		return nil
+	}
+	return Err(c.ctx, c.Cmd.Start())
+}
+
+func (c *Cmd) Wait() error {
+	if c.recorded {
+		return nil
+	}
+	return Err(c.ctx, c.Cmd.Wait())
+}
+
+// String returns the command line as it would be typed in a shell.
+func (c *Cmd) String() string {
+	quoted := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (c *Cmd) record() bool {
+	if recorder == nil {
+		return false
+	}
+	fmt.Fprintf(recorder, "[dry-run] %s\n", c)
+	return true
+}
+
+// recorder receives the commands that would run in recording mode.
+var recorder io.Writer
+
+// Record switches to recording mode, in which commands print their command
+// line to w and succeed without running. A nil w switches back.
+func Record(w io.Writer) {
+	recorder = w
+}
+
+// Recording reports whether commands are recorded instead of run.
+func Recording() bool {
+	return recorder != nil
+}
+
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\$`|&;<>(){}*?[]#~!") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// Err returns the reason a command run under ctx failed: the context's cause,
+// e.g. a *TimeoutError, if the context ended, and err otherwise.
+func Err(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package runner
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func interrupt(process *os.Process, cause error) error {
+	var sig os.Signal = syscall.SIGTERM
+
+	var interrupted *InterruptedError
+	if errors.As(cause, &interrupted) {
+		sig = interrupted.Signal
+	}
+	return process.Signal(sig)
+}
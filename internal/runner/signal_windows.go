@@ -0,0 +1,10 @@
+//go:build windows
+
+package runner
+
+import "os"
+
+// Windows cannot deliver signals to other processes
+func interrupt(process *os.Process, cause error) error {
+	return process.Kill()
+}
@@ -0,0 +1,192 @@
+// Package seed finds and describes the init scripts of a project, kept per
+// service under .dockenv/init/<service>, which give fresh service data a
+// schema and fixtures.
+package seed
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+)
+
+const (
+	// InitDBDir is where the images of some databases look for scripts to
+	// run on their first start
+	InitDBDir = "/docker-entrypoint-initdb.d"
+
+	// Marker is created in the data directory of a service once dockenv
+	// has run its scripts there
+	Marker = ".dockenv-seeded"
+)
+
+// Method describes how the scripts of a service are run.
+type Method struct {
+	// InitDB is set for images that run the scripts in InitDBDir
+	// themselves when they start with empty data. For other images dockenv
+	// runs the scripts once the service is healthy.
+	InitDB bool
+	// DataDir is the data directory in the service's container
+	DataDir string
+	// Commands holds, by file extension, a shell script that runs in the
+	// container and reads a script of that type from standard input
+	Commands map[string]string
+}
+
+// shellCommand runs .sh scripts with the container's environment, which
+// carries the credentials of the service.
+const shellCommand = `exec sh -s`
+
+// Methods holds the script types every catalog service runs. The database
+// commands connect as the entrypoints of their images do, so scripts behave
+// the same whether the image or dockenv runs them.
+var Methods = map[string]Method{
+	"postgres": {
+		InitDB:  true,
+		DataDir: "/var/lib/postgresql/data",
+		Commands: map[string]string{
+			".sql":    `exec psql -q -v ON_ERROR_STOP=1 -U "$POSTGRES_USER" -d "$POSTGRES_DB"`,
+			".sql.gz": `gunzip | psql -q -v ON_ERROR_STOP=1 -U "$POSTGRES_USER" -d "$POSTGRES_DB"`,
+			".sh":     shellCommand,
+		},
+	},
+	"mysql": {
+		InitDB:  true,
+		DataDir: "/var/lib/mysql",
+		Commands: map[string]string{
+			".sql":    `MYSQL_PWD="$MYSQL_ROOT_PASSWORD" exec mysql -uroot "$MYSQL_DATABASE"`,
+			".sql.gz": `gunzip | MYSQL_PWD="$MYSQL_ROOT_PASSWORD" mysql -uroot "$MYSQL_DATABASE"`,
+			".sh":     shellCommand,
+		},
+	},
+	"mongodb": {
+		InitDB:  true,
+		DataDir: "/data/db",
+		Commands: map[string]string{
+			// Images before MongoDB 6 only have the legacy shell
+			".js": `exec "$(command -v mongosh || command -v mongo)" --quiet -u "$MONGO_INITDB_ROOT_USERNAME" ` +
+				`-p "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin "$MONGO_INITDB_DATABASE"`,
+			".sh": shellCommand,
+		},
+	},
+	"redis": {
+		DataDir: "/data",
+		Commands: map[string]string{
+			".redis": `exec redis-cli`,
+			".sh":    shellCommand,
+		},
+	},
+	"rabbitmq": {
+		DataDir:  "/var/lib/rabbitmq",
+		Commands: map[string]string{".sh": shellCommand},
+	},
+	"elasticsearch": {
+		DataDir:  "/usr/share/elasticsearch/data",
+		Commands: map[string]string{".sh": shellCommand},
+	},
+	"kafka": {
+		DataDir:  "/var/lib/kafka/data",
+		Commands: map[string]string{".sh": shellCommand},
+	},
+}
+
+// LookupMethod returns how the scripts of a service are run.
+func LookupMethod(serviceName string) (Method, bool) {
+	method, exists := Methods[serviceName]
+	return method, exists
+}
+
+// ServiceDir returns the directory of a service's scripts, relative to the
+// project directory.
+func ServiceDir(serviceName string) string {
+	return filepath.Join(filepath.FromSlash(config.InitDir), serviceName)
+}
+
+// HasScripts reports whether the project has a script directory for a
+// service.
+func HasScripts(serviceName string) bool {
+	info, err := os.Stat(ServiceDir(serviceName))
+	return err == nil && info.IsDir()
+}
+
+// Mounted reports whether the image of a service runs its scripts itself,
+// from the script directory mounted at InitDBDir. The directory is on this
+// machine, so it cannot be mounted into the containers of a remote engine.
+func Mounted(serviceName string, remote bool) bool {
+	method, exists := LookupMethod(serviceName)
+	return exists && method.InitDB && !remote && HasScripts(serviceName)
+}
+
+// InitMounts returns the bind mount of the script directory of each of the
+// services that are Mounted, keyed by service, e.g.
+// "./.dockenv/init/postgres:/docker-entrypoint-initdb.d".
+func InitMounts(serviceNames []string, remote bool) map[string]string {
+	mounts := make(map[string]string)
+	for _, serviceName := range serviceNames {
+		if Mounted(serviceName, remote) {
+			mounts[serviceName] = "./" + filepath.ToSlash(ServiceDir(serviceName)) + ":" + InitDBDir
+		}
+	}
+	return mounts
+}
+
+// MarkerPath returns the path of the marker in the service's container.
+func (m Method) MarkerPath() string {
+	return path.Join(m.DataDir, Marker)
+}
+
+// Script is an init script and the command that runs it.
+type Script struct {
+	Name    string
+	Path    string
+	Command string
+}
+
+// Command returns the command that runs a script file, matching the longest
+// extension so that "dump.sql.gz" is not taken for SQL.
+func (m Method) Command(name string) (string, bool) {
+	extension := ""
+	for ext := range m.Commands {
+		if strings.HasSuffix(name, ext) && len(ext) > len(extension) {
+			extension = ext
+		}
+	}
+	if extension == "" {
+		return "", false
+	}
+	return m.Commands[extension], true
+}
+
+// Scripts returns the scripts in dir in the order of their names, which is
+// the order the images run them in. Files of a type the service cannot run
+// are returned as skipped. A missing directory has no scripts.
+func Scripts(dir string, method Method) (scripts []Script, skipped []string, err error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read init scripts: %w", err)
+	}
+
+	// The entries come sorted by name
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		command, exists := method.Command(entry.Name())
+		if !exists {
+			skipped = append(skipped, entry.Name())
+			continue
+		}
+		scripts = append(scripts, Script{
+			Name:    entry.Name(),
+			Path:    filepath.Join(dir, entry.Name()),
+			Command: command,
+		})
+	}
+	return scripts, skipped, nil
+}
@@ -2,17 +2,38 @@ package services
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
+// PortSpec describes a named container port a service publishes on the host.
+type PortSpec struct {
+	Name          string
+	ContainerPort int
+	DefaultPort   int
+	// EnvVar receives the host port in the generated .env, if set
+	EnvVar string
+	// Optional ports are only published when a host port is configured
+	Optional bool
+}
+
 type Service struct {
 	Name        string
 	DisplayName string
 	Description string
-	DefaultPort int
-	Template    string
-	Volumes     []string
-	EnvVars     map[string]string
+	Ports       []PortSpec
+	// Image is the repository of the main image and Version its default
+	// tag. Versions lists the tags the catalog knows, oldest first.
+	Image    string
+	Version  string
+	Versions []string
+	// Migrations lists the major upgrades the data survives in place
+	Migrations []Migration
+	Template   string
+	Volumes    []string
+	EnvVars    map[string]string
+	// DataOwner is the uid:gid the image writes its data directories as
+	DataOwner string
 }
 
 var AvailableServices = map[string]Service{
@@ -20,13 +41,18 @@ var AvailableServices = map[string]Service{
 		Name:        "mysql",
 		DisplayName: "MySQL",
 		Description: "MySQL Database Server",
-		DefaultPort: 3306,
-		Template:    "mysql.yaml",
-		Volumes:     []string{"mysql_data"},
+		Ports: []PortSpec{
+			{Name: "mysql", ContainerPort: 3306, DefaultPort: 3306, EnvVar: "DB_PORT"},
+		},
+		Image:     "mysql",
+		Version:   "8.0",
+		Versions:  []string{"5.7", "8.0", "8.4", "9.0", "9.1"},
+		Template:  "mysql.yaml",
+		Volumes:   []string{"mysql_data"},
+		DataOwner: "999:999",
 		EnvVars: map[string]string{
 			"DB_CONNECTION": "mysql",
 			"DB_HOST":       "127.0.0.1",
-			"DB_PORT":       "3306",
 			"DB_DATABASE":   "dockenv",
 			"DB_USERNAME":   "dockenv",
 			"DB_PASSWORD":   "password",
@@ -36,13 +62,18 @@ var AvailableServices = map[string]Service{
 		Name:        "postgres",
 		DisplayName: "PostgreSQL",
 		Description: "PostgreSQL Database Server",
-		DefaultPort: 5432,
-		Template:    "postgres.yaml",
-		Volumes:     []string{"postgres_data"},
+		Ports: []PortSpec{
+			{Name: "postgres", ContainerPort: 5432, DefaultPort: 5432, EnvVar: "DB_PORT"},
+		},
+		Image:     "postgres",
+		Version:   "15",
+		Versions:  []string{"13", "14", "15", "16", "17"},
+		Template:  "postgres.yaml",
+		Volumes:   []string{"postgres_data"},
+		DataOwner: "999:999",
 		EnvVars: map[string]string{
 			"DB_CONNECTION": "pgsql",
 			"DB_HOST":       "127.0.0.1",
-			"DB_PORT":       "5432",
 			"DB_DATABASE":   "dockenv",
 			"DB_USERNAME":   "dockenv",
 			"DB_PASSWORD":   "password",
@@ -52,12 +83,21 @@ var AvailableServices = map[string]Service{
 		Name:        "redis",
 		DisplayName: "Redis",
 		Description: "Redis In-Memory Data Store",
-		DefaultPort: 6379,
-		Template:    "redis.yaml",
-		Volumes:     []string{"redis_data"},
+		Ports: []PortSpec{
+			{Name: "redis", ContainerPort: 6379, DefaultPort: 6379, EnvVar: "REDIS_PORT"},
+		},
+		Image:    "redis",
+		Version:  "7-alpine",
+		Versions: []string{"6-alpine", "6.2-alpine", "7-alpine", "7.2-alpine", "7.4-alpine", "8-alpine"},
+		Migrations: []Migration{
+			{From: 6, To: 7, Note: "Redis 7 loads RDB and AOF files written by Redis 6."},
+			{From: 7, To: 8, Note: "Redis 8 loads RDB and AOF files written by Redis 7."},
+		},
+		Template:  "redis.yaml",
+		Volumes:   []string{"redis_data"},
+		DataOwner: "999:1000",
 		EnvVars: map[string]string{
 			"REDIS_HOST":     "127.0.0.1",
-			"REDIS_PORT":     "6379",
 			"REDIS_PASSWORD": "",
 		},
 	},
@@ -65,12 +105,22 @@ var AvailableServices = map[string]Service{
 		Name:        "mongodb",
 		DisplayName: "MongoDB",
 		Description: "MongoDB NoSQL Database",
-		DefaultPort: 27017,
-		Template:    "mongodb.yaml",
-		Volumes:     []string{"mongodb_data"},
+		Ports: []PortSpec{
+			{Name: "mongodb", ContainerPort: 27017, DefaultPort: 27017, EnvVar: "MONGO_PORT"},
+		},
+		Image:    "mongo",
+		Version:  "7",
+		Versions: []string{"5", "6", "7", "8"},
+		Migrations: []Migration{
+			{From: 5, To: 6, Note: "Set featureCompatibilityVersion to \"5.0\" before upgrading."},
+			{From: 6, To: 7, Note: "Set featureCompatibilityVersion to \"6.0\" before upgrading."},
+			{From: 7, To: 8, Note: "Set featureCompatibilityVersion to \"7.0\" before upgrading."},
+		},
+		Template:  "mongodb.yaml",
+		Volumes:   []string{"mongodb_data"},
+		DataOwner: "999:999",
 		EnvVars: map[string]string{
 			"MONGO_HOST":     "127.0.0.1",
-			"MONGO_PORT":     "27017",
 			"MONGO_DATABASE": "dockenv",
 			"MONGO_USERNAME": "dockenv",
 			"MONGO_PASSWORD": "password",
@@ -80,36 +130,60 @@ var AvailableServices = map[string]Service{
 		Name:        "kafka",
 		DisplayName: "Apache Kafka",
 		Description: "Apache Kafka Message Broker",
-		DefaultPort: 9092,
-		Template:    "kafka.yaml",
-		Volumes:     []string{"kafka_data", "zookeeper_data"},
+		Ports: []PortSpec{
+			{Name: "broker", ContainerPort: 9092, DefaultPort: 9092, EnvVar: "KAFKA_PORT"},
+			{Name: "jmx", ContainerPort: 9101, DefaultPort: 9101, Optional: true},
+		},
+		Image:     "confluentinc/cp-kafka",
+		Version:   "latest",
+		Versions:  []string{"7.4.0", "7.5.0", "7.6.0", "7.7.0", "7.8.0"},
+		Template:  "kafka.yaml",
+		Volumes:   []string{"kafka_data", "zookeeper_data"},
+		DataOwner: "1000:1000",
 		EnvVars: map[string]string{
 			"KAFKA_HOST": "127.0.0.1",
-			"KAFKA_PORT": "9092",
 		},
 	},
 	"elasticsearch": {
 		Name:        "elasticsearch",
 		DisplayName: "Elasticsearch",
 		Description: "Elasticsearch Search Engine",
-		DefaultPort: 9200,
-		Template:    "elasticsearch.yaml",
-		Volumes:     []string{"elasticsearch_data"},
+		Ports: []PortSpec{
+			{Name: "http", ContainerPort: 9200, DefaultPort: 9200, EnvVar: "ELASTICSEARCH_PORT"},
+			{Name: "transport", ContainerPort: 9300, DefaultPort: 9300, Optional: true},
+		},
+		Image:    "docker.elastic.co/elasticsearch/elasticsearch",
+		Version:  "8.11.0",
+		Versions: []string{"7.17.0", "8.11.0", "8.11.4", "8.12.2", "8.13.4", "8.14.3", "8.15.0"},
+		Migrations: []Migration{
+			{From: 7, To: 8, Note: "Upgrade to 7.17 first and resolve the deprecation warnings it reports."},
+		},
+		Template:  "elasticsearch.yaml",
+		Volumes:   []string{"elasticsearch_data"},
+		DataOwner: "1000:0",
 		EnvVars: map[string]string{
 			"ELASTICSEARCH_HOST": "127.0.0.1",
-			"ELASTICSEARCH_PORT": "9200",
 		},
 	},
 	"rabbitmq": {
 		Name:        "rabbitmq",
 		DisplayName: "RabbitMQ",
 		Description: "RabbitMQ Message Broker",
-		DefaultPort: 5672,
-		Template:    "rabbitmq.yaml",
-		Volumes:     []string{"rabbitmq_data"},
+		Ports: []PortSpec{
+			{Name: "amqp", ContainerPort: 5672, DefaultPort: 5672, EnvVar: "RABBITMQ_PORT"},
+			{Name: "management", ContainerPort: 15672, DefaultPort: 15672, EnvVar: "RABBITMQ_MANAGEMENT_PORT"},
+		},
+		Image:    "rabbitmq",
+		Version:  "3-management",
+		Versions: []string{"3-management", "3.12-management", "3.13-management", "4-management", "4.0-management"},
+		Migrations: []Migration{
+			{From: 3, To: 4, Note: "Enable all stable feature flags on 3.13 before upgrading."},
+		},
+		Template:  "rabbitmq.yaml",
+		Volumes:   []string{"rabbitmq_data"},
+		DataOwner: "999:999",
 		EnvVars: map[string]string{
 			"RABBITMQ_HOST":     "127.0.0.1",
-			"RABBITMQ_PORT":     "5672",
 			"RABBITMQ_USERNAME": "dockenv",
 			"RABBITMQ_PASSWORD": "password",
 		},
@@ -131,6 +205,102 @@ func GetService(name string) (Service, bool) {
 	return service, exists
 }
 
+// ContainerName returns the name of the service's container.
+func (s Service) ContainerName() string {
+	return "dockenv-" + s.Name
+}
+
+// PrimaryPort returns the port clients normally connect to.
+func (s Service) PrimaryPort() PortSpec {
+	return s.Ports[0]
+}
+
+func (s Service) GetPort(name string) (PortSpec, bool) {
+	for _, spec := range s.Ports {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return PortSpec{}, false
+}
+
+// DataDirs returns the directories below the data path that hold the
+// service's bind-mounted data, e.g. "kafka" and "zookeeper" for Kafka.
+func (s Service) DataDirs() []string {
+	dirs := make([]string, 0, len(s.Volumes))
+	for _, volume := range s.Volumes {
+		dirs = append(dirs, strings.TrimSuffix(volume, "_data"))
+	}
+	return dirs
+}
+
+func (s Service) PortNames() []string {
+	names := make([]string, 0, len(s.Ports))
+	for _, spec := range s.Ports {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// HostPorts returns the host port of every published port by name, applying
+// the configured overrides to the defaults. Optional ports are only included
+// when configured.
+func (s Service) HostPorts(configured map[string]int) map[string]int {
+	hostPorts := make(map[string]int)
+	for _, spec := range s.Ports {
+		if port := configured[spec.Name]; port != 0 {
+			hostPorts[spec.Name] = port
+		} else if !spec.Optional {
+			hostPorts[spec.Name] = spec.DefaultPort
+		}
+	}
+	return hostPorts
+}
+
+// PortEnvVars returns the environment variables that carry the given host
+// ports, e.g. DB_PORT for MySQL.
+func (s Service) PortEnvVars(hostPorts map[string]int) map[string]string {
+	envVars := make(map[string]string)
+	for _, spec := range s.Ports {
+		if port, exists := hostPorts[spec.Name]; exists && spec.EnvVar != "" {
+			envVars[spec.EnvVar] = strconv.Itoa(port)
+		}
+	}
+	return envVars
+}
+
+// ParsePortSpec parses a port override of the form service:port or
+// service.name:port, where name selects one of the service's named ports and
+// defaults to the primary port.
+func ParsePortSpec(spec string) (string, string, int, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) != 2 {
+		return "", "", 0, fmt.Errorf("invalid port specification: %s (expected format: service[.name]:port)", spec)
+	}
+
+	serviceName, portName, _ := strings.Cut(parts[0], ".")
+
+	port, err := strconv.Atoi(parts[1])
+	if err != nil || port <= 0 || port > 65535 {
+		return "", "", 0, fmt.Errorf("invalid port number: %s", parts[1])
+	}
+
+	service, exists := GetService(serviceName)
+	if !exists {
+		return "", "", 0, fmt.Errorf("unknown service: %s. Available services: %s",
+			serviceName, strings.Join(GetServiceNames(), ", "))
+	}
+
+	if portName == "" {
+		portName = service.PrimaryPort().Name
+	} else if _, exists := service.GetPort(portName); !exists {
+		return "", "", 0, fmt.Errorf("unknown port %s for %s. Available ports: %s",
+			portName, serviceName, strings.Join(service.PortNames(), ", "))
+	}
+
+	return serviceName, portName, port, nil
+}
+
 func GetServiceNames() []string {
 	names := make([]string, 0, len(AvailableServices))
 	for name := range AvailableServices {
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed image tag such as "15", "8.11.0" or "7-alpine".
+// Tags only compare when they share a variant suffix, e.g. "-alpine".
+type Version struct {
+	Tag    string
+	Parts  []int
+	Suffix string
+}
+
+// ParseVersion parses a numeric image tag with an optional variant suffix.
+// Floating tags without a version, like "latest", do not parse.
+func ParseVersion(tag string) (Version, bool) {
+	numeric, suffix := tag, ""
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		numeric, suffix = tag[:i], tag[i:]
+	}
+
+	var parts []int
+	for _, field := range strings.Split(numeric, ".") {
+		part, err := strconv.Atoi(strings.TrimPrefix(field, "v"))
+		if err != nil || part < 0 {
+			return Version{}, false
+		}
+		parts = append(parts, part)
+	}
+	return Version{Tag: tag, Parts: parts, Suffix: suffix}, true
+}
+
+// Major returns the first component of the version.
+func (v Version) Major() int {
+	return v.Parts[0]
+}
+
+// Compare orders versions by their components, treating missing ones as
+// zero, so a patch tag like "15.6" is newer than the floating "15".
+func (v Version) Compare(other Version) int {
+	for i := 0; i < len(v.Parts) || i < len(other.Parts); i++ {
+		a, b := 0, 0
+		if i < len(v.Parts) {
+			a = v.Parts[i]
+		}
+		if i < len(other.Parts) {
+			b = other.Parts[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	// "15.0" is more specific than "15" but not newer
+	return 0
+}
+
+// NewerVersions returns the tags that are newer than current and share its
+// variant, oldest first and without duplicates.
+func NewerVersions(current string, tags []string) []Version {
+	currentVersion, ok := ParseVersion(current)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var newer []Version
+	for _, tag := range tags {
+		version, ok := ParseVersion(tag)
+		if !ok || seen[tag] || version.Suffix != currentVersion.Suffix || version.Compare(currentVersion) <= 0 {
+			continue
+		}
+		seen[tag] = true
+		newer = append(newer, version)
+	}
+
+	sort.SliceStable(newer, func(i, j int) bool {
+		if c := newer[i].Compare(newer[j]); c != 0 {
+			return c < 0
+		}
+		// Among equal versions the floating, shorter tag sorts last, so
+		// that it is the one picked as the newest
+		return len(newer[i].Parts) > len(newer[j].Parts)
+	})
+	return newer
+}
+
+// LatestVersions returns the newest of tags within the major version of
+// current and the newest overall. Either is empty when there is none newer.
+func LatestVersions(current string, tags []string) (wanted, latest string) {
+	newer := NewerVersions(current, tags)
+	if len(newer) == 0 {
+		return "", ""
+	}
+
+	currentVersion, _ := ParseVersion(current)
+	for _, version := range newer {
+		if version.Major() == currentVersion.Major() {
+			wanted = version.Tag
+		}
+	}
+	return wanted, newer[len(newer)-1].Tag
+}
+
+// Migration allows moving the data of a service from one major version to
+// another in place, e.g. because the newer server upgrades the files.
+type Migration struct {
+	From int
+	To   int
+	// Note is shown before upgrading, e.g. a step to take first
+	Note string
+}
+
+// ResolveVersion returns the image tag to use: the configured one, or the
+// catalog default.
+func (s Service) ResolveVersion(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	return s.Version
+}
+
+// Stateful reports whether the service keeps data that a new major version
+// may not be able to read.
+func (s Service) Stateful() bool {
+	return len(s.Volumes) > 0
+}
+
+// FindMigration returns the migration from one major version to another.
+func (s Service) FindMigration(from, to int) (Migration, bool) {
+	for _, migration := range s.Migrations {
+		if migration.From == from && migration.To == to {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
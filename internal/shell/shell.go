@@ -0,0 +1,151 @@
+// Package shell describes the native clients of services, which dockenv
+// starts inside their containers with the project's connection settings.
+package shell
+
+// Connection holds the settings a client connects with, taken from the
+// project's configuration.
+type Connection struct {
+	Database string
+	Username string
+	Password string
+}
+
+// Client describes the native client of a service.
+type Client struct {
+	Tool string
+	// DatabaseVar, UsernameVar and PasswordVar name the configuration's
+	// environment variables holding the connection settings
+	DatabaseVar string
+	UsernameVar string
+	PasswordVar string
+	// PasswordEnv is the variable the password is passed to the client in,
+	// which keeps it off the command line
+	PasswordEnv string
+	// Command returns the command line of the client. args are passed on to
+	// the client; interactive is set when it runs in a terminal rather than
+	// reading from a pipe.
+	Command func(conn Connection, args []string, interactive bool) []string
+}
+
+// passwordEnv carries the password of clients that only take it as an
+// argument, which a shell in the container then passes on.
+const passwordEnv = "DOCKENV_PASSWORD"
+
+// kafkaBootstrap is the broker's listener inside its container.
+const kafkaBootstrap = "localhost:9092"
+
+// Clients holds the native clients of the services that have one.
+var Clients = map[string]Client{
+	"postgres": {
+		Tool:        "psql",
+		DatabaseVar: "DB_DATABASE",
+		UsernameVar: "DB_USERNAME",
+		PasswordVar: "DB_PASSWORD",
+		PasswordEnv: "PGPASSWORD",
+		Command: func(conn Connection, args []string, interactive bool) []string {
+			command := []string{"psql", "-U", conn.Username, "-d", conn.Database}
+			if !interactive {
+				// Fail on the first error of piped SQL, with psql's exit status
+				command = append(command, "-v", "ON_ERROR_STOP=1")
+			}
+			return append(command, args...)
+		},
+	},
+	"mysql": {
+		Tool:        "mysql",
+		DatabaseVar: "DB_DATABASE",
+		UsernameVar: "DB_USERNAME",
+		PasswordVar: "DB_PASSWORD",
+		PasswordEnv: "MYSQL_PWD",
+		Command: func(conn Connection, args []string, interactive bool) []string {
+			return append([]string{"mysql", "-u", conn.Username, conn.Database}, args...)
+		},
+	},
+	"mongodb": {
+		Tool:        "mongosh",
+		DatabaseVar: "MONGO_DATABASE",
+		UsernameVar: "MONGO_USERNAME",
+		PasswordVar: "MONGO_PASSWORD",
+		PasswordEnv: passwordEnv,
+		Command: func(conn Connection, args []string, interactive bool) []string {
+			// Images before MongoDB 6 only have the legacy shell
+			command := []string{"sh", "-c", `exec "$(command -v mongosh || command -v mongo)" -p "$` + passwordEnv + `" "$@"`, "mongosh",
+				"-u", conn.Username, "--authenticationDatabase", "admin"}
+			if !interactive {
+				command = append(command, "--quiet")
+			}
+			return append(append(command, conn.Database), args...)
+		},
+	},
+	"redis": {
+		Tool:        "redis-cli",
+		PasswordVar: "REDIS_PASSWORD",
+		PasswordEnv: "REDISCLI_AUTH",
+		Command: func(conn Connection, args []string, interactive bool) []string {
+			return append([]string{"redis-cli"}, args...)
+		},
+	},
+	"rabbitmq": {
+		Tool:        "rabbitmqadmin",
+		UsernameVar: "RABBITMQ_USERNAME",
+		PasswordVar: "RABBITMQ_PASSWORD",
+		PasswordEnv: passwordEnv,
+		Command: func(conn Connection, args []string, interactive bool) []string {
+			// rabbitmqadmin has no prompt, so show the broker by default
+			if len(args) == 0 {
+				args = []string{"show", "overview"}
+			}
+			command := []string{"sh", "-c", `exec rabbitmqadmin -p "$` + passwordEnv + `" "$@"`, "rabbitmqadmin", "-u", conn.Username}
+			return append(command, args...)
+		},
+	},
+	"kafka": {
+		Tool: "kafka-console tools",
+		// The topics are listed without one; with a topic the messages are
+		// consumed in a terminal, and produced from lines piped in
+		Command: func(conn Connection, args []string, interactive bool) []string {
+			if len(args) == 0 {
+				return []string{"kafka-topics", "--bootstrap-server", kafkaBootstrap, "--list"}
+			}
+			command := []string{"kafka-console-producer", "--bootstrap-server", kafkaBootstrap, "--topic", args[0]}
+			if interactive {
+				command = []string{"kafka-console-consumer", "--bootstrap-server", kafkaBootstrap, "--topic", args[0], "--from-beginning"}
+			}
+			return append(command, args[1:]...)
+		},
+	},
+}
+
+// LookupClient returns the native client of a service.
+func LookupClient(serviceName string) (Client, bool) {
+	client, exists := Clients[serviceName]
+	return client, exists
+}
+
+// Connect returns the connection settings of a client, taking each from the
+// configured environment and falling back to the service's defaults.
+func (c Client) Connect(configured, defaults map[string]string) Connection {
+	value := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		if value, exists := configured[name]; exists {
+			return value
+		}
+		return defaults[name]
+	}
+	return Connection{
+		Database: value(c.DatabaseVar),
+		Username: value(c.UsernameVar),
+		Password: value(c.PasswordVar),
+	}
+}
+
+// Env returns the environment the client needs in its container.
+func (c Client) Env(conn Connection) map[string]string {
+	env := make(map[string]string)
+	if c.PasswordEnv != "" && conn.Password != "" {
+		env[c.PasswordEnv] = conn.Password
+	}
+	return env
+}
@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// BranchPrefix starts the names of the snapshots kept per git branch
+	BranchPrefix = "branch-"
+
+	// branchStateFile in the snapshots directory names the branch whose
+	// data is in the data directories
+	branchStateFile = ".branch"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// BranchName returns the name of the snapshot kept for a git branch, e.g.
+// "branch-main". Branches with characters a name cannot hold, such as
+// "feature/login", get a hash suffix so that they do not collide with one
+// spelled "feature-login".
+func BranchName(branch string) string {
+	name := invalidNameChars.ReplaceAllString(branch, "-")
+	if name != branch {
+		sum := sha256.Sum256([]byte(branch))
+		name += "-" + hex.EncodeToString(sum[:4])
+	}
+	return BranchPrefix + name
+}
+
+// LiveBranch returns the branch whose data is in the data directories, or
+// "" if branch data was never synced.
+func LiveBranch(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, branchStateFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read branch state: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetLiveBranch records the branch whose data is in the data directories.
+func SetLiveBranch(root, branch string) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, branchStateFile), []byte(branch+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write branch state: %w", err)
+	}
+	return nil
+}
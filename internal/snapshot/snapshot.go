@@ -0,0 +1,148 @@
+// Package snapshot keeps copies of the data directories of services under
+// the data path, so that a project can jump between states of its data
+// without dumping and reloading it.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	// ManifestName is the file in a snapshot directory describing it
+	ManifestName = "snapshot.json"
+
+	ManifestVersion = 1
+)
+
+// The copies are made by the engine in a container running as root, as the
+// data directories belong to the users of the service images. Hard links
+// would be faster still, but databases change their files in place, which
+// would change the snapshot along with them. The scripts take the snapshot
+// directory and then the data directories as arguments, relative to /data.
+const (
+	// CreateScript copies data directories into a snapshot, replacing any
+	// left over from an interrupted one
+	CreateScript = `set -e
+dest="/data/$1"; shift
+mkdir -p "$dest"
+for dir in "$@"; do
+  rm -rf "$dest/$dir"
+  cp -a --reflink=auto "/data/$dir" "$dest/$dir"
+done`
+
+	// RestoreScript replaces data directories with their copies in a
+	// snapshot. Everything is copied before anything is replaced, so a
+	// failed copy leaves the data untouched.
+	RestoreScript = `set -e
+src="/data/$1"; shift
+for dir in "$@"; do
+  rm -rf "/data/.$dir.restore"
+  cp -a --reflink=auto "$src/$dir" "/data/.$dir.restore"
+done
+for dir in "$@"; do
+  rm -rf "/data/$dir"
+  mv "/data/.$dir.restore" "/data/$dir"
+done`
+
+	// DeleteScript removes a snapshot
+	DeleteScript = `rm -rf "/data/$1"`
+)
+
+// Manifest describes a snapshot.
+type Manifest struct {
+	Version  int       `json:"version"`
+	Name     string    `json:"name"`
+	Created  time.Time `json:"created"`
+	Project  string    `json:"project"`
+	Services []string  `json:"services"`
+	// Versions holds the image tag each service ran when the snapshot was
+	// taken, as a new major version may not read the data of an old one
+	Versions map[string]string `json:"versions"`
+	// Dirs are the data directories in the snapshot
+	Dirs []string `json:"dirs"`
+}
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateName checks that a snapshot name is usable as a directory name.
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Dir returns the directory of a snapshot under the snapshots directory.
+func Dir(root, name string) string {
+	return filepath.Join(root, name)
+}
+
+// Load reads the manifest of a snapshot. The error satisfies os.IsNotExist
+// when there is no such snapshot.
+func Load(root, name string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(root, name), ManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", name, err)
+	}
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("unsupported version %d of snapshot %s", manifest.Version, name)
+	}
+	return &manifest, nil
+}
+
+// Save writes the manifest into the snapshot's directory, creating it.
+func (m *Manifest) Save(root string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	dir := Dir(root, m.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// List returns the snapshots under root, oldest first. Directories without
+// a readable manifest, e.g. of an interrupted snapshot, are skipped.
+func List(root string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	var manifests []*Manifest
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest, err := Load(root, entry.Name())
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].Created.Before(manifests[j].Created)
+	})
+	return manifests, nil
+}
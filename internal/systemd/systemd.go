@@ -1,14 +1,18 @@
 package systemd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strings"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
 )
 
+// ServiceFilePath is where EnableAutostart installs the unit.
+const ServiceFilePath = "/etc/systemd/system/" + config.SystemdService
+
 const systemdTemplate = `[Unit]
 Description=Dockenv Development Services
 Requires=docker.service
@@ -28,7 +32,7 @@ User=%s
 WantedBy=multi-user.target
 `
 
-func EnableAutostart() error {
+func EnableAutostart(ctx context.Context) error {
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -44,30 +48,36 @@ func EnableAutostart() error {
 	// Create systemd service file content
 	serviceContent := fmt.Sprintf(systemdTemplate, cwd, user)
 
-	// Write service file to temporary location
+	// Write service file to temporary location, which is left alone when
+	// commands are only recorded
 	tmpServiceFile := "/tmp/dockenv.service"
-	if err := os.WriteFile(tmpServiceFile, []byte(serviceContent), 0644); err != nil {
+	if runner.Recording() {
+		fmt.Printf("🔍 Dry run: would install this unit:\n%s\n", serviceContent)
+	} else if err := os.WriteFile(tmpServiceFile, []byte(serviceContent), 0644); err != nil {
 		return fmt.Errorf("failed to write service file: %w", err)
 	}
 
 	// Copy service file to systemd directory (requires sudo)
-	systemdDir := "/etc/systemd/system"
-	serviceFile := filepath.Join(systemdDir, config.SystemdService)
+	serviceFile := ServiceFilePath
 
-	if err := exec.Command("sudo", "cp", tmpServiceFile, serviceFile).Run(); err != nil {
+	if err := runner.Command(ctx, "sudo", "cp", tmpServiceFile, serviceFile).Run(); err != nil {
 		return fmt.Errorf("failed to copy service file (try running with sudo): %w", err)
 	}
 
 	// Reload systemd
-	if err := exec.Command("sudo", "systemctl", "daemon-reload").Run(); err != nil {
+	if err := runner.Command(ctx, "sudo", "systemctl", "daemon-reload").Run(); err != nil {
 		return fmt.Errorf("failed to reload systemd: %w", err)
 	}
 
 	// Enable service
-	if err := exec.Command("sudo", "systemctl", "enable", "dockenv.service").Run(); err != nil {
+	if err := runner.Command(ctx, "sudo", "systemctl", "enable", "dockenv.service").Run(); err != nil {
 		return fmt.Errorf("failed to enable service: %w", err)
 	}
 
+	if runner.Recording() {
+		return nil
+	}
+
 	// Clean up temporary file
 	os.Remove(tmpServiceFile)
 
@@ -82,42 +92,89 @@ func EnableAutostart() error {
 	return nil
 }
 
-func DisableAutostart() error {
+func DisableAutostart(ctx context.Context) error {
 	// Stop service if running (ignore errors as service might not be running)
-	_ = exec.Command("sudo", "systemctl", "stop", "dockenv.service").Run()
+	_ = runner.Command(ctx, "sudo", "systemctl", "stop", "dockenv.service").Run()
 
 	// Disable service
-	if err := exec.Command("sudo", "systemctl", "disable", "dockenv.service").Run(); err != nil {
+	if err := runner.Command(ctx, "sudo", "systemctl", "disable", "dockenv.service").Run(); err != nil {
 		return fmt.Errorf("failed to disable service: %w", err)
 	}
 
 	// Remove service file
-	serviceFile := filepath.Join("/etc/systemd/system", config.SystemdService)
-	if err := exec.Command("sudo", "rm", "-f", serviceFile).Run(); err != nil {
+	serviceFile := ServiceFilePath
+	if err := runner.Command(ctx, "sudo", "rm", "-f", serviceFile).Run(); err != nil {
 		return fmt.Errorf("failed to remove service file: %w", err)
 	}
 
 	// Reload systemd
-	if err := exec.Command("sudo", "systemctl", "daemon-reload").Run(); err != nil {
+	if err := runner.Command(ctx, "sudo", "systemctl", "daemon-reload").Run(); err != nil {
 		return fmt.Errorf("failed to reload systemd: %w", err)
 	}
 
+	if runner.Recording() {
+		return nil
+	}
+
 	fmt.Println("✅ Autostart disabled successfully!")
 	fmt.Printf("   Removed service file: %s\n", serviceFile)
 
 	return nil
 }
 
-func GetStatus() error {
-	return exec.Command("systemctl", "status", "dockenv.service").Run()
+func GetStatus(ctx context.Context) error {
+	return runner.Command(ctx, "systemctl", "status", "dockenv.service").Run()
 }
 
-func IsEnabled() bool {
-	err := exec.Command("systemctl", "is-enabled", "dockenv.service").Run()
+func IsEnabled(ctx context.Context) bool {
+	err := runner.Command(ctx, "systemctl", "is-enabled", "dockenv.service").Run()
 	return err == nil
 }
 
-func IsActive() bool {
-	err := exec.Command("systemctl", "is-active", "dockenv.service").Run()
+func IsActive(ctx context.Context) bool {
+	err := runner.Command(ctx, "systemctl", "is-active", "dockenv.service").Run()
 	return err == nil
 }
+
+// Unit holds the settings of an installed dockenv unit that doctor checks.
+type Unit struct {
+	WorkingDirectory string
+	ExecStart        string
+	User             string
+	Requires         []string
+}
+
+// ReadUnit reads the installed unit file. It returns an error satisfying
+// os.IsNotExist when autostart is not set up.
+func ReadUnit() (*Unit, error) {
+	content, err := os.ReadFile(ServiceFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	unit := ParseUnit(string(content))
+	return &unit, nil
+}
+
+// ParseUnit extracts the settings dockenv writes from unit file content.
+func ParseUnit(content string) Unit {
+	var unit Unit
+	for _, line := range strings.Split(content, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "WorkingDirectory":
+			unit.WorkingDirectory = value
+		case "ExecStart":
+			unit.ExecStart = value
+		case "User":
+			unit.User = value
+		case "Requires":
+			unit.Requires = append(unit.Requires, strings.Fields(value)...)
+		}
+	}
+	return unit
+}
@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+)
+
+// funcMap returns the helpers available to built-in and custom service
+// templates:
+//
+//	env "KEY" "default"  value of KEY from the configured env, then the
+//	                     process environment, falling back to default
+//	quote VALUE          VALUE as a double-quoted YAML string
+//	port "name"          configured host port of a service ("mysql"), of a
+//	                     named port ("rabbitmq.management") or of a named
+//	                     port of the service being rendered ("management")
+//	secret "name"        value from the secrets section of the config or the
+//	                     DOCKENV_SECRET_<NAME> environment variable
+//	projectName          Compose project name of the current directory
+//	hostIP               address the host reaches published ports on
+//	dataSource "name"    source of a data mount: the directory name under
+//	                     the data path, or the named volume name_data
+func funcMap(cfg *config.Config, data TemplateData) template.FuncMap {
+	return template.FuncMap{
+		"env": func(key string, defaultValue ...string) string {
+			if value, exists := cfg.Env[key]; exists {
+				return value
+			}
+			if value, exists := os.LookupEnv(key); exists {
+				return value
+			}
+			if len(defaultValue) > 0 {
+				return defaultValue[0]
+			}
+			return ""
+		},
+		"quote": func(value interface{}) string {
+			return yamlQuote(fmt.Sprint(value))
+		},
+		"port": func(name string) (int, error) {
+			if port, exists := data.Ports[name]; exists {
+				return port, nil
+			}
+			// Bare port names refer to the service being rendered
+			if port, exists := data.Ports[data.Name+"."+name]; exists {
+				return port, nil
+			}
+			return 0, fmt.Errorf("no port configured for %q", name)
+		},
+		"secret": func(name string) (string, error) {
+			if value, exists := cfg.Secrets[name]; exists {
+				return value, nil
+			}
+			envKey := "DOCKENV_SECRET_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+			if value, exists := os.LookupEnv(envKey); exists {
+				return value, nil
+			}
+			return "", fmt.Errorf("secret %q is not set; add it under 'secrets' in %s or set %s",
+				name, config.ConfigFileName, envKey)
+		},
+		"projectName": func() string {
+			return data.ProjectName
+		},
+		"hostIP": func() string {
+			return data.HostIP
+		},
+		"dataSource": func(name string) string {
+			if data.Volumes {
+				return name + "_data"
+			}
+			return data.DataPath + "/" + name
+		},
+	}
+}
+
+// yamlQuote renders a value as a YAML double-quoted scalar. Only backslashes,
+// double quotes and unprintable characters are escaped, so the rest is kept
+// as it is.
+func yamlQuote(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '\\' || r == '"':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r > 0x7f && r <= 0xffff && !unicode.IsPrint(r):
+			// C1 controls and Unicode line breaks, which YAML would fold
+			fmt.Fprintf(&b, `\u%04x`, r)
+		case r > 0xffff && !unicode.IsPrint(r):
+			fmt.Fprintf(&b, `\U%08x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -1,9 +1,14 @@
 package templates
 
 import (
+	"bytes"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
@@ -11,11 +16,98 @@ import (
 )
 
 type TemplateData struct {
+	Name     string
+	Instance string
+	Version  string
 	Port     int
 	DataPath string
 	Env      map[string]string
+
+	// Ports holds the host port of every configured service, keyed by
+	// service name for the primary port and by service.name for each
+	// named port, e.g. "rabbitmq.management"
+	Ports       map[string]int
+	Published   []PortMapping
+	ProjectName string
+	HostIP      string
+
+	// Host is the name clients connect to: localhost, or the machine a
+	// remote engine runs on
+	Host string
+
+	// Volumes is set when data is kept in named volumes instead of under
+	// DataPath
+	Volumes bool
+
+	// MountOptions is appended to data bind mounts, ":Z" under Podman so
+	// SELinux relabels the directories for the container
+	MountOptions string
+
+	// InitMount mounts the project's init scripts for the service where
+	// the image runs them itself, empty otherwise
+	InitMount string
+
+	// Resource limits, empty when not configured. HeapSize is half the
+	// memory limit and sizes JVM heaps and database buffer pools.
+	Memory   string
+	CPUs     string
+	HeapSize string
+}
+
+// Environment holds what rendering needs to know beyond the configuration.
+// Callers resolve it from the container runtime and the project's files, so
+// that rendering itself never probes either.
+type Environment struct {
+	// EngineHost is the machine a remote engine runs on, empty for an
+	// engine on this machine
+	EngineHost string
+
+	// Podman is set when the engine is Podman, whose bind mounts need
+	// SELinux labels
+	Podman bool
+
+	// InitMounts holds the init script mount of each service whose image
+	// runs the scripts itself, e.g.
+	// "./.dockenv/init/postgres:/docker-entrypoint-initdb.d"
+	InitMounts map[string]string
+
+	// Pins maps the images of each service to the references pinned to the
+	// lock file's digests
+	Pins map[string]map[string]string
 }
 
+// PortMapping is a container port published on the host.
+type PortMapping struct {
+	Name string
+	// HostIP is the bind address, bracketed for IPv6
+	HostIP        string
+	HostPort      int
+	ContainerPort int
+}
+
+// portsTemplate renders the ports section of a service from its published
+// port mappings via {{template "ports" .}}.
+const portsTemplate = `{{define "ports"}}{{if .Published}}
+    ports:
+{{- range .Published}}
+      - "{{.HostIP}}:{{.HostPort}}:{{.ContainerPort}}"
+{{- end}}
+{{- end}}{{end}}`
+
+// resourcesTemplate renders the deploy.resources block shared by every
+// service template via {{template "resources" .}}.
+const resourcesTemplate = `{{define "resources"}}{{if or .Memory .CPUs}}
+    deploy:
+      resources:
+        limits:
+{{- if .Memory}}
+          memory: {{.Memory}}
+{{- end}}
+{{- if .CPUs}}
+          cpus: "{{.CPUs}}"
+{{- end}}
+{{- end}}{{end}}`
+
 type ComposeData struct {
 	Version  string
 	Services map[string]TemplateData
@@ -23,147 +115,229 @@ type ComposeData struct {
 	Volumes  map[string]string
 }
 
-func GenerateDockerCompose(cfg *config.Config) error {
-	composeFile := config.GetComposePath()
+// newTemplateData collects the values a service template is rendered with.
+func newTemplateData(cfg *config.Config, env Environment, service services.Service) (TemplateData, error) {
+	hostPorts := service.HostPorts(cfg.Ports[service.Name])
+	remote := env.EngineHost != ""
+
+	data := TemplateData{
+		Name:        service.Name,
+		Instance:    service.ContainerName(),
+		Version:     service.ResolveVersion(cfg.Versions[service.Name]),
+		Port:        hostPorts[service.PrimaryPort().Name],
+		DataPath:    cfg.DataPath,
+		Env:         cfg.Env,
+		Ports:       make(map[string]int),
+		ProjectName: config.GetProjectName(),
+		HostIP:      config.DefaultHostIP,
+		Host:        "localhost",
+		Volumes:     cfg.UsesVolumes(remote),
+	}
 
-	file, err := os.Create(composeFile)
-	if err != nil {
-		return fmt.Errorf("failed to create compose file: %w", err)
+	switch cfg.Storage {
+	case "", config.StorageBind, config.StorageVolume:
+	default:
+		return data, fmt.Errorf("invalid storage: %s (expected %s or %s)", cfg.Storage, config.StorageBind, config.StorageVolume)
 	}
-	defer file.Close()
 
-	// Write header
-	fmt.Fprintln(file, "version: '3.8'")
-	fmt.Fprintln(file, "")
-	fmt.Fprintln(file, "services:")
+	if remote {
+		data.HostIP = env.EngineHost
+		data.Host = env.EngineHost
+	}
 
-	// Generate services
-	for _, serviceName := range cfg.Services {
-		service, exists := services.GetService(serviceName)
-		if !exists {
-			return fmt.Errorf("unknown service: %s", serviceName)
-		}
+	if env.Podman {
+		data.MountOptions = ":Z"
+	}
 
-		port := cfg.Ports[serviceName]
-		if port == 0 {
-			port = service.DefaultPort
+	if mount, exists := env.InitMounts[service.Name]; exists {
+		// The scripts stay shared with the host, so they are relabelled
+		// for all containers rather than privately
+		data.InitMount = mount + ":ro"
+		if data.MountOptions != "" {
+			data.InitMount += ",z"
 		}
+	}
 
-		templateData := TemplateData{
-			Port:     port,
-			DataPath: cfg.DataPath,
-			Env:      cfg.Env,
-		}
+	bindAddress := cfg.GetPublishAddress(service.Name, remote)
+	bindIP := net.ParseIP(bindAddress)
+	if bindIP == nil {
+		return data, fmt.Errorf("invalid bind address for %s: %s", service.Name, bindAddress)
+	}
+	if !remote && !bindIP.IsLoopback() && !bindIP.IsUnspecified() {
+		data.HostIP = bindAddress
+	}
+	if bindIP.To4() == nil {
+		bindAddress = "[" + bindAddress + "]"
+	}
 
-		if err := generateServiceTemplate(file, service.Template, templateData); err != nil {
-			return fmt.Errorf("failed to generate template for %s: %w", serviceName, err)
+	for _, spec := range service.Ports {
+		if hostPort, exists := hostPorts[spec.Name]; exists {
+			data.Published = append(data.Published, PortMapping{
+				Name:          spec.Name,
+				HostIP:        bindAddress,
+				HostPort:      hostPort,
+				ContainerPort: spec.ContainerPort,
+			})
 		}
-
-		fmt.Fprintln(file, "")
 	}
 
-	// Add volumes section
-	fmt.Fprintln(file, "volumes:")
 	for _, serviceName := range cfg.Services {
-		service, _ := services.GetService(serviceName)
-		for _, volume := range service.Volumes {
-			fmt.Fprintf(file, "  %s:\n", volume)
+		other, exists := services.GetService(serviceName)
+		if !exists {
+			continue
+		}
+		otherPorts := other.HostPorts(cfg.Ports[serviceName])
+		data.Ports[serviceName] = otherPorts[other.PrimaryPort().Name]
+		for portName, hostPort := range otherPorts {
+			data.Ports[serviceName+"."+portName] = hostPort
 		}
 	}
 
-	return nil
+	resources := cfg.Resources[service.Name]
+	if resources.Memory != "" {
+		memory, err := config.ParseMemory(resources.Memory)
+		if err != nil {
+			return data, fmt.Errorf("invalid memory limit for %s: %w", service.Name, err)
+		}
+		data.Memory = config.FormatMemory(memory)
+		data.HeapSize = config.FormatMemory(memory / 2)
+	}
+	if resources.CPUs < 0 {
+		return data, fmt.Errorf("invalid cpus limit for %s: %v", service.Name, resources.CPUs)
+	}
+	if resources.CPUs > 0 {
+		data.CPUs = strconv.FormatFloat(resources.CPUs, 'f', -1, 64)
+	}
+
+	return data, nil
 }
 
-func generateServiceTemplate(file *os.File, templateName string, data TemplateData) error {
-	templatePath := filepath.Join("templates", templateName)
+// newServiceTemplate parses a service template with the helper functions and
+// shared partials available.
+func newServiceTemplate(name, content string, cfg *config.Config, data TemplateData) (*template.Template, error) {
+	return template.New(name).Funcs(funcMap(cfg, data)).Parse(portsTemplate + resourcesTemplate + content)
+}
 
-	content, err := os.ReadFile(templatePath)
-	if err != nil {
-		return fmt.Errorf("failed to read template %s: %w", templateName, err)
+// loadServiceTemplate returns the project's custom template for a service from
+// .dockenv/templates, or from the deprecated templates/ directory, if there is
+// one, and the embedded template otherwise.
+func loadServiceTemplate(service services.Service) (string, error) {
+	for _, dir := range []string{config.TemplatesDir, config.LegacyTemplatesDir} {
+		customPath := filepath.Join(dir, service.Template)
+		content, err := os.ReadFile(customPath)
+		if err == nil {
+			return string(content), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read custom template %s: %w", customPath, err)
+		}
 	}
 
-	tmpl, err := template.New(templateName).Parse(string(content))
-	if err != nil {
-		return fmt.Errorf("failed to parse template %s: %w", templateName, err)
-	}
+	return GetEmbeddedTemplate(service.Name)
+}
 
-	if err := tmpl.Execute(file, data); err != nil {
-		return fmt.Errorf("failed to execute template %s: %w", templateName, err)
+// LegacyTemplates returns the custom templates of the configured services
+// that are read from the deprecated templates/ directory.
+func LegacyTemplates(cfg *config.Config) []string {
+	var paths []string
+	for _, serviceName := range cfg.Services {
+		service, exists := services.GetService(serviceName)
+		if !exists {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(config.TemplatesDir, service.Template)); err == nil {
+			continue
+		}
+		legacyPath := filepath.Join(config.LegacyTemplatesDir, service.Template)
+		if _, err := os.Stat(legacyPath); err == nil {
+			paths = append(paths, legacyPath)
+		}
 	}
-
-	return nil
+	return paths
 }
 
 func GetEmbeddedTemplate(serviceName string) (string, error) {
 	// Return embedded templates as fallback when template files don't exist
 	templates := map[string]string{
 		"mysql": `  mysql:
-    image: mysql:8.0
-    container_name: dockenv-mysql
+    image: mysql:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
     environment:
       MYSQL_ROOT_PASSWORD: root
       MYSQL_DATABASE: dockenv
       MYSQL_USER: dockenv
       MYSQL_PASSWORD: password
-    ports:
-      - "{{.Port}}:3306"
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/mysql:/var/lib/mysql
+      - {{dataSource "mysql"}}:/var/lib/mysql{{.MountOptions}}
+{{- if .InitMount}}
+      - {{.InitMount}}
+{{- end}}
+{{- if .HeapSize}}
+    command: --innodb-buffer-pool-size={{.HeapSize}}
+{{- end}}
     healthcheck:
       test: ["CMD", "mysqladmin", "ping", "-h", "localhost"]
       timeout: 20s
-      retries: 10`,
+      retries: 10
+{{- template "resources" .}}`,
 
 		"postgres": `  postgres:
-    image: postgres:15
-    container_name: dockenv-postgres
+    image: postgres:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
     environment:
       POSTGRES_DB: dockenv
       POSTGRES_USER: dockenv
       POSTGRES_PASSWORD: password
-    ports:
-      - "{{.Port}}:5432"
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/postgres:/var/lib/postgresql/data
+      - {{dataSource "postgres"}}:/var/lib/postgresql/data{{.MountOptions}}
+{{- if .InitMount}}
+      - {{.InitMount}}
+{{- end}}
     healthcheck:
       test: ["CMD-SHELL", "pg_isready -U dockenv"]
       interval: 30s
       timeout: 10s
-      retries: 5`,
+      retries: 5
+{{- template "resources" .}}`,
 
 		"redis": `  redis:
-    image: redis:7-alpine
-    container_name: dockenv-redis
+    image: redis:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
-    ports:
-      - "{{.Port}}:6379"
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/redis:/data
+      - {{dataSource "redis"}}:/data{{.MountOptions}}
     healthcheck:
       test: ["CMD", "redis-cli", "ping"]
       interval: 30s
       timeout: 10s
-      retries: 5`,
+      retries: 5
+{{- template "resources" .}}`,
 
 		"mongodb": `  mongodb:
-    image: mongo:7
-    container_name: dockenv-mongodb
+    image: mongo:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
     environment:
       MONGO_INITDB_ROOT_USERNAME: dockenv
       MONGO_INITDB_ROOT_PASSWORD: password
       MONGO_INITDB_DATABASE: dockenv
-    ports:
-      - "{{.Port}}:27017"
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/mongodb:/data/db
+      - {{dataSource "mongodb"}}:/data/db{{.MountOptions}}
+{{- if .InitMount}}
+      - {{.InitMount}}
+{{- end}}
     healthcheck:
       test: ["CMD", "mongo", "--eval", "db.adminCommand('ping')"]
       interval: 30s
       timeout: 10s
-      retries: 5`,
+      retries: 5
+{{- template "resources" .}}`,
 
 		"kafka": `  zookeeper:
     image: confluentinc/cp-zookeeper:latest
@@ -173,59 +347,65 @@ func GetEmbeddedTemplate(serviceName string) (string, error) {
       ZOOKEEPER_CLIENT_PORT: 2181
       ZOOKEEPER_TICK_TIME: 2000
     volumes:
-      - {{.DataPath}}/zookeeper:/var/lib/zookeeper/data
+      - {{dataSource "zookeeper"}}:/var/lib/zookeeper/data{{.MountOptions}}
 
   kafka:
-    image: confluentinc/cp-kafka:latest
-    container_name: dockenv-kafka
+    image: confluentinc/cp-kafka:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
     depends_on:
       - zookeeper
     environment:
       KAFKA_BROKER_ID: 1
       KAFKA_ZOOKEEPER_CONNECT: zookeeper:2181
-      KAFKA_ADVERTISED_LISTENERS: PLAINTEXT://localhost:{{.Port}}
+      KAFKA_ADVERTISED_LISTENERS: PLAINTEXT://{{.Host}}:{{.Port}}
       KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR: 1
-    ports:
-      - "{{.Port}}:9092"
+{{- if index .Ports "kafka.jmx"}}
+      KAFKA_JMX_PORT: 9101
+      KAFKA_JMX_HOSTNAME: {{.Host}}
+{{- end}}
+{{- if .HeapSize}}
+      KAFKA_HEAP_OPTS: "-Xms{{.HeapSize}} -Xmx{{.HeapSize}}"
+{{- end}}
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/kafka:/var/lib/kafka/data`,
+      - {{dataSource "kafka"}}:/var/lib/kafka/data{{.MountOptions}}
+{{- template "resources" .}}`,
 
 		"elasticsearch": `  elasticsearch:
-    image: docker.elastic.co/elasticsearch/elasticsearch:8.11.0
-    container_name: dockenv-elasticsearch
+    image: docker.elastic.co/elasticsearch/elasticsearch:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
     environment:
       - discovery.type=single-node
       - xpack.security.enabled=false
-      - "ES_JAVA_OPTS=-Xms512m -Xmx512m"
-    ports:
-      - "{{.Port}}:9200"
+      - "ES_JAVA_OPTS=-Xms{{or .HeapSize "512m"}} -Xmx{{or .HeapSize "512m"}}"
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/elasticsearch:/usr/share/elasticsearch/data
+      - {{dataSource "elasticsearch"}}:/usr/share/elasticsearch/data{{.MountOptions}}
     healthcheck:
       test: ["CMD-SHELL", "curl -f http://localhost:9200/_cluster/health || exit 1"]
       interval: 30s
       timeout: 10s
-      retries: 5`,
+      retries: 5
+{{- template "resources" .}}`,
 
 		"rabbitmq": `  rabbitmq:
-    image: rabbitmq:3-management
-    container_name: dockenv-rabbitmq
+    image: rabbitmq:{{.Version}}
+    container_name: {{.Instance}}
     restart: unless-stopped
     environment:
       RABBITMQ_DEFAULT_USER: dockenv
       RABBITMQ_DEFAULT_PASS: password
-    ports:
-      - "{{.Port}}:5672"
-      - "15672:15672"
+{{- template "ports" .}}
     volumes:
-      - {{.DataPath}}/rabbitmq:/var/lib/rabbitmq
+      - {{dataSource "rabbitmq"}}:/var/lib/rabbitmq{{.MountOptions}}
     healthcheck:
       test: ["CMD", "rabbitmq-diagnostics", "ping"]
       interval: 30s
       timeout: 10s
-      retries: 5`,
+      retries: 5
+{{- template "resources" .}}`,
 	}
 
 	templateStr, exists := templates[serviceName]
@@ -236,91 +416,139 @@ func GetEmbeddedTemplate(serviceName string) (string, error) {
 	return templateStr, nil
 }
 
-func GenerateDockerComposeEmbedded(cfg *config.Config) error {
-	composeFile := config.GetComposePath()
-
-	file, err := os.Create(composeFile)
+func GenerateDockerComposeEmbedded(cfg *config.Config, env Environment) error {
+	content, err := RenderDockerCompose(cfg, env)
 	if err != nil {
-		return fmt.Errorf("failed to create compose file: %w", err)
+		return err
 	}
-	defer file.Close()
-
-	// Write header
-	fmt.Fprintln(file, "version: '3.8'")
-	fmt.Fprintln(file, "")
-	fmt.Fprintln(file, "services:")
 
-	// Generate services using embedded templates
-	for _, serviceName := range cfg.Services {
-		service, exists := services.GetService(serviceName)
-		if !exists {
-			return fmt.Errorf("unknown service: %s", serviceName)
-		}
+	if err := os.WriteFile(config.GetComposePath(), content, 0644); err != nil {
+		return fmt.Errorf("failed to write compose file: %w", err)
+	}
 
-		port := cfg.Ports[serviceName]
-		if port == 0 {
-			port = service.DefaultPort
-		}
+	return nil
+}
 
-		templateData := TemplateData{
-			Port:     port,
-			DataPath: cfg.DataPath,
-			Env:      cfg.Env,
-		}
+// RenderDockerCompose renders the compose file for cfg without touching disk,
+// using the project's custom templates where present and the embedded ones
+// otherwise. Images are pinned as given by env.
+func RenderDockerCompose(cfg *config.Config, env Environment) ([]byte, error) {
+	var buf bytes.Buffer
 
-		templateStr, err := GetEmbeddedTemplate(serviceName)
-		if err != nil {
-			return fmt.Errorf("failed to get embedded template for %s: %w", serviceName, err)
-		}
+	// Write header
+	fmt.Fprintln(&buf, "version: '3.8'")
+	fmt.Fprintln(&buf, "")
+	fmt.Fprintln(&buf, "services:")
 
-		tmpl, err := template.New(serviceName).Parse(templateStr)
+	// Generate services
+	for _, serviceName := range cfg.Services {
+		content, err := RenderService(cfg, env, serviceName)
 		if err != nil {
-			return fmt.Errorf("failed to parse embedded template for %s: %w", serviceName, err)
+			return nil, err
 		}
+		buf.Write(pinImages(content, env.Pins[serviceName]))
 
-		if err := tmpl.Execute(file, templateData); err != nil {
-			return fmt.Errorf("failed to execute embedded template for %s: %w", serviceName, err)
-		}
-
-		fmt.Fprintln(file, "")
+		fmt.Fprintln(&buf, "")
 	}
 
 	// Add volumes section
-	fmt.Fprintln(file, "volumes:")
+	fmt.Fprintln(&buf, "volumes:")
 	volumes := make(map[string]bool)
 	for _, serviceName := range cfg.Services {
 		service, _ := services.GetService(serviceName)
 		for _, volume := range service.Volumes {
 			if !volumes[volume] {
-				fmt.Fprintf(file, "  %s:\n", volume)
+				fmt.Fprintf(&buf, "  %s:\n", volume)
 				volumes[volume] = true
 			}
 		}
 	}
 
-	return nil
+	return buf.Bytes(), nil
+}
+
+// EnvVars returns the variables written to .env: the configured env, with
+// the service hosts pointing at the machine a remote engine runs on. Hosts
+// that were changed from the default are kept.
+func EnvVars(cfg *config.Config, env Environment) map[string]string {
+	envVars := make(map[string]string, len(cfg.Env))
+	for key, value := range cfg.Env {
+		if env.EngineHost != "" && strings.HasSuffix(key, "_HOST") && value == config.DefaultHostIP {
+			value = env.EngineHost
+		}
+		envVars[key] = value
+	}
+	return envVars
 }
 
-func UpdateDockerCompose(cfg *config.Config, servicesToAdd []string, servicesToRemove []string) error {
+// RenderService renders the compose snippet of a single service, without
+// pinning its images.
+func RenderService(cfg *config.Config, env Environment, serviceName string) ([]byte, error) {
+	service, exists := services.GetService(serviceName)
+	if !exists {
+		return nil, fmt.Errorf("unknown service: %s", serviceName)
+	}
+
+	templateData, err := newTemplateData(cfg, env, service)
+	if err != nil {
+		return nil, err
+	}
+
+	templateStr, err := loadServiceTemplate(service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template for %s: %w", serviceName, err)
+	}
+
+	tmpl, err := newServiceTemplate(serviceName, templateStr, cfg, templateData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template for %s: %w", serviceName, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData); err != nil {
+		return nil, fmt.Errorf("failed to execute template for %s: %w", serviceName, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// imageLine matches the image of a compose service, e.g. "    image: redis:7".
+var imageLine = regexp.MustCompile(`(?m)^([ \t]+image:[ \t]*)["']?([^\s"']+)["']?[ \t]*$`)
+
+// pinImages rewrites the image references of a service's compose snippet to
+// their pinned references. Images without a pin are kept.
+func pinImages(content []byte, pins map[string]string) []byte {
+	if len(pins) == 0 {
+		return content
+	}
+	return imageLine.ReplaceAllFunc(content, func(line []byte) []byte {
+		match := imageLine.FindSubmatch(line)
+		pinned, locked := pins[string(match[2])]
+		if !locked {
+			return line
+		}
+		return append(append([]byte{}, match[1]...), pinned...)
+	})
+}
+
+func UpdateDockerCompose(cfg *config.Config, env Environment, servicesToAdd []string, servicesToRemove []string) error {
 	// Add new services
 	for _, serviceName := range servicesToAdd {
 		if !contains(cfg.Services, serviceName) {
 			cfg.Services = append(cfg.Services, serviceName)
 
-			// Add default port if not set
-			if cfg.Ports[serviceName] == 0 {
-				if service, exists := services.GetService(serviceName); exists {
-					cfg.Ports[serviceName] = service.DefaultPort
-				}
-			}
-
-			// Add env vars
+			// Add default ports and env vars
 			if service, exists := services.GetService(serviceName); exists {
+				hostPorts := service.HostPorts(cfg.Ports[serviceName])
+				cfg.Ports[serviceName] = hostPorts
+
 				for key, value := range service.EnvVars {
 					if cfg.Env[key] == "" {
 						cfg.Env[key] = value
 					}
 				}
+				for key, value := range service.PortEnvVars(hostPorts) {
+					cfg.Env[key] = value
+				}
 			}
 		}
 	}
@@ -335,11 +563,14 @@ func UpdateDockerCompose(cfg *config.Config, servicesToAdd []string, servicesToR
 			for key := range service.EnvVars {
 				delete(cfg.Env, key)
 			}
+			for _, spec := range service.Ports {
+				delete(cfg.Env, spec.EnvVar)
+			}
 		}
 	}
 
 	// Regenerate Docker Compose file
-	return GenerateDockerComposeEmbedded(cfg)
+	return GenerateDockerComposeEmbedded(cfg, env)
 }
 
 func contains(slice []string, item string) bool {
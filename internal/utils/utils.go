@@ -2,16 +2,17 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
-	"text/template"
 
 	yaml "gopkg.in/yaml.v3"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/services"
 )
 
 func FileExists(filename string) bool {
@@ -24,12 +25,16 @@ func LoadConfig() (*config.Config, error) {
 
 	if !FileExists(configPath) {
 		return &config.Config{
-			Version:  "1.0",
-			Services: []string{},
-			Ports:    make(map[string]int),
-			Env:      make(map[string]string),
-			Volumes:  make(map[string]string),
-			DataPath: config.GetDataPath(),
+			Version:       "1.0",
+			Services:      []string{},
+			Ports:         make(map[string]config.ServicePorts),
+			Versions:      make(map[string]string),
+			Env:           make(map[string]string),
+			Volumes:       make(map[string]string),
+			Resources:     make(map[string]config.Resources),
+			Secrets:       make(map[string]string),
+			BindAddresses: make(map[string]string),
+			DataPath:      config.GetDataPath(),
 		}, nil
 	}
 
@@ -45,7 +50,7 @@ func LoadConfig() (*config.Config, error) {
 
 	// Ensure maps are initialized
 	if cfg.Ports == nil {
-		cfg.Ports = make(map[string]int)
+		cfg.Ports = make(map[string]config.ServicePorts)
 	}
 	if cfg.Env == nil {
 		cfg.Env = make(map[string]string)
@@ -53,10 +58,33 @@ func LoadConfig() (*config.Config, error) {
 	if cfg.Volumes == nil {
 		cfg.Volumes = make(map[string]string)
 	}
+	if cfg.Resources == nil {
+		cfg.Resources = make(map[string]config.Resources)
+	}
+	if cfg.Secrets == nil {
+		cfg.Secrets = make(map[string]string)
+	}
+	if cfg.BindAddresses == nil {
+		cfg.BindAddresses = make(map[string]string)
+	}
+	if cfg.Versions == nil {
+		cfg.Versions = make(map[string]string)
+	}
 	if cfg.DataPath == "" {
 		cfg.DataPath = config.GetDataPath()
 	}
 
+	// Older configurations store a single port per service
+	for serviceName, ports := range cfg.Ports {
+		port, legacy := ports[config.LegacyPortName]
+		service, exists := services.GetService(serviceName)
+		if !legacy || !exists {
+			continue
+		}
+		delete(ports, config.LegacyPortName)
+		ports[service.PrimaryPort().Name] = port
+	}
+
 	return &cfg, nil
 }
 
@@ -66,9 +94,9 @@ func SaveConfig(cfg *config.Config) error {
 	}
 
 	configPath := config.GetConfigPath()
-	data, err := yaml.Marshal(cfg)
+	data, err := MarshalConfig(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(configPath, data, 0644); err != nil {
@@ -78,25 +106,13 @@ func SaveConfig(cfg *config.Config) error {
 	return nil
 }
 
-func GenerateFromTemplate(templateName string, data interface{}, outputPath string) error {
-	templatePath := filepath.Join("templates", templateName)
-
-	tmpl, err := template.ParseFiles(templatePath)
-	if err != nil {
-		return fmt.Errorf("failed to parse template %s: %w", templateName, err)
-	}
-
-	outputFile, err := os.Create(outputPath)
+// MarshalConfig returns the YAML that SaveConfig would write for cfg.
+func MarshalConfig(cfg *config.Config) ([]byte, error) {
+	data, err := yaml.Marshal(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create output file %s: %w", outputPath, err)
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
-	defer outputFile.Close()
-
-	if err := tmpl.Execute(outputFile, data); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
-	}
-
-	return nil
+	return data, nil
 }
 
 func CopyFile(src, dst string) error {
@@ -116,9 +132,11 @@ func CopyFile(src, dst string) error {
 	return err
 }
 
-func CreateEnvFile(envVars map[string]string) error {
+// CreateEnvFile merges envVars into .env, and into .env.example if the
+// project has one, dropping the stale keys dockenv no longer sets.
+func CreateEnvFile(envVars map[string]string, stale []string) error {
 	envPath := config.EnvFileName
-	err := updateEnvFile(envPath, envVars, true)
+	err := updateEnvFile(envPath, envVars, stale, true)
 	if err != nil {
 		return err
 	}
@@ -126,30 +144,67 @@ func CreateEnvFile(envVars map[string]string) error {
 	// Also update .env.example if it exists
 	examplePath := ".env.example"
 	if FileExists(examplePath) {
-		// For .env.example, we'll use placeholder values
-		exampleVars := make(map[string]string)
-		for key, value := range envVars {
-			// Create example values (remove sensitive data)
-			switch {
-			case strings.Contains(strings.ToLower(key), "password"):
-				exampleVars[key] = "your_password_here"
-			case strings.Contains(strings.ToLower(key), "secret"):
-				exampleVars[key] = "your_secret_here"
-			case strings.Contains(strings.ToLower(key), "key") && !strings.Contains(strings.ToLower(key), "port"):
-				exampleVars[key] = "your_key_here"
-			case strings.Contains(strings.ToLower(key), "token"):
-				exampleVars[key] = "your_token_here"
-			default:
-				exampleVars[key] = value
-			}
+		return updateEnvFile(examplePath, ExampleEnvVars(envVars), stale, false)
+	}
+
+	return nil
+}
+
+// StaleEnvKeys returns the keys of the previous configuration's env that the
+// current one no longer sets, e.g. those of removed services, sorted.
+func StaleEnvKeys(previous, current *config.Config) []string {
+	if previous == nil {
+		return nil
+	}
+	var stale []string
+	for key := range previous.Env {
+		if _, exists := current.Env[key]; !exists {
+			stale = append(stale, key)
 		}
-		return updateEnvFile(examplePath, exampleVars, false)
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// ExampleEnvVars returns a copy of envVars with sensitive values replaced by
+// placeholders, as written to .env.example.
+func ExampleEnvVars(envVars map[string]string) map[string]string {
+	exampleVars := make(map[string]string)
+	for key, value := range envVars {
+		// Create example values (remove sensitive data)
+		switch {
+		case strings.Contains(strings.ToLower(key), "password"):
+			exampleVars[key] = "your_password_here"
+		case strings.Contains(strings.ToLower(key), "secret"):
+			exampleVars[key] = "your_secret_here"
+		case strings.Contains(strings.ToLower(key), "key") && !strings.Contains(strings.ToLower(key), "port"):
+			exampleVars[key] = "your_key_here"
+		case strings.Contains(strings.ToLower(key), "token"):
+			exampleVars[key] = "your_token_here"
+		default:
+			exampleVars[key] = value
+		}
+	}
+	return exampleVars
+}
+
+func updateEnvFile(filePath string, newVars map[string]string, stale []string, addHeader bool) error {
+	content, err := RenderEnvFile(filePath, newVars, stale)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to create env file: %w", err)
 	}
 
 	return nil
 }
 
-func updateEnvFile(filePath string, newVars map[string]string, addHeader bool) error {
+// RenderEnvFile returns the content filePath would have after merging newVars
+// into it, preserving existing keys, their order and user comments. Stale
+// keys are dropped unless newVars sets them.
+func RenderEnvFile(filePath string, newVars map[string]string, stale []string) ([]byte, error) {
 	// Parse existing env file if it exists
 	existingVars := make(map[string]string)
 	var comments []string
@@ -158,7 +213,7 @@ func updateEnvFile(filePath string, newVars map[string]string, addHeader bool) e
 	if FileExists(filePath) {
 		file, err := os.Open(filePath)
 		if err != nil {
-			return fmt.Errorf("failed to open existing env file: %w", err)
+			return nil, fmt.Errorf("failed to open existing env file: %w", err)
 		}
 		defer file.Close()
 
@@ -191,44 +246,46 @@ func updateEnvFile(filePath string, newVars map[string]string, addHeader bool) e
 		}
 
 		if err := scanner.Err(); err != nil {
-			return fmt.Errorf("failed to read env file: %w", err)
+			return nil, fmt.Errorf("failed to read env file: %w", err)
 		}
 	}
 
-	// Merge new variables with existing ones
-	for key, value := range newVars {
-		existingVars[key] = value
+	// Merge new variables with existing ones, sorted so the output is stable
+	newKeys := make([]string, 0, len(newVars))
+	for key := range newVars {
+		newKeys = append(newKeys, key)
+	}
+	sort.Strings(newKeys)
+
+	for _, key := range stale {
+		delete(existingVars, key)
+	}
+	for _, key := range newKeys {
+		existingVars[key] = newVars[key]
 		// Add new keys to order if they don't exist
 		if !Contains(order, key) {
 			order = append(order, key)
 		}
 	}
 
-	// Write the updated env file
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create env file: %w", err)
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
+	var buf bytes.Buffer
 
 	// Write preserved comments first
 	for _, comment := range comments {
-		fmt.Fprintln(writer, comment)
+		fmt.Fprintln(&buf, comment)
 	}
 	if len(comments) > 0 {
-		fmt.Fprintln(writer, "")
+		fmt.Fprintln(&buf, "")
 	}
 
 	// Write environment variables in the preserved order
 	for _, key := range order {
 		if value, exists := existingVars[key]; exists {
-			fmt.Fprintf(writer, "%s=%s\n", key, value)
+			fmt.Fprintf(&buf, "%s=%s\n", key, value)
 		}
 	}
 
-	return writer.Flush()
+	return buf.Bytes(), nil
 }
 
 func DetectProjectType() string {
@@ -1,15 +1,38 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"syscall"
 
 	"github.com/mohammed-bageri/dockenv/cmd"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
+		var exitErr *cmd.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
+
+// exitCode follows the shell conventions of timeout(1) and of processes
+// ended by a signal, so scripts can tell them apart from failed commands.
+func exitCode(err error) int {
+	var interrupted *runner.InterruptedError
+	switch {
+	case errors.As(err, new(*runner.TimeoutError)):
+		return 124
+	case errors.As(err, &interrupted):
+		if sig, ok := interrupted.Signal.(syscall.Signal); ok {
+			return 128 + int(sig)
+		}
+		return 130
+	}
+	return 1
+}
@@ -4,8 +4,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/backup"
+	"github.com/mohammed-bageri/dockenv/internal/services"
 )
 
 const binaryName = "dockenv"
@@ -204,3 +209,453 @@ func TestDockenvAdd(t *testing.T) {
 		t.Errorf("Add output should indicate mysql was added, got: %s", outputStr)
 	}
 }
+
+func TestDockenvDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	// Change to temp directory
+	err = os.Chdir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	// Record container commands instead of running them, so no Docker is
+	// needed; files are still written
+	env := append(os.Environ(),
+		"DOCKENV_CONFIG="+filepath.Join(tempDir, "dockenv.yaml"),
+		"DOCKENV_DATA="+filepath.Join(tempDir, "data"),
+		"DOCKENV_RUNTIME=record",
+	)
+
+	initCmd := exec.Command(filepath.Join(oldDir, binaryName), "init", "--services", "mysql,redis")
+	initCmd.Env = env
+	if output, err := initCmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to initialize dockenv: %v\nOutput: %s", err, output)
+	}
+
+	upCmd := exec.Command(filepath.Join(oldDir, binaryName), "up", "mysql")
+	upCmd.Env = env
+	output, err := upCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run dockenv up: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "[dry-run] docker compose -f ./docker-compose.dockenv.yaml up -d mysql") {
+		t.Errorf("Up output should record the compose command, got: %s", output)
+	}
+
+	downCmd := exec.Command(filepath.Join(oldDir, binaryName), "down", "--volumes", "--rmi")
+	downCmd.Env = env
+	output, err = downCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run dockenv down: %v\nOutput: %s", err, output)
+	}
+	for _, expected := range []string{
+		"[dry-run] docker compose -f ./docker-compose.dockenv.yaml down -v",
+		"[dry-run] docker compose -f ./docker-compose.dockenv.yaml down --rmi all",
+	} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Down output should contain %q, got: %s", expected, output)
+		}
+	}
+}
+
+func TestDockenvDryRunWritesNothing(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	// Change to temp directory
+	err = os.Chdir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	cmd := exec.Command(filepath.Join(oldDir, binaryName), "--dry-run", "init", "--services", "postgres")
+	cmd.Env = append(os.Environ(),
+		"DOCKENV_CONFIG="+filepath.Join(tempDir, "dockenv.yaml"),
+		"DOCKENV_DATA="+filepath.Join(tempDir, "data"),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run dockenv --dry-run init: %v\nOutput: %s", err, output)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("Dry run should not write files, found %d entries", len(entries))
+	}
+}
+
+func TestDockenvDryRunWithBrokenConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	configPath := filepath.Join(tempDir, "dockenv.yaml")
+	if err := os.WriteFile(configPath, []byte("services: [mysql\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("docker-compose.dockenv.yaml", []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No engine is on the PATH, so the command only succeeds if it records
+	cmd := exec.Command(filepath.Join(oldDir, binaryName), "down")
+	cmd.Env = append(os.Environ(),
+		"DOCKENV_CONFIG="+configPath,
+		"DOCKENV_RUNTIME=record",
+		"PATH="+t.TempDir(),
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to run dockenv down: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "[dry-run] docker compose -f ./docker-compose.dockenv.yaml down\n") {
+		t.Errorf("DOCKENV_RUNTIME=record should apply without a valid config, got: %s", output)
+	}
+}
+
+func TestDockenvSeedOnlyFreshData(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	env := append(os.Environ(),
+		"DOCKENV_CONFIG="+filepath.Join(tempDir, "dockenv.yaml"),
+		"DOCKENV_DATA="+filepath.Join(tempDir, "data"),
+		"DOCKENV_RUNTIME=record",
+	)
+	run := func(args ...string) string {
+		cmd := exec.Command(filepath.Join(oldDir, binaryName), args...)
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to run dockenv %v: %v\nOutput: %s", args, err, output)
+		}
+		return string(output)
+	}
+
+	run("init", "--services", "redis")
+	if err := os.MkdirAll(filepath.Join(".dockenv", "init", "redis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(".dockenv", "init", "redis", "cache.redis"), []byte("SET a 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	seeding := "[dry-run] docker exec -i dockenv-redis sh -c 'exec redis-cli' sh"
+	marking := "[dry-run] docker exec dockenv-redis sh -c 'touch \"$1\"' sh /data/.dockenv-seeded"
+
+	// Starting without data creates it, so the scripts run
+	output := run("up", "redis")
+	if !strings.Contains(output, seeding) || !strings.Contains(output, marking) {
+		t.Errorf("Up without data should seed Redis, got: %s", output)
+	}
+
+	// Existing data is only marked as seeded
+	if err := os.MkdirAll(filepath.Join(tempDir, "data", "redis"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	output = run("up", "redis")
+	if strings.Contains(output, seeding) {
+		t.Errorf("Up with existing data should not run the init scripts, got: %s", output)
+	}
+	if !strings.Contains(output, marking) || !strings.Contains(output, "dockenv seed redis") {
+		t.Errorf("Up with existing data should mark it and point to 'dockenv seed', got: %s", output)
+	}
+
+	// 'dockenv seed' runs them on demand
+	if output = run("seed", "redis"); !strings.Contains(output, seeding) {
+		t.Errorf("Seed should run the init scripts, got: %s", output)
+	}
+}
+
+func TestDockenvReset(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	configPath := filepath.Join(tempDir, "dockenv.yaml")
+	dataPath := filepath.Join(tempDir, "data")
+	env := append(os.Environ(),
+		"DOCKENV_CONFIG="+configPath,
+		"DOCKENV_DATA="+dataPath,
+		"DOCKENV_RUNTIME=record",
+		"COMPOSE_PROJECT_NAME=shop",
+	)
+	run := func(stdin string, args ...string) string {
+		cmd := exec.Command(filepath.Join(oldDir, binaryName), args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to run dockenv %v: %v\nOutput: %s", args, err, output)
+		}
+		return string(output)
+	}
+
+	run("", "init", "--services", "kafka,redis")
+
+	// Kafka keeps its data in two directories, those of Kafka and ZooKeeper
+	removeContainers := "[dry-run] docker rm -f dockenv-kafka dockenv-zookeeper\n"
+	removeDirs := "[dry-run] docker run --rm --mount type=bind,source=" + dataPath + ",target=/data debian:stable-slim sh -c 'set -e\n" +
+		"for dir in \"$@\"; do\n" +
+		"  rm -rf \"/data/$dir\"\n" +
+		"done' sh kafka zookeeper\n"
+	start := "[dry-run] docker compose -f ./docker-compose.dockenv.yaml up -d kafka zookeeper\n"
+
+	// Without --force the reset asks first
+	output := run("n\n", "reset", "kafka")
+	if !strings.Contains(output, "Operation cancelled.") {
+		t.Errorf("Reset should ask for confirmation, got: %s", output)
+	}
+	if strings.Contains(output, "[dry-run]") {
+		t.Errorf("A cancelled reset should run nothing, got: %s", output)
+	}
+
+	output = run("y\n", "reset", "kafka")
+	for _, expected := range []string{removeContainers, removeDirs, start} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Confirmed reset should run %q, got: %s", expected, output)
+		}
+	}
+
+	output = run("", "reset", "--force", "kafka")
+	for _, expected := range []string{removeContainers, removeDirs, start} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Forced reset should run %q, got: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Are you sure") || strings.Contains(output, "redis") {
+		t.Errorf("Forced reset should not ask nor touch other services, got: %s", output)
+	}
+
+	// With volumes, the service's volumes are removed instead
+	config, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, append(config, "storage: volume\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output = run("", "reset", "-f", "kafka")
+	for _, expected := range []string{
+		removeContainers,
+		"[dry-run] docker volume rm shop_kafka_data\n",
+		"[dry-run] docker volume rm shop_zookeeper_data\n",
+		start,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Reset with volumes should run %q, got: %s", expected, output)
+		}
+	}
+	if strings.Contains(output, "rm -rf") {
+		t.Errorf("Reset with volumes should not delete data directories, got: %s", output)
+	}
+}
+
+func TestDockenvAnonymizeSalt(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	dump := filepath.Join(oldDir, "..", "testdata", "dumps", "mysql.sql")
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	configPath := filepath.Join(tempDir, "dockenv.yaml")
+	env := append(os.Environ(),
+		"DOCKENV_CONFIG="+configPath,
+		"DOCKENV_DATA="+filepath.Join(tempDir, "data"),
+	)
+	run := func(extraEnv []string, args ...string) (string, string) {
+		cmd := exec.Command(filepath.Join(oldDir, binaryName), args...)
+		cmd.Env = append(env, extraEnv...)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Failed to run dockenv %v: %v\nOutput: %s", args, err, stderr.String())
+		}
+		return string(output), stderr.String()
+	}
+	record := []string{"DOCKENV_RUNTIME=record"}
+
+	run(record, "init", "--services", "mysql")
+	initial, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := "anonymize:\n  rules:\n    users.email: email\n    orders.customer_email: email\n"
+	if err := os.WriteFile(configPath, append(initial, rules...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configured, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Previews use a random salt without saving it
+	first, messages := run(nil, "anonymize", "mysql", dump)
+	if !strings.Contains(messages, "using a random one") {
+		t.Errorf("Anonymize without a salt should warn about a random one, got: %s", messages)
+	}
+	second, _ := run(nil, "anonymize", "mysql", dump)
+	if first == second || strings.Contains(first, "jane@acme.io") {
+		t.Errorf("Previews without a salt should anonymize with different salts")
+	}
+
+	// Neither do dry runs of an import
+	_, messages = run(record, "import-data", "mysql", dump, "-f")
+	if !strings.Contains(messages, "using a random one for this dry run") {
+		t.Errorf("A dry-run import without a salt should use a random one, got: %s", messages)
+	}
+
+	saved, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != string(configured) {
+		t.Errorf("Config should be unchanged, got:\n%s", saved)
+	}
+
+	// A configured salt gives the same fakes on every run
+	if err := os.WriteFile(configPath, append(configured, "  salt: pepper\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first, messages = run(nil, "anonymize", "mysql", dump)
+	second, _ = run(nil, "anonymize", "mysql", dump)
+	if strings.Contains(messages, "random") {
+		t.Errorf("Anonymize should use the configured salt, got: %s", messages)
+	}
+	if first != second || strings.Contains(first, "jane@acme.io") {
+		t.Errorf("Runs with a salt should give the same anonymized dump")
+	}
+}
+
+func TestDockenvRestoreStartsAfterTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake engine is a shell script")
+	}
+
+	tempDir := t.TempDir()
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	defer os.Chdir(oldDir)
+
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	// A fake engine that logs its calls and hangs while copying
+	binDir := filepath.Join(tempDir, "bin")
+	logPath := filepath.Join(tempDir, "docker.log")
+	script := "#!/bin/sh\n" +
+		"echo \"$*\" >> " + logPath + "\n" +
+		"case \"$1 $2\" in\n" +
+		"  \"compose version\") echo 'Docker Compose version v2.29.0' ;;\n" +
+		"  cp\\ *) exec sleep 30 ;;\n" +
+		"esac\n"
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(tempDir, "dockenv.yaml")
+	env := append(os.Environ(),
+		"DOCKENV_CONFIG="+configPath,
+		"DOCKENV_DATA="+filepath.Join(tempDir, "data"),
+		"COMPOSE_PROJECT_NAME=shop",
+	)
+	initCmd := exec.Command(filepath.Join(oldDir, binaryName), "init", "--services", "redis")
+	initCmd.Env = append(env, "DOCKENV_RUNTIME=record")
+	if output, err := initCmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to run dockenv init: %v\nOutput: %s", err, output)
+	}
+
+	// A backup of Redis, which is restored by copying its data file
+	dumpDir := filepath.Join(tempDir, "dump")
+	if err := os.MkdirAll(dumpDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dumpDir, "redis.rdb"), []byte("REDIS0011"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	redis, _ := services.GetService("redis")
+	manifest := &backup.Manifest{
+		Version:  backup.ManifestVersion,
+		Created:  time.Now(),
+		Project:  "shop",
+		Services: []backup.Dump{{Service: "redis", Version: redis.ResolveVersion(""), Tool: "BGSAVE", File: "redis.rdb"}},
+	}
+	archivePath := filepath.Join(tempDir, "backup.tar.gz")
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backup.Write(archive, manifest, dumpDir); err != nil {
+		t.Fatal(err)
+	}
+	archive.Close()
+
+	cmd := exec.Command(filepath.Join(oldDir, binaryName), "restore", archivePath, "-f", "--timeout", "2s")
+	cmd.Env = append(env,
+		"PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"DOCKER_HOST=tcp://127.0.0.1:1",
+	)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Restore should fail when the copy times out, got: %s", output)
+	}
+
+	// The service is started again although the command's context has ended
+	calls, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := "compose -f ./docker-compose.dockenv.yaml start redis\n"
+	if !strings.Contains(string(calls), start) {
+		t.Errorf("Restore should start redis after the timeout, got calls:\n%s\nOutput: %s", calls, output)
+	}
+}
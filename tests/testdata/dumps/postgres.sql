@@ -0,0 +1,61 @@
+--
+-- PostgreSQL database dump
+--
+
+-- Dumped from database version 16.2
+-- Dumped by pg_dump version 16.2
+
+SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  NEW.updated_at := now(); -- keeps 'quotes' and ; inside
+  RETURN NEW;
+END;
+$$;
+
+SET default_tablespace = '';
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    email text NOT NULL,
+    full_name character varying(100),
+    phone text,
+    exclude boolean DEFAULT false,
+    CONSTRAINT users_email_check CHECK ((email <> ''::text))
+);
+
+CREATE TABLE public.orders (
+    id integer NOT NULL,
+    customer_email text,
+    note text
+);
+
+--
+-- Data for Name: users; Type: TABLE DATA; Schema: public; Owner: dockenv
+--
+
+COPY public.users (id, email, full_name, phone, exclude) FROM stdin;
+1	jane@acme.io	Jane O'Connor	+44 20 7946 0958	f
+2	bob@acme.io	Bob\tSmith	\N	t
+3	jane@acme.io	Jane Again	555	f
+\.
+
+
+INSERT INTO public.orders VALUES (1, 'jane@acme.io', 'Leave at the door; ring twice');
+INSERT INTO public.orders (id, note, customer_email) VALUES (2, E'It\'s fragile', 'bob@acme.io'), (3, NULL, NULL);
+
+\connect shop
+
+COPY public.orders (id, customer_email, note) FROM stdin;
+4	jane@acme.io	\N
+\.
+
+--
+-- PostgreSQL database dump complete
+--
@@ -0,0 +1,289 @@
+package unit
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mohammed-bageri/dockenv/internal/anonymize"
+)
+
+var testRules = map[string]string{
+	"users.email":           "email",
+	"users.full_name":       "name",
+	"users.phone":           "phone",
+	"users.password":        "hash",
+	"users.bio":             "null",
+	"orders.customer_email": "email",
+}
+
+func anonymizeString(t *testing.T, dialect anonymize.Dialect, configured map[string]string, dump string) (string, map[string]int) {
+	t.Helper()
+	rules, err := anonymize.ParseRules(configured)
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	reader := anonymize.NewReader(strings.NewReader(dump), dialect, rules, "salt")
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("anonymize error = %v", err)
+	}
+	matches := make(map[string]int)
+	for rule, count := range reader.Matches() {
+		matches[rule.String()] = count
+	}
+	return string(out), matches
+}
+
+func TestAnonymizeDumps(t *testing.T) {
+	tests := []struct {
+		file     string
+		dialect  anonymize.Dialect
+		changed  int
+		expected map[string]int
+	}{
+		{
+			file:    "mysql.sql",
+			dialect: anonymize.MySQL,
+			changed: 2,
+			expected: map[string]int{
+				"users.email": 3, "users.full_name": 3, "users.phone": 2, "users.password": 3,
+				"users.bio": 2, "orders.customer_email": 2,
+			},
+		},
+		{
+			file:    "postgres.sql",
+			dialect: anonymize.Postgres,
+			changed: 6,
+			expected: map[string]int{
+				"users.email": 3, "users.full_name": 3, "users.phone": 2, "orders.customer_email": 3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			dump, err := os.ReadFile(filepath.Join("..", "testdata", "dumps", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, matches := anonymizeString(t, tt.dialect, testRules, string(dump))
+
+			for _, personal := range []string{"jane@acme.io", "bob@acme.io", "Jane O", "Bob", "+44 20", "$2y$10$"} {
+				if strings.Contains(out, personal) {
+					t.Errorf("output still contains %q", personal)
+				}
+			}
+			for rule, count := range tt.expected {
+				if matches[rule] != count {
+					t.Errorf("rule %s matched %d values, want %d", rule, matches[rule], count)
+				}
+			}
+
+			// Only the lines with data change
+			in, got := strings.Split(string(dump), "\n"), strings.Split(out, "\n")
+			if len(in) != len(got) {
+				t.Fatalf("output has %d lines, want %d", len(got), len(in))
+			}
+			changed := 0
+			for i := range in {
+				if in[i] != got[i] {
+					changed++
+				}
+			}
+			if changed != tt.changed {
+				t.Errorf("%d lines changed, want %d", changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestAnonymizeStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  anonymize.Dialect
+		dump     string
+		expected string
+	}{
+		{
+			name:     "column list",
+			dialect:  anonymize.MySQL,
+			dump:     "INSERT INTO `users` (`id`, `bio`) VALUES (1, 'secret'), (2, NULL);\n",
+			expected: "INSERT INTO `users` (`id`, `bio`) VALUES (1, NULL), (2, NULL);\n",
+		},
+		{
+			name:     "schema and case",
+			dialect:  anonymize.Postgres,
+			dump:     "INSERT INTO public.USERS (ID, Bio) VALUES (1, 'secret'::text);\n",
+			expected: "INSERT INTO public.USERS (ID, Bio) VALUES (1, NULL);\n",
+		},
+		{
+			name:     "other tables",
+			dialect:  anonymize.Postgres,
+			dump:     "INSERT INTO public.accounts (id, bio) VALUES (1, 'kept');\n",
+			expected: "INSERT INTO public.accounts (id, bio) VALUES (1, 'kept');\n",
+		},
+		{
+			name:     "copy of other tables",
+			dialect:  anonymize.Postgres,
+			dump:     "COPY public.accounts (id, bio) FROM stdin;\n1\tkept;\n\\.\nINSERT INTO users (bio) VALUES ('secret');\n",
+			expected: "COPY public.accounts (id, bio) FROM stdin;\n1\tkept;\n\\.\nINSERT INTO users (bio) VALUES (NULL);\n",
+		},
+		{
+			name:     "copy to stdout",
+			dialect:  anonymize.Postgres,
+			dump:     "COPY users (bio) TO stdout;\nINSERT INTO users (bio) VALUES ('secret');\n",
+			expected: "COPY users (bio) TO stdout;\nINSERT INTO users (bio) VALUES (NULL);\n",
+		},
+		{
+			name:     "without trailing line break",
+			dialect:  anonymize.MySQL,
+			dump:     "INSERT INTO users (bio) VALUES ('secret')",
+			expected: "INSERT INTO users (bio) VALUES (NULL)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := anonymizeString(t, tt.dialect, map[string]string{"users.bio": "null"}, tt.dump)
+			if out != tt.expected {
+				t.Errorf("anonymized\n%s\nwant\n%s", out, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAnonymizeLongValues(t *testing.T) {
+	// A value spanning many reads, with what ends statements inside it
+	long := strings.Repeat("a line; with 'quotes' \\' and -- no comment\\n\n", 4000)
+	dump := "INSERT INTO users (id, bio, email) VALUES (1, '" + long + "', 'jane@acme.io');\n" +
+		"INSERT INTO users (id, bio, email) VALUES (2, NULL, 'bob@acme.io');\n"
+
+	rules, err := anonymize.ParseRules(map[string]string{"users.email": "email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := anonymize.NewReader(iotest.HalfReader(strings.NewReader(dump)), anonymize.MySQL, rules, "")
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("anonymize error = %v", err)
+	}
+
+	if !bytes.Contains(out, []byte(long)) {
+		t.Error("long value was not kept")
+	}
+	if bytes.Contains(out, []byte("acme.io")) {
+		t.Error("emails were not replaced")
+	}
+	if count := reader.Matches()[rules[0]]; count != 2 {
+		t.Errorf("rule matched %d values, want 2", count)
+	}
+}
+
+func TestAnonymizeUnknownColumns(t *testing.T) {
+	rules, _ := anonymize.ParseRules(map[string]string{"users.email": "email"})
+	dump := "INSERT INTO `users` VALUES (1,'jane@acme.io');\n"
+	reader := anonymize.NewReader(strings.NewReader(dump), anonymize.MySQL, rules, "")
+	out, err := io.ReadAll(reader)
+	if err == nil {
+		t.Fatal("inserting into a table with rules before creating it should fail")
+	}
+	if bytes.Contains(out, []byte("jane@acme.io")) {
+		t.Error("the statement that failed was written")
+	}
+}
+
+func TestAnonymizeDeterministic(t *testing.T) {
+	mysql, _ := anonymizeString(t, anonymize.MySQL, testRules,
+		"INSERT INTO users (email) VALUES ('jane@acme.io');\nINSERT INTO orders (customer_email) VALUES ('jane@acme.io');\n")
+	postgres, _ := anonymizeString(t, anonymize.Postgres, testRules,
+		"COPY public.users (email) FROM stdin;\njane@acme.io\n\\.\n")
+
+	fake := regexp.MustCompile(`[a-z]+\.[a-z]+\.[0-9a-f]{10}@example\.com`)
+	fakes := fake.FindAllString(mysql+postgres, -1)
+	if len(fakes) != 3 || fakes[0] != fakes[1] || fakes[0] != fakes[2] {
+		t.Errorf("fakes of one email = %v, want three equal ones", fakes)
+	}
+
+	rules, _ := anonymize.ParseRules(testRules)
+	other, _ := io.ReadAll(anonymize.NewReader(strings.NewReader("INSERT INTO users (email) VALUES ('jane@acme.io');\n"),
+		anonymize.MySQL, rules, "other salt"))
+	if strings.Contains(string(other), fakes[0]) {
+		t.Error("a different salt should give different fakes")
+	}
+}
+
+func TestAnonymizeFakes(t *testing.T) {
+	tests := []struct {
+		fake    string
+		value   string
+		pattern string
+	}{
+		{fake: "email", value: "jane@acme.io", pattern: `^'[a-z]+\.[a-z]+\.[0-9a-f]{10}@example\.com'$`},
+		{fake: "name", value: "Jane O'Connor", pattern: `^'[A-Z][a-z]+ [A-Z][a-z]+'$`},
+		{fake: "phone", value: "+44 20 7946 0958", pattern: `^'\+1-555-[0-9]{3}-[0-9]{4}'$`},
+		{fake: "hash", value: "short", pattern: `^'[0-9a-f]{5}'$`},
+		{fake: "hash", value: strings.Repeat("x", 100), pattern: `^'[0-9a-f]{64}'$`},
+		{fake: "null", value: "anything", pattern: `^NULL$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fake+"/"+tt.value, func(t *testing.T) {
+			quoted := strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(tt.value)
+			out, _ := anonymizeString(t, anonymize.MySQL, map[string]string{"t.c": tt.fake},
+				"INSERT INTO t (c) VALUES ('"+quoted+"');\n")
+			value := strings.TrimSuffix(strings.TrimPrefix(out, "INSERT INTO t (c) VALUES ("), ");\n")
+			if !regexp.MustCompile(tt.pattern).MatchString(value) {
+				t.Errorf("fake %s = %s, want it to match %s", tt.fake, value, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestParseAnonymizeRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured map[string]string
+		expected   []anonymize.Rule
+		wantErr    bool
+	}{
+		{
+			name:       "sorted",
+			configured: map[string]string{"users.phone": "phone", "public.users.email": "email"},
+			expected: []anonymize.Rule{
+				{Table: "public.users", Column: "email", Fake: "email"},
+				{Table: "users", Column: "phone", Fake: "phone"},
+			},
+		},
+		{
+			name:       "unquoted null",
+			configured: map[string]string{"users.ssn": ""},
+			expected:   []anonymize.Rule{{Table: "users", Column: "ssn", Fake: anonymize.Null}},
+		},
+		{name: "no table", configured: map[string]string{"email": "email"}, wantErr: true},
+		{name: "no column", configured: map[string]string{"users.": "email"}, wantErr: true},
+		{name: "unknown fake", configured: map[string]string{"users.email": "scramble"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := anonymize.ParseRules(tt.configured)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(rules) != len(tt.expected) {
+				t.Fatalf("ParseRules() = %v, want %v", rules, tt.expected)
+			}
+			for i := range rules {
+				if rules[i] != tt.expected[i] {
+					t.Errorf("ParseRules()[%d] = %v, want %v", i, rules[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
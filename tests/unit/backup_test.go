@@ -0,0 +1,180 @@
+package unit
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/backup"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	dumpDir := t.TempDir()
+	dumps := map[string]string{
+		"postgres/dump.sql": "CREATE TABLE users ();\n",
+		"redis/dump.rdb":    "REDIS0011",
+	}
+	for name, content := range dumps {
+		file := filepath.Join(dumpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest := &backup.Manifest{
+		Version: backup.ManifestVersion,
+		Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Project: "shop",
+		Services: []backup.Dump{
+			{Service: "postgres", Version: "15", Tool: "pg_dumpall", File: "postgres/dump.sql"},
+			{Service: "redis", Version: "7-alpine", Tool: "BGSAVE", File: "redis/dump.rdb"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := backup.Write(&buf, manifest, dumpDir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data := buf.Bytes()
+
+	got, err := backup.Read(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, manifest) {
+		t.Errorf("Read() = %+v, want %+v", got, manifest)
+	}
+
+	extractDir := t.TempDir()
+	if _, err := backup.Read(bytes.NewReader(data), extractDir); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	for name, expected := range dumps {
+		content, err := os.ReadFile(filepath.Join(extractDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("dump %s not extracted: %v", name, err)
+		}
+		if string(content) != expected {
+			t.Errorf("dump %s = %q, want %q", name, content, expected)
+		}
+	}
+
+	if dump, found := got.Find("redis"); !found || dump.Version != "7-alpine" {
+		t.Errorf("Find(redis) = %+v, %v", dump, found)
+	}
+	if _, found := got.Find("mysql"); found {
+		t.Errorf("Find(mysql) should not find a dump")
+	}
+
+	if _, err := backup.Read(strings.NewReader("not a backup"), ""); err == nil {
+		t.Errorf("Read() expected error for a file that is not a backup")
+	}
+}
+
+// writeBackupArchive writes a backup archive of the given entries, in order.
+func writeBackupArchive(t *testing.T, entries [][2]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: entry[0], Mode: 0644, Size: int64(len(entry[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBackupReadRejectsInvalidDumps(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries [][2]string
+	}{
+		{
+			name: "escaping path",
+			entries: [][2]string{
+				{backup.ManifestName, `{"version":1,"services":[{"service":"postgres","file":"../outside.sql"}]}`},
+				{"../outside.sql", "DROP TABLE users;"},
+			},
+		},
+		{
+			name: "absolute path",
+			entries: [][2]string{
+				{backup.ManifestName, `{"version":1,"services":[{"service":"postgres","file":"/tmp/dump.sql"}]}`},
+				{"/tmp/dump.sql", "DROP TABLE users;"},
+			},
+		},
+		{
+			name: "missing dump",
+			entries: [][2]string{
+				{backup.ManifestName, `{"version":1,"services":[{"service":"postgres","file":"postgres/dump.sql"}]}`},
+			},
+		},
+		{
+			name: "unsupported version",
+			entries: [][2]string{
+				{backup.ManifestName, `{"version":2,"services":[]}`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeBackupArchive(t, tt.entries)
+			dir := t.TempDir()
+			if _, err := backup.Read(bytes.NewReader(data), filepath.Join(dir, "extract")); err == nil {
+				t.Errorf("Read() expected error")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "outside.sql")); err == nil {
+				t.Errorf("Read() wrote a dump outside the extraction directory")
+			}
+		})
+	}
+}
+
+func TestBackupFileName(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 5, 0, time.UTC)
+	if name := backup.FileName("shop", created); name != "shop-20240501-123005.tar.gz" {
+		t.Errorf("FileName() = %s, want shop-20240501-123005.tar.gz", name)
+	}
+}
+
+func TestBackupMethods(t *testing.T) {
+	for name, method := range backup.Methods {
+		if _, exists := services.GetService(name); !exists {
+			t.Errorf("backup method for unknown service %s", name)
+		}
+		if method.Tool == "" || method.File == "" || method.Dump == "" {
+			t.Errorf("backup method for %s is incomplete: %+v", name, method)
+		}
+		if method.Restore == "" && method.RestorePath == "" {
+			t.Errorf("backup method for %s has no way to restore", name)
+		}
+	}
+
+	for _, name := range []string{"kafka", "elasticsearch"} {
+		if _, exists := backup.LookupMethod(name); exists {
+			t.Errorf("%s should have no logical backup", name)
+		}
+	}
+}
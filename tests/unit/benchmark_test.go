@@ -1,8 +1,13 @@
 package unit
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/mohammed-bageri/dockenv/internal/anonymize"
 	"github.com/mohammed-bageri/dockenv/internal/config"
 	"github.com/mohammed-bageri/dockenv/internal/services"
 	"github.com/mohammed-bageri/dockenv/internal/templates"
@@ -34,9 +39,9 @@ func BenchmarkGenerateDockerComposeEmbedded(b *testing.B) {
 	cfg := &config.Config{
 		Version:  "1.0",
 		Services: []string{"mysql", "redis"},
-		Ports: map[string]int{
-			"mysql": 3306,
-			"redis": 6379,
+		Ports: map[string]config.ServicePorts{
+			"mysql": {"mysql": 3306},
+			"redis": {"redis": 6379},
 		},
 		Env:      map[string]string{},
 		Volumes:  map[string]string{},
@@ -45,7 +50,7 @@ func BenchmarkGenerateDockerComposeEmbedded(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = templates.GenerateDockerComposeEmbedded(cfg)
+		_ = templates.GenerateDockerComposeEmbedded(cfg, templates.Environment{})
 	}
 }
 
@@ -55,3 +60,17 @@ func BenchmarkGetEmbeddedTemplate(b *testing.B) {
 		_, _ = templates.GetEmbeddedTemplate("mysql")
 	}
 }
+
+func BenchmarkAnonymize(b *testing.B) {
+	dump, err := os.ReadFile(filepath.Join("..", "testdata", "dumps", "mysql.sql"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	rules, _ := anonymize.ParseRules(testRules)
+
+	b.SetBytes(int64(len(dump)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = io.Copy(io.Discard, anonymize.NewReader(bytes.NewReader(dump), anonymize.MySQL, rules, ""))
+	}
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/bundle"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+)
+
+func TestParseComposeImages(t *testing.T) {
+	compose := `version: '3.8'
+
+services:
+  postgres:
+    image: postgres:15
+  redis:
+    image: redis:7-alpine
+  worker:
+    build: .
+  replica:
+    image: postgres:15
+`
+
+	images, err := docker.ParseComposeImages([]byte(compose))
+	if err != nil {
+		t.Fatalf("ParseComposeImages() error = %v", err)
+	}
+
+	expected := []string{"postgres:15", "redis:7-alpine"}
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("ParseComposeImages() = %v, want %v", images, expected)
+	}
+
+	if _, err := docker.ParseComposeImages([]byte("services: [")); err == nil {
+		t.Errorf("ParseComposeImages() expected error for invalid YAML")
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	imagesPath := filepath.Join(dir, "images.tar")
+	archive := []byte("engine image archive")
+	if err := os.WriteFile(imagesPath, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &bundle.Manifest{
+		Version:       bundle.ManifestVersion,
+		Created:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Project:       "shop",
+		Services:      []string{"postgres", "redis"},
+		Images:        []string{"postgres:15", "redis:7-alpine"},
+		ComposeDigest: bundle.Digest([]byte("compose")),
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Write(&buf, manifest, imagesPath); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data := buf.Bytes()
+
+	// The manifest can be read without extracting the images
+	got, err := bundle.Read(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, manifest) {
+		t.Errorf("Read() = %+v, want %+v", got, manifest)
+	}
+
+	extracted := filepath.Join(dir, "extracted.tar")
+	if _, err := bundle.Read(bytes.NewReader(data), extracted); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	content, err := os.ReadFile(extracted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, archive) {
+		t.Errorf("extracted archive = %q, want %q", content, archive)
+	}
+
+	if _, err := bundle.Read(strings.NewReader("not a tar file"), ""); err == nil {
+		t.Errorf("Read() expected error for a file that is not a bundle")
+	}
+}
+
+func TestManifestMissing(t *testing.T) {
+	manifest := &bundle.Manifest{Images: []string{"postgres:15", "redis:7-alpine"}}
+
+	missing := manifest.Missing([]string{"postgres:15", "mysql:8.0"})
+	if !reflect.DeepEqual(missing, []string{"mysql:8.0"}) {
+		t.Errorf("Missing() = %v, want [mysql:8.0]", missing)
+	}
+	if missing := manifest.Missing([]string{"redis:7-alpine"}); len(missing) != 0 {
+		t.Errorf("Missing() = %v, want none", missing)
+	}
+}
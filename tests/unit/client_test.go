@@ -0,0 +1,208 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+)
+
+// newFakeEngine serves handler on a unix socket like the Docker daemon and
+// returns the socket path.
+func newFakeEngine(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return socketPath
+}
+
+func containerList(health string) string {
+	return fmt.Sprintf(`[
+  {"Id":"abc","Names":["/dockenv-mysql"],"Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"mysql"},
+   "State":"running","Status":"Up 3 minutes (%s)","Ports":[{"IP":"127.0.0.1","PrivatePort":3306,"PublicPort":3306,"Type":"tcp"},{"PrivatePort":33060,"Type":"tcp"}]}
+]`, health)
+}
+
+func TestClientVersion(t *testing.T) {
+	socketPath := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"Version":"4.9.3","ApiVersion":"1.41","Components":[{"Name":"Podman Engine"}]}`)
+	}))
+
+	version, err := docker.NewClient(socketPath).Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	if version.Version != "4.9.3" || version.APIVersion != "1.41" || version.Product() != "Podman" {
+		t.Errorf("Version() = %+v, product %s", version, version.Product())
+	}
+}
+
+func TestClientListContainers(t *testing.T) {
+	socketPath := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil {
+			t.Errorf("invalid filters: %v", err)
+		}
+		if r.URL.Query().Get("all") != "1" {
+			t.Errorf("stopped containers should be listed, query: %s", r.URL.RawQuery)
+		}
+		if len(filters["label"]) != 1 || filters["label"][0] != "com.docker.compose.project=shop" {
+			t.Errorf("unexpected label filter: %v", filters["label"])
+		}
+		fmt.Fprint(w, containerList("healthy"))
+	}))
+
+	containers, err := docker.NewClient(socketPath).ListContainers(context.Background(), "shop")
+	if err != nil {
+		t.Fatalf("ListContainers() error = %v", err)
+	}
+	if len(containers) != 1 {
+		t.Fatalf("ListContainers() returned %d containers, want 1", len(containers))
+	}
+
+	mysql := containers[0]
+	if mysql.Name != "dockenv-mysql" || mysql.Service != "mysql" || !mysql.Healthy() || mysql.Uptime != "3 minutes" {
+		t.Errorf("unexpected container: %+v", mysql)
+	}
+	expected := docker.PortBinding{HostIP: "127.0.0.1", HostPort: 3306, ContainerPort: 3306, Protocol: "tcp"}
+	if len(mysql.Ports) != 1 || mysql.Ports[0] != expected {
+		t.Errorf("Ports = %+v, want [%+v]", mysql.Ports, expected)
+	}
+}
+
+func TestClientContainerHealth(t *testing.T) {
+	socketPath := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/dockenv-mysql/json":
+			fmt.Fprint(w, `{"State":{"Status":"running","Health":{"Status":"unhealthy"}}}`)
+		case "/containers/dockenv-redis/json":
+			fmt.Fprint(w, `{"State":{"Status":"running"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"No such container: dockenv-kafka"}`)
+		}
+	}))
+	client := docker.NewClient(socketPath)
+
+	if health, err := client.ContainerHealth(context.Background(), "dockenv-mysql"); err != nil || health != "unhealthy" {
+		t.Errorf("ContainerHealth(mysql) = %q, %v", health, err)
+	}
+	if health, err := client.ContainerHealth(context.Background(), "dockenv-redis"); err != nil || health != "" {
+		t.Errorf("ContainerHealth(redis) = %q, %v", health, err)
+	}
+
+	_, err := client.ContainerHealth(context.Background(), "dockenv-kafka")
+	if err == nil || !strings.Contains(err.Error(), "No such container") {
+		t.Errorf("ContainerHealth(kafka) error = %v, want engine message", err)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	socketPath := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, action := range []string{"start", "health_status: healthy", "die"} {
+			fmt.Fprintf(w, `{"Type":"container","Action":%q,"Actor":{"ID":"abc","Attributes":{"name":"dockenv-mysql","com.docker.compose.service":"mysql"}},"timeNano":1700000000000000000}`+"\n", action)
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	var actions []string
+	err := docker.NewClient(socketPath).Events(context.Background(), "shop", func(event docker.Event) bool {
+		if event.Service != "mysql" || event.Container != "dockenv-mysql" {
+			t.Errorf("unexpected event: %+v", event)
+		}
+		actions = append(actions, event.Action)
+		return len(actions) < 2
+	})
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if strings.Join(actions, ",") != "start,health_status: healthy" {
+		t.Errorf("Events() actions = %v", actions)
+	}
+}
+
+func TestClientWaitHealthy(t *testing.T) {
+	var lists int32
+	socketPath := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/events":
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+			fmt.Fprint(w, `{"Type":"container","Action":"health_status: healthy","Actor":{"Attributes":{"name":"dockenv-mysql"}}}`+"\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/containers/json":
+			if atomic.AddInt32(&lists, 1) == 1 {
+				fmt.Fprint(w, containerList("health: starting"))
+			} else {
+				fmt.Fprint(w, containerList("healthy"))
+			}
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := docker.NewClient(socketPath).WaitHealthy(ctx, "shop", []string{"mysql"}); err != nil {
+		t.Fatalf("WaitHealthy() error = %v", err)
+	}
+	if atomic.LoadInt32(&lists) < 2 {
+		t.Errorf("WaitHealthy() should re-check after the health event")
+	}
+}
+
+func TestClientWaitHealthyUnhealthy(t *testing.T) {
+	socketPath := newFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, containerList("unhealthy"))
+	}))
+
+	err := docker.NewClient(socketPath).WaitHealthy(context.Background(), "shop", []string{"mysql"})
+	if err == nil || !strings.Contains(err.Error(), "unhealthy") {
+		t.Errorf("WaitHealthy() error = %v, want unhealthy error", err)
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	socketPath := newFakeEngine(t, http.NotFoundHandler())
+
+	t.Setenv("DOCKER_HOST", "unix://"+socketPath)
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		t.Fatalf("NewClientFromEnv() error = %v", err)
+	}
+	if client.SocketPath() != socketPath {
+		t.Errorf("SocketPath() = %s, want %s", client.SocketPath(), socketPath)
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.5:2376")
+	if _, err := docker.NewClientFromEnv(); err == nil {
+		t.Error("NewClientFromEnv() should reject tcp hosts")
+	}
+}
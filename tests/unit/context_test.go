@@ -0,0 +1,222 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+)
+
+// writeDockerContext stores a context the way 'docker context create' does.
+func writeDockerContext(t *testing.T, configDir, name, host string) {
+	t.Helper()
+
+	digest := sha256.Sum256([]byte(name))
+	dir := filepath.Join(configDir, "contexts", "meta", hex.EncodeToString(digest[:]))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta := `{"Name":"` + name + `","Metadata":{},"Endpoints":{"docker":{"Host":"` + host + `","SkipTLSVerify":false}}}`
+	if err := os.WriteFile(filepath.Join(dir, "meta.json"), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		dockerHost    string
+		dockerContext string
+		current       string
+		expectedName  string
+		expectedHost  string
+		expectError   bool
+	}{
+		{
+			name:         "local engine",
+			expectedName: "default",
+			expectedHost: "unix:///var/run/docker.sock",
+		},
+		{
+			name:         "DOCKER_HOST",
+			dockerHost:   "ssh://dev@devvm",
+			current:      "staging",
+			expectedName: "default",
+			expectedHost: "ssh://dev@devvm",
+		},
+		{
+			name:         "current context",
+			current:      "devvm",
+			expectedName: "devvm",
+			expectedHost: "ssh://dev@devvm",
+		},
+		{
+			name:          "DOCKER_CONTEXT overrides the current context",
+			dockerContext: "staging",
+			current:       "devvm",
+			expectedName:  "staging",
+			expectedHost:  "tcp://10.0.0.5:2376",
+		},
+		{
+			name:          "default context",
+			dockerContext: "default",
+			current:       "devvm",
+			expectedName:  "default",
+			expectedHost:  "unix:///var/run/docker.sock",
+		},
+		{
+			name:          "missing context",
+			dockerContext: "gone",
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			writeDockerContext(t, configDir, "devvm", "ssh://dev@devvm")
+			writeDockerContext(t, configDir, "staging", "tcp://10.0.0.5:2376")
+			if tt.current != "" {
+				cliConfig := `{"auths":{},"currentContext":"` + tt.current + `"}`
+				if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(cliConfig), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			t.Setenv("DOCKER_CONFIG", configDir)
+			t.Setenv("DOCKER_HOST", tt.dockerHost)
+			t.Setenv("DOCKER_CONTEXT", tt.dockerContext)
+
+			c, err := docker.ResolveContext("docker")
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ResolveContext() expected error, got %v", c)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveContext() error = %v", err)
+			}
+			if c.Name != tt.expectedName || c.Host != tt.expectedHost {
+				t.Errorf("ResolveContext() = %v, want %s (%s)", c, tt.expectedName, tt.expectedHost)
+			}
+		})
+	}
+}
+
+func TestContextHostname(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"unix:///var/run/docker.sock", ""},
+		{"npipe:////./pipe/docker_engine", ""},
+		{"ssh://dev@devvm", "devvm"},
+		{"ssh://dev@devvm.example.com:2222", "devvm.example.com"},
+		{"tcp://10.0.0.5:2376", "10.0.0.5"},
+		{"tcp://127.0.0.1:2375", ""},
+		{"tcp://localhost:2375", ""},
+		{"tcp://[::1]:2375", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			c := docker.Context{Name: "test", Host: tt.host}
+			if got := c.Hostname(); got != tt.expected {
+				t.Errorf("Hostname() = %q, want %q", got, tt.expected)
+			}
+			if got := c.Remote(); got != (tt.expected != "") {
+				t.Errorf("Remote() = %v, want %v", got, tt.expected != "")
+			}
+		})
+	}
+}
+
+func TestConfigStorage(t *testing.T) {
+	tests := []struct {
+		storage  string
+		remote   bool
+		expected bool
+	}{
+		{"", false, false},
+		{"", true, true},
+		{config.StorageBind, true, false},
+		{config.StorageVolume, false, true},
+	}
+
+	for _, tt := range tests {
+		cfg := &config.Config{Storage: tt.storage}
+		if got := cfg.UsesVolumes(tt.remote); got != tt.expected {
+			t.Errorf("UsesVolumes(%v) with storage %q = %v, want %v", tt.remote, tt.storage, got, tt.expected)
+		}
+	}
+}
+
+func TestGetPublishAddress(t *testing.T) {
+	cfg := &config.Config{BindAddresses: map[string]string{"redis": "10.0.0.5"}}
+
+	if got := cfg.GetPublishAddress("mysql", false); got != config.DefaultBindAddress {
+		t.Errorf("GetPublishAddress(mysql, local) = %s, want %s", got, config.DefaultBindAddress)
+	}
+	if got := cfg.GetPublishAddress("mysql", true); got != config.ExposeBindAddress {
+		t.Errorf("GetPublishAddress(mysql, remote) = %s, want %s", got, config.ExposeBindAddress)
+	}
+	if got := cfg.GetPublishAddress("redis", true); got != "10.0.0.5" {
+		t.Errorf("GetPublishAddress(redis, remote) = %s, want 10.0.0.5", got)
+	}
+}
+
+func TestRenderDockerComposeRemote(t *testing.T) {
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql", "kafka"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{"DB_HOST": "127.0.0.1", "KAFKA_HOST": "kafka.internal", "DB_PORT": "3306"},
+		DataPath: "/srv/dockenv",
+	}
+	env := templates.Environment{EngineHost: "devvm"}
+
+	content, err := templates.RenderDockerCompose(cfg, env)
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	for _, expected := range []string{
+		`- "0.0.0.0:3306:3306"`,
+		`- mysql_data:/var/lib/mysql`,
+		`- kafka_data:/var/lib/kafka/data`,
+		`PLAINTEXT://devvm:9092`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Rendered compose file should contain '%s'.\nActual content:\n%s", expected, content)
+		}
+	}
+
+	// Bind storage keeps the data path, which is a path on the remote host
+	cfg.Storage = config.StorageBind
+	content, err = templates.RenderDockerCompose(cfg, env)
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	if !strings.Contains(string(content), "- /srv/dockenv/mysql:/var/lib/mysql") {
+		t.Errorf("Rendered compose file should bind mount the data path:\n%s", content)
+	}
+
+	cfg.Storage = "nfs"
+	if _, err := templates.RenderDockerCompose(cfg, env); err == nil {
+		t.Errorf("RenderDockerCompose() expected error for invalid storage")
+	}
+
+	envVars := templates.EnvVars(cfg, env)
+	expected := map[string]string{"DB_HOST": "devvm", "KAFKA_HOST": "kafka.internal", "DB_PORT": "3306"}
+	for key, value := range expected {
+		if envVars[key] != value {
+			t.Errorf("EnvVars()[%s] = %q, want %q", key, envVars[key], value)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -8,7 +9,7 @@ import (
 )
 
 func TestCheckDocker(t *testing.T) {
-	info, err := docker.CheckDocker()
+	info, err := docker.CheckDocker(context.Background())
 	if err != nil {
 		t.Errorf("CheckDocker() error = %v, want nil", err)
 		return
@@ -150,7 +151,7 @@ func TestRunCompose(t *testing.T) {
 	}()
 
 	// This will likely fail, but should not panic
-	_ = docker.RunCompose("--help")
+	_ = docker.RunCompose(context.Background(), "--help")
 }
 
 func TestComposeValidate(t *testing.T) {
@@ -162,5 +163,163 @@ func TestComposeValidate(t *testing.T) {
 	}()
 
 	// This will likely fail due to no file, but should not panic
-	_ = docker.ComposeValidate("non-existent-file.yaml")
+	_ = docker.ComposeValidate(context.Background(), "non-existent-file.yaml")
+}
+
+func TestRuntimes(t *testing.T) {
+	tests := []struct {
+		name         string
+		engine       string
+		expectedArgs []string
+	}{
+		{"compose", "docker", []string{"docker", "compose", "-f", "file.yaml", "up", "-d"}},
+		{"docker-compose", "docker", []string{"docker-compose", "-f", "file.yaml", "up", "-d"}},
+		{"podman-compose", "podman", []string{"podman-compose", "-f", "file.yaml", "up", "-d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt, exists := docker.GetRuntime(tt.name)
+			if !exists {
+				t.Fatalf("GetRuntime(%q) not found", tt.name)
+			}
+			if rt.Engine() != tt.engine {
+				t.Errorf("Engine() = %s, want %s", rt.Engine(), tt.engine)
+			}
+
+			args := rt.Command(context.Background(), "-f", "file.yaml", "up", "-d").Args
+			if strings.Join(args, " ") != strings.Join(tt.expectedArgs, " ") {
+				t.Errorf("Command() args = %v, want %v", args, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestSelectRuntimeUnknown(t *testing.T) {
+	_, err := docker.SelectRuntime(context.Background(), "containerd")
+	if err == nil {
+		t.Fatal("SelectRuntime() expected error for unknown runtime")
+	}
+	if !strings.Contains(err.Error(), "podman-compose") {
+		t.Errorf("error should list available runtimes, got: %v", err)
+	}
+}
+
+func TestDockerInfo_GetInstallInstructionsRuntime(t *testing.T) {
+	info := &docker.DockerInfo{
+		DockerInstalled: false,
+		Runtime:         "podman-compose",
+		Engine:          "podman",
+	}
+
+	instructions := info.GetInstallInstructions()
+	for _, expected := range []string{"Podman is not installed", "runtime podman-compose is not installed"} {
+		if !strings.Contains(instructions, expected) {
+			t.Errorf("GetInstallInstructions() should contain '%s', got: %s", expected, instructions)
+		}
+	}
+}
+
+func TestParseContainers(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{
+			name: "docker",
+			output: `{"Names":"dockenv-mysql","Labels":"com.docker.compose.project=shop,com.docker.compose.service=mysql","Ports":"127.0.0.1:3306->3306/tcp, 33060/tcp","State":"running","Status":"Up 5 minutes (healthy)"}
+{"Names":"dockenv-redis","Labels":"com.docker.compose.project=shop,com.docker.compose.service=redis","Ports":"","State":"exited","Status":"Exited (0) 2 minutes ago"}`,
+		},
+		{
+			name: "compose",
+			output: `{"Name":"dockenv-mysql","Service":"mysql","State":"running","Health":"healthy","Status":"Up 5 minutes (healthy)","Ports":"127.0.0.1:3306->3306/tcp","Publishers":[{"URL":"127.0.0.1","TargetPort":3306,"PublishedPort":3306,"Protocol":"tcp"},{"URL":"","TargetPort":33060,"PublishedPort":0,"Protocol":"tcp"}]}
+{"Name":"dockenv-redis","Service":"redis","State":"exited","Health":"","Status":"Exited (0) 2 minutes ago","Publishers":null}`,
+		},
+		{
+			name: "podman",
+			output: `[
+  {"Names":["dockenv-mysql"],"Labels":{"com.docker.compose.project":"shop","com.docker.compose.service":"mysql","io.podman.compose.project":"shop"},"Ports":[{"host_ip":"127.0.0.1","container_port":3306,"host_port":3306,"range":1,"protocol":"tcp"}],"State":"running","Status":"Up 5 minutes (healthy)"},
+  {"Names":["dockenv-redis"],"Labels":{"com.docker.compose.service":"redis"},"Ports":null,"State":"exited","Status":"Exited (0) 2 minutes ago"}
+]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			containers, err := docker.ParseContainers([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("ParseContainers() error = %v", err)
+			}
+			if len(containers) != 2 {
+				t.Fatalf("ParseContainers() returned %d containers, want 2", len(containers))
+			}
+
+			mysql := containers[0]
+			if mysql.Name != "dockenv-mysql" || mysql.Service != "mysql" {
+				t.Errorf("unexpected name/service: %s/%s", mysql.Name, mysql.Service)
+			}
+			if !mysql.Healthy() || mysql.Health != "healthy" || mysql.Uptime != "5 minutes" {
+				t.Errorf("mysql should be up 5 minutes and healthy, got state %s health %q uptime %q", mysql.State, mysql.Health, mysql.Uptime)
+			}
+			expectedPorts := []docker.PortBinding{{HostIP: "127.0.0.1", HostPort: 3306, ContainerPort: 3306, Protocol: "tcp"}}
+			if len(mysql.Ports) != 1 || mysql.Ports[0] != expectedPorts[0] {
+				t.Errorf("mysql ports = %+v, want %+v", mysql.Ports, expectedPorts)
+			}
+
+			redis := containers[1]
+			if redis.Service != "redis" || redis.Running() || len(redis.Ports) != 0 {
+				t.Errorf("unexpected redis container: %+v", redis)
+			}
+		})
+	}
+}
+
+func TestParsePortString(t *testing.T) {
+	bindings := docker.ParsePortString("0.0.0.0:5672->5672/tcp, [::1]:15672->15672/tcp, 4369/tcp")
+	expected := []docker.PortBinding{
+		{HostIP: "0.0.0.0", HostPort: 5672, ContainerPort: 5672, Protocol: "tcp"},
+		{HostIP: "::1", HostPort: 15672, ContainerPort: 15672, Protocol: "tcp"},
+	}
+
+	if len(bindings) != len(expected) {
+		t.Fatalf("ParsePortString() = %+v, want %+v", bindings, expected)
+	}
+	for i := range expected {
+		if bindings[i] != expected[i] {
+			t.Errorf("binding %d = %+v, want %+v", i, bindings[i], expected[i])
+		}
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "unix:///tmp/docker-test.sock")
+	if path := docker.SocketPath("docker"); path != "/tmp/docker-test.sock" {
+		t.Errorf("SocketPath(docker) = %s, want /tmp/docker-test.sock", path)
+	}
+
+	t.Setenv("CONTAINER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	path := docker.SocketPath("podman")
+	if path != "/run/user/1000/podman/podman.sock" && path != "/run/podman/podman.sock" {
+		t.Errorf("SocketPath(podman) = %s", path)
+	}
+}
+
+func TestDockerInfo_WarningsPodmanSocket(t *testing.T) {
+	info := &docker.DockerInfo{
+		Runtime:    "podman",
+		Engine:     "podman",
+		SocketPath: "/run/user/1000/podman/podman.sock",
+		Rootless:   true,
+	}
+
+	warnings := info.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "systemctl --user enable --now podman.socket") {
+		t.Errorf("Warnings() = %v, want socket hint", warnings)
+	}
+
+	info.SocketAvailable = true
+	if warnings := info.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() = %v, want none", warnings)
+	}
 }
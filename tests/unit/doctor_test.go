@@ -0,0 +1,242 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/doctor"
+	"github.com/mohammed-bageri/dockenv/internal/systemd"
+)
+
+func TestExtractVersion(t *testing.T) {
+	tests := []struct {
+		banner   string
+		expected string
+	}{
+		{"Docker version 24.0.7, build afdd53b", "24.0.7"},
+		{"Docker Compose version v2.23.3", "2.23.3"},
+		{"docker-compose version 1.29.2, build 5becea4c", "1.29.2"},
+		{"podman-compose version 1.0", "1.0"},
+		{"unknown", ""},
+	}
+
+	for _, tt := range tests {
+		if got := doctor.ExtractVersion(tt.banner); got != tt.expected {
+			t.Errorf("ExtractVersion(%q) = %q, want %q", tt.banner, got, tt.expected)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"20.10.0", "20.10.0", 0},
+		{"20.10", "20.10.0", 0},
+		{"19.03.15", "20.10.0", -1},
+		{"24.0.7", "20.10.0", 1},
+		{"2.10.0", "2.9.0", 1},
+	}
+
+	for _, tt := range tests {
+		if got := doctor.CompareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckEngineAndCompose(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    docker.DockerInfo
+		engine  doctor.Status
+		compose doctor.Status
+	}{
+		{
+			name: "current docker",
+			info: docker.DockerInfo{DockerInstalled: true, DockerRunning: true, DockerComposeInstalled: true,
+				Engine: "docker", Runtime: "compose", DockerVersion: "Docker version 24.0.7", ComposeVersion: "v2.23.3"},
+			engine:  doctor.StatusPass,
+			compose: doctor.StatusPass,
+		},
+		{
+			name: "old docker with compose v1",
+			info: docker.DockerInfo{DockerInstalled: true, DockerRunning: true, DockerComposeInstalled: true,
+				Engine: "docker", Runtime: "docker-compose", DockerVersion: "Docker version 19.03.15", ComposeVersion: "1.29.2"},
+			engine:  doctor.StatusFail,
+			compose: doctor.StatusWarn,
+		},
+		{
+			name:    "daemon stopped",
+			info:    docker.DockerInfo{DockerInstalled: true, Engine: "docker"},
+			engine:  doctor.StatusFail,
+			compose: doctor.StatusFail,
+		},
+		{
+			name: "old podman",
+			info: docker.DockerInfo{DockerInstalled: true, DockerRunning: true, DockerComposeInstalled: true,
+				Engine: "podman", Runtime: "podman-compose", DockerVersion: "podman version 3.4.4", ComposeVersion: "podman-compose version 1.0.6"},
+			engine:  doctor.StatusFail,
+			compose: doctor.StatusPass,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := doctor.CheckEngine(&tt.info); got.Status != tt.engine {
+				t.Errorf("CheckEngine() = %+v, want %s", got, tt.engine)
+			}
+			if got := doctor.CheckCompose(&tt.info); got.Status != tt.compose {
+				t.Errorf("CheckCompose() = %+v, want %s", got, tt.compose)
+			}
+		})
+	}
+}
+
+func TestCheckSocket(t *testing.T) {
+	dockerInfo := &docker.DockerInfo{Engine: "docker"}
+
+	tests := []struct {
+		name     string
+		access   doctor.SocketAccess
+		expected doctor.Status
+		hint     string
+	}{
+		{"writable", doctor.SocketAccess{Exists: true, Writable: true}, doctor.StatusPass, ""},
+		{"not in group", doctor.SocketAccess{Exists: true}, doctor.StatusFail, "Add yourself to the docker group: sudo usermod -aG docker $USER, then log out and back in"},
+		{"group not active", doctor.SocketAccess{Exists: true, InGroup: true}, doctor.StatusFail, "Log out and back in, or run 'newgrp docker', to pick up the group membership"},
+	}
+
+	for _, tt := range tests {
+		got := doctor.CheckSocket(dockerInfo, tt.access)
+		if got.Status != tt.expected || got.Hint != tt.hint {
+			t.Errorf("%s: CheckSocket() = %+v", tt.name, got)
+		}
+	}
+
+	// Remote engines have no local socket
+	remoteInfo := &docker.DockerInfo{Engine: "docker", Context: docker.Context{Name: "devvm", Host: "ssh://dev@devvm"}}
+	if got := doctor.CheckSocket(remoteInfo, doctor.SocketAccess{}); got.Status != doctor.StatusPass {
+		t.Errorf("remote: CheckSocket() = %+v", got)
+	}
+}
+
+func TestCheckRootless(t *testing.T) {
+	rootless := &docker.DockerInfo{Rootless: true}
+
+	if got := doctor.CheckRootless(rootless, []int{80, 3306}); got.Status != doctor.StatusWarn {
+		t.Errorf("privileged port should warn: %+v", got)
+	}
+	if got := doctor.CheckRootless(rootless, []int{3306}); got.Status != doctor.StatusPass {
+		t.Errorf("unprivileged ports should pass: %+v", got)
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	tests := []struct {
+		free     uint64
+		expected doctor.Status
+	}{
+		{50 << 30, doctor.StatusPass},
+		{5 << 30, doctor.StatusWarn},
+		{1 << 30, doctor.StatusFail},
+	}
+
+	for _, tt := range tests {
+		if got := doctor.CheckDiskSpace("/var/lib/dockenv", tt.free); got.Status != tt.expected {
+			t.Errorf("CheckDiskSpace(%d) = %+v, want %s", tt.free, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckDataOwner(t *testing.T) {
+	tests := []struct {
+		name     string
+		owner    string
+		rootless bool
+		expected doctor.Status
+		hint     string
+	}{
+		{"missing", "", false, doctor.StatusPass, ""},
+		{"same uid", "999:0", false, doctor.StatusPass, ""},
+		{"root owned", "0:0", false, doctor.StatusWarn, "sudo chown -R 999:999 /data/mysql"},
+		{"rootless", "0:0", true, doctor.StatusWarn, "podman unshare chown -R 999:999 /data/mysql"},
+	}
+
+	for _, tt := range tests {
+		got := doctor.CheckDataOwner("/data/mysql", tt.owner, "999:999", tt.rootless)
+		if got.Status != tt.expected || got.Hint != tt.hint {
+			t.Errorf("%s: CheckDataOwner() = %+v", tt.name, got)
+		}
+	}
+}
+
+func TestCheckStaleContainers(t *testing.T) {
+	containers := []docker.Container{
+		{Name: "dockenv-mysql", Service: "mysql", State: "running"},
+		{Name: "dockenv-mongodb", Service: "mongodb", State: "exited"},
+	}
+
+	got := doctor.CheckStaleContainers([]string{"mysql"}, containers)
+	if got.Status != doctor.StatusWarn || got.Message != "dockenv-mongodb (exited)" {
+		t.Errorf("CheckStaleContainers() = %+v", got)
+	}
+
+	if got := doctor.CheckStaleContainers([]string{"mysql", "mongodb"}, containers); got.Status != doctor.StatusPass {
+		t.Errorf("CheckStaleContainers() = %+v, want pass", got)
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	unit := systemd.ParseUnit(`[Unit]
+Description=DockEnv Development Services
+Requires=docker.service
+After=docker.service
+
+[Service]
+WorkingDirectory=/home/dev/shop
+ExecStart=/usr/local/bin/dockenv up
+User=dev
+`)
+
+	if unit.WorkingDirectory != "/home/dev/shop" || unit.ExecStart != "/usr/local/bin/dockenv up" || unit.User != "dev" {
+		t.Errorf("ParseUnit() = %+v", unit)
+	}
+	if len(unit.Requires) != 1 || unit.Requires[0] != "docker.service" {
+		t.Errorf("Requires = %v", unit.Requires)
+	}
+}
+
+func TestCheckUnit(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, config.ComposeFileName), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		unit     *systemd.Unit
+		engine   string
+		expected doctor.Status
+	}{
+		{"not installed", nil, "docker", doctor.StatusPass},
+		{"consistent", &systemd.Unit{WorkingDirectory: projectDir, ExecStart: binary + " up", Requires: []string{"docker.service"}}, "docker", doctor.StatusPass},
+		{"project moved", &systemd.Unit{WorkingDirectory: t.TempDir(), ExecStart: binary + " up"}, "docker", doctor.StatusFail},
+		{"binary missing", &systemd.Unit{WorkingDirectory: projectDir, ExecStart: "/nonexistent/dockenv up"}, "docker", doctor.StatusFail},
+		{"podman requires docker", &systemd.Unit{WorkingDirectory: projectDir, ExecStart: binary + " up", Requires: []string{"docker.service"}}, "podman", doctor.StatusWarn},
+	}
+
+	for _, tt := range tests {
+		if got := doctor.CheckUnit(tt.unit, tt.engine); got.Status != tt.expected {
+			t.Errorf("%s: CheckUnit() = %+v, want %s", tt.name, got, tt.expected)
+		}
+	}
+}
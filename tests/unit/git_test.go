@@ -0,0 +1,90 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/git"
+)
+
+func TestParseHead(t *testing.T) {
+	tests := []struct {
+		head        string
+		expected    string
+		expectError bool
+	}{
+		{head: "ref: refs/heads/main\n", expected: "main"},
+		{head: "ref: refs/heads/feature/login\n", expected: "feature/login"},
+		{head: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b\n", expectError: true},
+		{head: "ref: refs/remotes/origin/main\n", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			branch, err := git.ParseHead(tt.head)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseHead(%q) expected error, got %q", tt.head, branch)
+				}
+				return
+			}
+			if err != nil || branch != tt.expected {
+				t.Errorf("ParseHead(%q) = %q, %v, want %q", tt.head, branch, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGitFindDir(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	gitDir := filepath.Join(repo, ".git")
+	if err := os.MkdirAll(filepath.Join(gitDir, "worktrees", "wt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	subdir := filepath.Join(repo, "app", "src")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := git.FindDir(subdir)
+	if err != nil || found != gitDir {
+		t.Fatalf("FindDir() = %q, %v, want %q", found, err, gitDir)
+	}
+	if branch, err := git.CurrentBranch(found); err != nil || branch != "main" {
+		t.Errorf("CurrentBranch() = %q, %v, want main", branch, err)
+	}
+	if hooks := git.HooksDir(found); hooks != filepath.Join(gitDir, "hooks") {
+		t.Errorf("HooksDir() = %q, want %q", hooks, filepath.Join(gitDir, "hooks"))
+	}
+
+	// A worktree has a .git file pointing into the main repository, which
+	// holds the hooks
+	worktree := filepath.Join(root, "wt")
+	worktreeGitDir := filepath.Join(gitDir, "worktrees", "wt")
+	if err := os.MkdirAll(worktree, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte("gitdir: "+worktreeGitDir+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktreeGitDir, "commondir"), []byte("../..\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err = git.FindDir(worktree)
+	if err != nil || found != worktreeGitDir {
+		t.Fatalf("FindDir() of worktree = %q, %v, want %q", found, err, worktreeGitDir)
+	}
+	if hooks := git.HooksDir(found); hooks != filepath.Join(gitDir, "hooks") {
+		t.Errorf("HooksDir() of worktree = %q, want %q", hooks, filepath.Join(gitDir, "hooks"))
+	}
+
+	if _, err := git.FindDir(filepath.Join(root)); err == nil {
+		t.Errorf("FindDir() expected error outside a repository")
+	}
+}
@@ -0,0 +1,184 @@
+package unit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/importer"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+)
+
+func TestImporterDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		expected importer.Format
+		wantErr  bool
+	}{
+		{name: "pg_dump", header: []byte("--\n-- PostgreSQL database dump\n--\n"), expected: importer.FormatSQL},
+		{name: "mysqldump", header: []byte("-- MySQL dump 10.13\r\n/*!40101 SET NAMES utf8 */;\r\n"), expected: importer.FormatSQL},
+		{name: "cut off character", header: []byte("INSERT INTO names VALUES ('J\xc3"), expected: importer.FormatSQL},
+		{name: "custom format", header: []byte("PGDMP\x01\x0e\x00\x04\x08"), expected: importer.FormatPGCustom},
+		{name: "mongo archive", header: []byte{0x6d, 0xe2, 0x99, 0x81, 0x00, 0x01}, expected: importer.FormatMongoArchive},
+		{name: "rdb", header: []byte("REDIS0011\xfa\x09redis-ver"), expected: importer.FormatRDB},
+		{name: "binary", header: []byte{0x00, 0x01, 0x02, 0xff}, wantErr: true},
+		{name: "invalid utf-8", header: []byte("SELECT '\xff\xfe' FROM users"), wantErr: true},
+		{name: "empty", header: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := importer.Detect(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if format != tt.expected {
+				t.Errorf("Detect() = %q, want %q", format, tt.expected)
+			}
+		})
+	}
+}
+
+func TestImporterOpen(t *testing.T) {
+	sql := []byte("CREATE TABLE users (id int);\nINSERT INTO users VALUES (1);\n")
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(sql)
+	gz.Close()
+
+	tests := []struct {
+		name       string
+		content    []byte
+		compressed bool
+	}{
+		{name: "dump.sql", content: sql},
+		{name: "dump.sql.gz", content: compressed.Bytes(), compressed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			progress := importer.NewProgress(&out, int64(len(tt.content)))
+			dump, err := importer.Open(path, progress)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer dump.Close()
+
+			if dump.Format != importer.FormatSQL || dump.Compressed != tt.compressed {
+				t.Errorf("Open() = %s, compressed %v, want sql, compressed %v", dump.Format, dump.Compressed, tt.compressed)
+			}
+			if dump.Size != int64(len(tt.content)) {
+				t.Errorf("Size = %d, want %d", dump.Size, len(tt.content))
+			}
+
+			data, err := io.ReadAll(dump)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(data, sql) {
+				t.Errorf("read %q, want %q", data, sql)
+			}
+
+			progress.Done()
+			if !bytes.Contains(out.Bytes(), []byte("100%")) {
+				t.Errorf("progress = %q, want it to reach 100%%", out.String())
+			}
+		})
+	}
+}
+
+func TestImporterOpenUnknown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junk.bin")
+	if err := os.WriteFile(path, []byte{0x00, 0x01, 0x02}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importer.Open(path, nil); err == nil {
+		t.Error("Open() of an unknown format should fail")
+	}
+}
+
+func TestImporterMethods(t *testing.T) {
+	for name, method := range importer.Methods {
+		if _, exists := services.GetService(name); !exists {
+			t.Errorf("method for unknown service %s", name)
+		}
+		formats := method.Formats()
+		if len(formats) == 0 {
+			t.Errorf("%s loads no formats", name)
+		}
+		for _, format := range formats {
+			if importer.Descriptions[format] == "" {
+				t.Errorf("%s: format %s has no description", name, format)
+			}
+		}
+		if method.DatabaseVar != "" {
+			service, _ := services.GetService(name)
+			if service.EnvVars[method.DatabaseVar] == "" {
+				t.Errorf("%s: %s has no default", name, method.DatabaseVar)
+			}
+		}
+	}
+
+	postgres, _ := importer.LookupMethod("postgres")
+	expected := []importer.Format{importer.FormatPGCustom, importer.FormatSQL}
+	if formats := postgres.Formats(); !reflect.DeepEqual(formats, expected) {
+		t.Errorf("postgres Formats() = %v, want %v", formats, expected)
+	}
+}
+
+func TestImporterValidateDatabase(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "dockenv"},
+		{name: "shop_test"},
+		{name: "shop-2024"},
+		{name: "", wantErr: true},
+		{name: "-shop", wantErr: true},
+		{name: "a;b", wantErr: true},
+		{name: "shop`", wantErr: true},
+		{name: "o'brien", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := importer.ValidateDatabase(tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDatabase(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 1023, expected: "1023 B"},
+		{size: 1536, expected: "1.5 KiB"},
+		{size: 5 << 20, expected: "5.0 MiB"},
+		{size: 3 << 29, expected: "1.5 GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if result := importer.FormatBytes(tt.size); result != tt.expected {
+				t.Errorf("FormatBytes(%d) = %q, want %q", tt.size, result, tt.expected)
+			}
+		})
+	}
+}
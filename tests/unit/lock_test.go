@@ -0,0 +1,214 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/lock"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+)
+
+const (
+	redisDigest     = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	kafkaDigest     = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	zookeeperDigest = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+func TestLockSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.LockFileName)
+
+	if _, err := lock.Load(path); !os.IsNotExist(err) {
+		t.Fatalf("Load() error = %v, want not exist", err)
+	}
+
+	lockFile := lock.New()
+	lockFile.Services["redis"] = []lock.Image{{Image: "redis:7-alpine", Digest: redisDigest}}
+	if err := lockFile.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := lock.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, lockFile) {
+		t.Errorf("Load() = %+v, want %+v", loaded, lockFile)
+	}
+
+	digest, locked := loaded.Digest("redis", "redis:7-alpine")
+	if !locked || digest != redisDigest {
+		t.Errorf("Digest(redis) = %s, %v", digest, locked)
+	}
+	if _, locked := loaded.Digest("redis", "redis:6"); locked {
+		t.Errorf("Digest() should not match another tag")
+	}
+
+	if err := os.WriteFile(path, []byte("version: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lock.Load(path); err == nil {
+		t.Errorf("Load() expected error for an unsupported version")
+	}
+}
+
+func TestLockStale(t *testing.T) {
+	lockFile := lock.New()
+	lockFile.Services["redis"] = []lock.Image{{Image: "redis:7-alpine", Digest: redisDigest}}
+	lockFile.Services["kafka"] = []lock.Image{
+		{Image: "confluentinc/cp-kafka:latest", Digest: kafkaDigest},
+		{Image: "confluentinc/cp-zookeeper:latest", Digest: zookeeperDigest},
+	}
+
+	tests := []struct {
+		name     string
+		required map[string][]string
+		expected []string
+	}{
+		{
+			name: "up to date",
+			required: map[string][]string{
+				"redis": {"redis:7-alpine"},
+				"kafka": {"confluentinc/cp-kafka:latest", "confluentinc/cp-zookeeper:latest"},
+			},
+		},
+		{
+			name: "new service",
+			required: map[string][]string{
+				"redis":    {"redis:7-alpine"},
+				"kafka":    {"confluentinc/cp-kafka:latest", "confluentinc/cp-zookeeper:latest"},
+				"postgres": {"postgres:15"},
+			},
+			expected: []string{"postgres"},
+		},
+		{
+			name: "changed tag and removed service",
+			required: map[string][]string{
+				"redis": {"redis:8-alpine"},
+			},
+			expected: []string{"kafka", "redis"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lockFile.Stale(tt.required); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Stale() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLockPin(t *testing.T) {
+	if got := lock.Pin("redis:7-alpine", redisDigest); got != "redis:7-alpine@"+redisDigest {
+		t.Errorf("Pin() = %s", got)
+	}
+	if got := lock.Pin("redis:7-alpine@sha256:0000", redisDigest); got != "redis:7-alpine@"+redisDigest {
+		t.Errorf("Pin() should replace an existing digest, got %s", got)
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	tests := []struct {
+		name        string
+		image       string
+		repoDigests []string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:        "docker hub",
+			image:       "redis:7-alpine",
+			repoDigests: []string{"redis@" + redisDigest},
+			expected:    redisDigest,
+		},
+		{
+			name:        "podman long names",
+			image:       "confluentinc/cp-kafka:latest",
+			repoDigests: []string{"docker.io/confluentinc/cp-kafka@" + kafkaDigest},
+			expected:    kafkaDigest,
+		},
+		{
+			name:        "other repositories",
+			image:       "redis:7-alpine",
+			repoDigests: []string{"registry.example.com:5000/redis@" + kafkaDigest, "docker.io/library/redis@" + redisDigest},
+			expected:    redisDigest,
+		},
+		{
+			name:        "built locally",
+			image:       "redis:7-alpine",
+			repoDigests: []string{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := docker.RepoDigest(tt.image, tt.repoDigests)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("RepoDigest() expected error, got %s", digest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RepoDigest() error = %v", err)
+			}
+			if digest != tt.expected {
+				t.Errorf("RepoDigest() = %s, want %s", digest, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderDockerComposeLocked(t *testing.T) {
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"redis", "kafka"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{},
+		DataPath: t.TempDir(),
+	}
+
+	required := make(map[string][]string)
+	for _, serviceName := range cfg.Services {
+		content, err := templates.RenderService(cfg, templates.Environment{}, serviceName)
+		if err != nil {
+			t.Fatalf("RenderService(%s) error = %v", serviceName, err)
+		}
+		if required[serviceName], err = docker.ParseComposeImages(append([]byte("services:\n"), content...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string][]string{
+		"redis": {"redis:7-alpine"},
+		"kafka": {"confluentinc/cp-kafka:latest", "confluentinc/cp-zookeeper:latest"},
+	}
+	if !reflect.DeepEqual(required, expected) {
+		t.Fatalf("service images = %v, want %v", required, expected)
+	}
+
+	// Only redis is locked; kafka keeps its floating tags
+	lockFile := lock.New()
+	lockFile.Services["redis"] = []lock.Image{{Image: "redis:7-alpine", Digest: redisDigest}}
+	lockFile.Services["kafka"] = []lock.Image{{Image: "confluentinc/cp-kafka:latest"}}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{Pins: lockFile.Pins()})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	contentStr := string(content)
+
+	for _, expected := range []string{
+		"image: redis:7-alpine@" + redisDigest,
+		"image: confluentinc/cp-kafka:latest\n",
+	} {
+		if !strings.Contains(contentStr, expected) {
+			t.Errorf("Rendered compose file should contain %q.\nActual content:\n%s", expected, contentStr)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/plan"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+	"github.com/mohammed-bageri/dockenv/internal/utils"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		before   string
+		after    string
+		expected string
+	}{
+		{
+			name:     "identical",
+			before:   "a\nb\n",
+			after:    "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "new file",
+			before:   "",
+			after:    "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "changed line with context",
+			before:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			after:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: "--- old\n+++ new\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := plan.UnifiedDiff("old", "new", []byte(tt.before), []byte(tt.after))
+			if result != tt.expected {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldCwd, _ := os.Getwd()
+	defer os.Chdir(oldCwd)
+	os.Chdir(tempDir)
+
+	os.Setenv("DOCKENV_CONFIG", filepath.Join(tempDir, "dockenv.yaml"))
+	defer os.Unsetenv("DOCKENV_CONFIG")
+
+	current := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql", "redis"},
+		Ports:    map[string]config.ServicePorts{"mysql": {"mysql": 3306}, "redis": {"redis": 6379}},
+		Env:      map[string]string{},
+		Volumes:  map[string]string{},
+		DataPath: tempDir,
+	}
+	if err := templates.GenerateDockerComposeEmbedded(current, templates.Environment{}); err != nil {
+		t.Fatalf("GenerateDockerComposeEmbedded() error = %v", err)
+	}
+
+	planned := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql", "postgres"},
+		Ports:    map[string]config.ServicePorts{"mysql": {"mysql": 3307}, "postgres": {"postgres": 5432}},
+		Env:      map[string]string{"DB_HOST": "127.0.0.1"},
+		Volumes:  map[string]string{},
+		DataPath: tempDir,
+	}
+
+	p, err := plan.Build(planned, templates.Environment{})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	expectedActions := map[string]plan.ActionKind{
+		"mysql":    plan.ActionRecreate,
+		"postgres": plan.ActionCreate,
+		"redis":    plan.ActionRemove,
+	}
+	if len(p.Actions) != len(expectedActions) {
+		t.Errorf("Build() actions = %v, want %d actions", p.Actions, len(expectedActions))
+	}
+	for _, action := range p.Actions {
+		if expectedActions[action.Service] != action.Kind {
+			t.Errorf("Action for %s = %s, want %s", action.Service, action.Kind, expectedActions[action.Service])
+		}
+	}
+
+	// Nothing must be written while planning
+	for _, name := range []string{"dockenv.yaml", ".env"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Build() should not create %s", name)
+		}
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, config.ComposeFileName))
+	if strings.Contains(string(content), "postgres") {
+		t.Errorf("Build() should not modify the compose file")
+	}
+}
+
+func TestBuildPlanStaleEnvKeys(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldCwd, _ := os.Getwd()
+	defer os.Chdir(oldCwd)
+	os.Chdir(tempDir)
+
+	t.Setenv("DOCKENV_CONFIG", filepath.Join(tempDir, "dockenv.yaml"))
+
+	current := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql", "redis"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{"DB_HOST": "127.0.0.1", "REDIS_HOST": "127.0.0.1"},
+		DataPath: tempDir,
+	}
+	if err := utils.SaveConfig(current); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(".env", []byte("APP_NAME=shop\nDB_HOST=127.0.0.1\nREDIS_HOST=127.0.0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	planned := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{"DB_HOST": "127.0.0.1"},
+		DataPath: tempDir,
+	}
+	p, err := plan.Build(planned, templates.Environment{})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	for _, file := range p.Files {
+		if file.Path != ".env" {
+			continue
+		}
+		// Keys the user added themselves are kept
+		if expected := "APP_NAME=shop\nDB_HOST=127.0.0.1\n"; string(file.After) != expected {
+			t.Errorf("planned .env = %q, want %q", file.After, expected)
+		}
+		if !strings.Contains(file.Diff(), "\n-REDIS_HOST=127.0.0.1\n") {
+			t.Errorf("diff should show REDIS_HOST as removed:\n%s", file.Diff())
+		}
+		return
+	}
+	t.Fatal("Build() planned no .env")
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+)
+
+func TestParseListenInodes(t *testing.T) {
+	input := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   999        0 41234 1 0000000000000000 100 0 0 10 0
+   1: 00000000:18EB 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 41300 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0CEA 0100007F:D431 01 00000000:00000000 00:00000000 00000000   999        0 41555 1 0000000000000000 20 4 30 10 -1
+`
+
+	tests := []struct {
+		port     int
+		expected []string
+	}{
+		{3306, []string{"41234"}},
+		{6379, []string{"41300"}},
+		{5432, nil},
+	}
+
+	for _, tt := range tests {
+		inodes, err := host.ParseListenInodes(strings.NewReader(input), tt.port)
+		if err != nil {
+			t.Fatalf("ParseListenInodes() error = %v", err)
+		}
+		if !reflect.DeepEqual(inodes, tt.expected) {
+			t.Errorf("ParseListenInodes(%d) = %v, want %v", tt.port, inodes, tt.expected)
+		}
+	}
+}
+
+func TestPortInUseAndFindFreePort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	if !host.PortInUse("127.0.0.1", port) {
+		t.Errorf("PortInUse(%d) = false for a listening port", port)
+	}
+
+	free, err := host.FindFreePort("127.0.0.1", port-1, map[int]bool{port + 1: true})
+	if err != nil {
+		t.Fatalf("FindFreePort() error = %v", err)
+	}
+	if free == port || free == port+1 {
+		t.Errorf("FindFreePort() = %d, should skip used port %d and reserved port %d", free, port, port+1)
+	}
+}
+
+func TestParsePublishedPorts(t *testing.T) {
+	output := "/dockenv-mysql\tshop\t3306 \n" +
+		"/dockenv-rabbitmq\tblog\t5672 15672 \n" +
+		"/standalone\t\t8080 \n" +
+		"/no-ports\tblog\t\n"
+
+	expected := []docker.PublishedPort{
+		{HostPort: 3306, Container: "dockenv-mysql", Project: "shop"},
+		{HostPort: 5672, Container: "dockenv-rabbitmq", Project: "blog"},
+		{HostPort: 15672, Container: "dockenv-rabbitmq", Project: "blog"},
+		{HostPort: 8080, Container: "standalone", Project: ""},
+	}
+
+	result := docker.ParsePublishedPorts(output)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ParsePublishedPorts() = %+v, want %+v", result, expected)
+	}
+}
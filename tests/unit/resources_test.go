@@ -0,0 +1,132 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/host"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    int64
+		expectError bool
+	}{
+		{"512m", 512 << 20, false},
+		{"2g", 2 << 30, false},
+		{"1.5GiB", 3 << 29, false},
+		{"256MB", 256 << 20, false},
+		{"1024", 1 << 30, false},
+		{"512", 512 << 20, false},
+		{"8388608b", 8 << 20, false},
+		{"4096k", 4 << 20, false},
+		{"3m", 0, true},
+		{"1024b", 0, true},
+		{"g", 0, true},
+		{"5mm", 0, true},
+		{"", 0, true},
+		{"lots", 0, true},
+		{"-1g", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := config.ParseMemory(tt.value)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParseMemory(%q) expected error, got nil", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseMemory(%q) unexpected error: %v", tt.value, err)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseMemory(%q) = %d, want %d", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatMemory(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{2 << 30, "2g"},
+		{3 << 29, "1536m"},
+		{4 << 20, "4m"},
+		{512 << 10, "512k"},
+		{1536, "2k"},
+		{100, "1k"},
+		{0, "1k"},
+	}
+
+	for _, tt := range tests {
+		if result := config.FormatMemory(tt.bytes); result != tt.expected {
+			t.Errorf("FormatMemory(%d) = %q, want %q", tt.bytes, result, tt.expected)
+		}
+	}
+}
+
+func TestParseMemTotal(t *testing.T) {
+	input := "MemTotal:       16318480 kB\nMemFree:         1234567 kB\n"
+
+	total, err := host.ParseMemTotal(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseMemTotal() error = %v", err)
+	}
+	if total != 16318480*1024 {
+		t.Errorf("ParseMemTotal() = %d, want %d", total, 16318480*1024)
+	}
+
+	if _, err := host.ParseMemTotal(strings.NewReader("MemFree: 1 kB\n")); err == nil {
+		t.Errorf("ParseMemTotal() expected error when MemTotal is missing")
+	}
+}
+
+func TestRenderDockerComposeWithResources(t *testing.T) {
+	tempDir := t.TempDir()
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"elasticsearch", "mysql", "redis"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{},
+		Resources: map[string]config.Resources{
+			"elasticsearch": {Memory: "2g"},
+			"mysql":         {Memory: "1g", CPUs: 1.5},
+		},
+		DataPath: tempDir,
+	}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	contentStr := string(content)
+
+	expectedStrings := []string{
+		"ES_JAVA_OPTS=-Xms1g -Xmx1g",
+		"memory: 2g",
+		"command: --innodb-buffer-pool-size=512m",
+		"memory: 1g",
+		`cpus: "1.5"`,
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(contentStr, expected) {
+			t.Errorf("Rendered compose file should contain '%s'", expected)
+		}
+	}
+
+	if strings.Count(contentStr, "deploy:") != 2 {
+		t.Errorf("Only services with limits should get a deploy section:\n%s", contentStr)
+	}
+
+	cfg.Resources["redis"] = config.Resources{Memory: "plenty"}
+	if _, err := templates.RenderDockerCompose(cfg, templates.Environment{}); err == nil {
+		t.Errorf("RenderDockerCompose() expected error for invalid memory limit")
+	}
+}
@@ -0,0 +1,127 @@
+//go:build !windows
+
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/runner"
+)
+
+func TestRunnerTimeout(t *testing.T) {
+	ctx, cancel := runner.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := runner.Command(ctx, "sleep", "10").Run()
+
+	var timeout *runner.TimeoutError
+	if !errors.As(err, &timeout) || timeout.Timeout != 100*time.Millisecond {
+		t.Fatalf("Err() = %v, want a TimeoutError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("child was not stopped on timeout, took %s", elapsed)
+	}
+}
+
+func TestRunnerNoTimeout(t *testing.T) {
+	ctx, cancel := runner.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("WithTimeout(0) should not set a deadline")
+	}
+
+	err := runner.Command(ctx, "false").Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Errorf("Err() = %v, want the command's own failure", err)
+	}
+}
+
+func TestRunnerForwardsSignal(t *testing.T) {
+	ctx, stop := runner.WithSignals(context.Background())
+	defer stop()
+
+	// The shell reports the signal that ended it in its exit status
+	cmd := runner.Command(ctx, "sh", "-c", `trap 'exit 42' TERM; sleep 10 & wait`)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cmd.Wait()
+	var interrupted *runner.InterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Signal != syscall.SIGTERM {
+		t.Fatalf("Err() = %v, want an InterruptedError for SIGTERM", err)
+	}
+	if code := cmd.ProcessState.ExitCode(); code != 42 {
+		t.Errorf("child exit code = %d, want 42 from its TERM trap", code)
+	}
+}
+
+func TestRunnerRecord(t *testing.T) {
+	var record bytes.Buffer
+	runner.Record(&record)
+	defer runner.Record(nil)
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	out, err := runner.Command(context.Background(), "touch", marker).Output()
+	if err != nil || len(out) != 0 {
+		t.Fatalf("Output() = %q, %v, want no output and no error", out, err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("recorded command was run")
+	}
+
+	if err := runner.Command(context.Background(), "sh", "-c", "echo it's $HOME").Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	expected := "[dry-run] touch " + marker + "\n" +
+		`[dry-run] sh -c 'echo it'\''s $HOME'` + "\n"
+	if record.String() != expected {
+		t.Errorf("record = %q, want %q", record.String(), expected)
+	}
+}
+
+func TestRecordRuntime(t *testing.T) {
+	var record bytes.Buffer
+	docker.SetRuntime(context.Background(), docker.RecordRuntime)
+	runner.Record(&record)
+	defer func() {
+		runner.Record(nil)
+		docker.SetRuntime(context.Background(), "")
+	}()
+
+	info, err := docker.CheckDocker(context.Background())
+	if err != nil || !info.IsReady() || info.Runtime != "compose" {
+		t.Fatalf("CheckDocker() = %+v, %v, want a ready compose runtime", info, err)
+	}
+
+	if err := docker.ComposeUp(context.Background(), "docker-compose.dockenv.yaml", "mysql"); err != nil {
+		t.Fatalf("ComposeUp() error = %v", err)
+	}
+	if err := docker.RunCompose(context.Background(), "-f", "docker-compose.dockenv.yaml", "down", "--rmi", "all"); err != nil {
+		t.Fatalf("RunCompose() error = %v", err)
+	}
+
+	expected := "[dry-run] docker compose -f docker-compose.dockenv.yaml up -d mysql\n" +
+		"[dry-run] docker compose -f docker-compose.dockenv.yaml down --rmi all\n"
+	if record.String() != expected {
+		t.Errorf("record = %q, want %q", record.String(), expected)
+	}
+}
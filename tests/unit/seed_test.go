@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/seed"
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/templates"
+)
+
+func TestSeedCommand(t *testing.T) {
+	postgres, _ := seed.LookupMethod("postgres")
+	redis, _ := seed.LookupMethod("redis")
+
+	tests := []struct {
+		method   seed.Method
+		file     string
+		expected string
+		found    bool
+	}{
+		{method: postgres, file: "001-schema.sql", expected: postgres.Commands[".sql"], found: true},
+		{method: postgres, file: "002-data.sql.gz", expected: postgres.Commands[".sql.gz"], found: true},
+		{method: postgres, file: "003-users.sh", expected: postgres.Commands[".sh"], found: true},
+		{method: postgres, file: "fixtures.js"},
+		{method: redis, file: "cache.redis", expected: redis.Commands[".redis"], found: true},
+		{method: redis, file: "schema.sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			command, found := tt.method.Command(tt.file)
+			if found != tt.found || command != tt.expected {
+				t.Errorf("Command(%s) = %q, %v, want %q, %v", tt.file, command, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+func TestSeedScripts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"002-data.sql.gz", "001-schema.sql", "README.md", ".hidden.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.sql"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	method, _ := seed.LookupMethod("postgres")
+	scripts, skipped, err := seed.Scripts(dir, method)
+	if err != nil {
+		t.Fatalf("Scripts() error = %v", err)
+	}
+
+	var names []string
+	for _, script := range scripts {
+		names = append(names, script.Name)
+	}
+	if expected := []string{"001-schema.sql", "002-data.sql.gz"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Scripts() = %v, want %v", names, expected)
+	}
+	if scripts[1].Command != method.Commands[".sql.gz"] {
+		t.Errorf("Scripts() should run compressed SQL with %q, got %q", method.Commands[".sql.gz"], scripts[1].Command)
+	}
+	if !reflect.DeepEqual(skipped, []string{"README.md"}) {
+		t.Errorf("Scripts() skipped = %v, want [README.md]", skipped)
+	}
+
+	if scripts, _, err := seed.Scripts(filepath.Join(dir, "missing"), method); err != nil || len(scripts) != 0 {
+		t.Errorf("Scripts() of a missing directory = %v, %v, want none", scripts, err)
+	}
+}
+
+func TestSeedMethods(t *testing.T) {
+	for _, name := range services.GetServiceNames() {
+		method, exists := seed.LookupMethod(name)
+		if !exists {
+			t.Errorf("no init script method for %s", name)
+			continue
+		}
+		if method.DataDir == "" || method.Commands[".sh"] == "" {
+			t.Errorf("init script method for %s is incomplete: %+v", name, method)
+		}
+	}
+}
+
+func TestRenderDockerComposeInitMount(t *testing.T) {
+	tempDir := t.TempDir()
+	oldCwd, _ := os.Getwd()
+	defer os.Chdir(oldCwd)
+	os.Chdir(tempDir)
+
+	for _, name := range []string{"postgres", "redis"} {
+		if err := os.MkdirAll(seed.ServiceDir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"postgres", "redis", "mysql"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{},
+		DataPath: tempDir,
+	}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{InitMounts: seed.InitMounts(cfg.Services, false)})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+
+	// Only PostgreSQL has scripts and an image that runs them; dockenv runs
+	// those of Redis
+	mount := "- ./.dockenv/init/postgres:/docker-entrypoint-initdb.d:ro\n"
+	if !strings.Contains(string(content), mount) {
+		t.Errorf("Rendered compose file should contain %q.\nActual content:\n%s", mount, content)
+	}
+	if count := strings.Count(string(content), seed.InitDBDir); count != 1 {
+		t.Errorf("Rendered compose file should mount init scripts once, got %d.\nActual content:\n%s", count, content)
+	}
+}
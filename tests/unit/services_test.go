@@ -76,7 +76,7 @@ func TestGetService(t *testing.T) {
 				if service.Name != tt.serviceName {
 					t.Errorf("GetService(%v) name = %v, want %v", tt.serviceName, service.Name, tt.serviceName)
 				}
-				if service.DefaultPort == 0 {
+				if len(service.Ports) == 0 {
 					t.Errorf("GetService(%v) should have a default port", tt.serviceName)
 				}
 				if service.Template == "" {
@@ -99,8 +99,16 @@ func TestServiceStructure(t *testing.T) {
 			if service.Description == "" {
 				t.Errorf("Service %s: Description should not be empty", serviceName)
 			}
-			if service.DefaultPort == 0 {
-				t.Errorf("Service %s: DefaultPort should be set", serviceName)
+			if len(service.Ports) == 0 {
+				t.Errorf("Service %s: Ports should be set", serviceName)
+			}
+			if service.PrimaryPort().Optional {
+				t.Errorf("Service %s: primary port must not be optional", serviceName)
+			}
+			for _, spec := range service.Ports {
+				if spec.Name == "" || spec.ContainerPort == 0 || spec.DefaultPort == 0 {
+					t.Errorf("Service %s: incomplete port spec %+v", serviceName, spec)
+				}
 			}
 			if service.Template == "" {
 				t.Errorf("Service %s: Template should not be empty", serviceName)
@@ -159,3 +167,196 @@ func TestGetProfileNames(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePortSpec(t *testing.T) {
+	tests := []struct {
+		spec        string
+		service     string
+		portName    string
+		port        int
+		expectError bool
+	}{
+		{"mysql:3307", "mysql", "mysql", 3307, false},
+		{"rabbitmq:5673", "rabbitmq", "amqp", 5673, false},
+		{"rabbitmq.management:25672", "rabbitmq", "management", 25672, false},
+		{"elasticsearch.transport:9301", "elasticsearch", "transport", 9301, false},
+		{"rabbitmq.unknown:1234", "", "", 0, true},
+		{"unknown:1234", "", "", 0, true},
+		{"mysql:abc", "", "", 0, true},
+		{"mysql:70000", "", "", 0, true},
+		{"mysql", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			service, portName, port, err := services.ParsePortSpec(tt.spec)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("ParsePortSpec(%q) expected error, got nil", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePortSpec(%q) unexpected error: %v", tt.spec, err)
+			}
+			if service != tt.service || portName != tt.portName || port != tt.port {
+				t.Errorf("ParsePortSpec(%q) = %s, %s, %d, want %s, %s, %d",
+					tt.spec, service, portName, port, tt.service, tt.portName, tt.port)
+			}
+		})
+	}
+}
+
+func TestServiceHostPorts(t *testing.T) {
+	rabbitmq, _ := services.GetService("rabbitmq")
+	hostPorts := rabbitmq.HostPorts(map[string]int{"management": 25672})
+	if hostPorts["amqp"] != 5672 || hostPorts["management"] != 25672 {
+		t.Errorf("HostPorts() = %v, want amqp 5672 and management 25672", hostPorts)
+	}
+
+	envVars := rabbitmq.PortEnvVars(hostPorts)
+	if envVars["RABBITMQ_MANAGEMENT_PORT"] != "25672" {
+		t.Errorf("PortEnvVars() = %v, want RABBITMQ_MANAGEMENT_PORT=25672", envVars)
+	}
+
+	elasticsearch, _ := services.GetService("elasticsearch")
+	if _, exists := elasticsearch.HostPorts(nil)["transport"]; exists {
+		t.Errorf("Optional transport port should only be published when configured")
+	}
+	if elasticsearch.HostPorts(map[string]int{"transport": 9301})["transport"] != 9301 {
+		t.Errorf("Configured optional port should be published")
+	}
+}
+
+func TestServiceDataDirs(t *testing.T) {
+	service, _ := services.GetService("kafka")
+	dirs := service.DataDirs()
+	if len(dirs) != 2 || dirs[0] != "kafka" || dirs[1] != "zookeeper" {
+		t.Errorf("DataDirs() = %v, want [kafka zookeeper]", dirs)
+	}
+
+	for name, service := range services.AvailableServices {
+		if service.DataOwner == "" {
+			t.Errorf("service %s has no data owner", name)
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		tag    string
+		parts  []int
+		suffix string
+		ok     bool
+	}{
+		{tag: "15", parts: []int{15}, ok: true},
+		{tag: "8.11.0", parts: []int{8, 11, 0}, ok: true},
+		{tag: "7-alpine", parts: []int{7}, suffix: "-alpine", ok: true},
+		{tag: "3.13-management", parts: []int{3, 13}, suffix: "-management", ok: true},
+		{tag: "latest"},
+		{tag: "8.x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			version, ok := services.ParseVersion(tt.tag)
+			if ok != tt.ok {
+				t.Fatalf("ParseVersion(%q) ok = %v, want %v", tt.tag, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if len(version.Parts) != len(tt.parts) || version.Suffix != tt.suffix {
+				t.Fatalf("ParseVersion(%q) = %v %q, want %v %q", tt.tag, version.Parts, version.Suffix, tt.parts, tt.suffix)
+			}
+			for i := range tt.parts {
+				if version.Parts[i] != tt.parts[i] {
+					t.Errorf("ParseVersion(%q) = %v, want %v", tt.tag, version.Parts, tt.parts)
+				}
+			}
+		})
+	}
+}
+
+func TestLatestVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		tags     []string
+		expected [2]string
+	}{
+		{
+			name:     "patch within major",
+			current:  "15",
+			tags:     []string{"14", "15", "15.6", "16", "17"},
+			expected: [2]string{"15.6", "17"},
+		},
+		{
+			name:     "floating tag preferred among equal versions",
+			current:  "8.0",
+			tags:     []string{"8.4.0", "8.4", "9", "9.0"},
+			expected: [2]string{"8.4", "9"},
+		},
+		{
+			name:     "variants do not mix",
+			current:  "7-alpine",
+			tags:     []string{"7.4", "7.2-alpine", "8-alpine", "8-bookworm"},
+			expected: [2]string{"7.2-alpine", "8-alpine"},
+		},
+		{
+			name:     "only a new major",
+			current:  "3-management",
+			tags:     []string{"3-management", "4.0-management"},
+			expected: [2]string{"", "4.0-management"},
+		},
+		{
+			name:    "up to date",
+			current: "17",
+			tags:    []string{"15", "16", "17"},
+		},
+		{
+			name:    "floating current tag",
+			current: "latest",
+			tags:    []string{"7.5.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wanted, latest := services.LatestVersions(tt.current, tt.tags)
+			if wanted != tt.expected[0] || latest != tt.expected[1] {
+				t.Errorf("LatestVersions(%q) = %q, %q, want %q, %q", tt.current, wanted, latest, tt.expected[0], tt.expected[1])
+			}
+		})
+	}
+}
+
+func TestServiceVersions(t *testing.T) {
+	for name, service := range services.AvailableServices {
+		if service.Image == "" {
+			t.Errorf("service %s has no image", name)
+		}
+		for _, tag := range service.Versions {
+			if _, ok := services.ParseVersion(tag); !ok {
+				t.Errorf("service %s lists unversioned tag %q", name, tag)
+			}
+		}
+		for _, migration := range service.Migrations {
+			if migration.To <= migration.From || migration.Note == "" {
+				t.Errorf("service %s has invalid migration %+v", name, migration)
+			}
+		}
+	}
+
+	redis, _ := services.GetService("redis")
+	if redis.ResolveVersion("") != "7-alpine" || redis.ResolveVersion("7.2-alpine") != "7.2-alpine" {
+		t.Errorf("ResolveVersion() should prefer the configured tag over the catalog default")
+	}
+	if _, found := redis.FindMigration(7, 8); !found {
+		t.Errorf("FindMigration(7, 8) should find the Redis migration")
+	}
+	postgres, _ := services.GetService("postgres")
+	if _, found := postgres.FindMigration(15, 16); found {
+		t.Errorf("FindMigration(15, 16) should not find a PostgreSQL migration")
+	}
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mohammed-bageri/dockenv/internal/services"
+	"github.com/mohammed-bageri/dockenv/internal/shell"
+)
+
+func TestShellConnect(t *testing.T) {
+	client, _ := shell.LookupClient("postgres")
+	service, _ := services.GetService("postgres")
+
+	conn := client.Connect(map[string]string{"DB_DATABASE": "shop", "DB_PASSWORD": ""}, service.EnvVars)
+	expected := shell.Connection{Database: "shop", Username: "dockenv", Password: ""}
+	if conn != expected {
+		t.Errorf("Connect() = %+v, want %+v", conn, expected)
+	}
+	if env := client.Env(conn); len(env) != 0 {
+		t.Errorf("Env() without a password = %v, want none", env)
+	}
+
+	conn.Password = "secret"
+	if env := client.Env(conn); !reflect.DeepEqual(env, map[string]string{"PGPASSWORD": "secret"}) {
+		t.Errorf("Env() = %v, want the password in PGPASSWORD", env)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	conn := shell.Connection{Database: "dockenv", Username: "dockenv", Password: "password"}
+
+	tests := []struct {
+		service     string
+		args        []string
+		interactive bool
+		expected    string
+	}{
+		{service: "postgres", interactive: true, expected: "psql -U dockenv -d dockenv"},
+		{service: "postgres", args: []string{"-c", "SELECT 1"}, expected: "psql -U dockenv -d dockenv -v ON_ERROR_STOP=1 -c SELECT 1"},
+		{service: "mysql", args: []string{"-e", "SHOW TABLES"}, interactive: true, expected: "mysql -u dockenv dockenv -e SHOW TABLES"},
+		{service: "redis", args: []string{"PING"}, expected: "redis-cli PING"},
+		{service: "kafka", interactive: true, expected: "kafka-topics --bootstrap-server localhost:9092 --list"},
+		{service: "kafka", args: []string{"orders"}, interactive: true, expected: "kafka-console-consumer --bootstrap-server localhost:9092 --topic orders --from-beginning"},
+		{service: "kafka", args: []string{"orders"}, expected: "kafka-console-producer --bootstrap-server localhost:9092 --topic orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service+" "+strings.Join(tt.args, " "), func(t *testing.T) {
+			client, exists := shell.LookupClient(tt.service)
+			if !exists {
+				t.Fatalf("no client for %s", tt.service)
+			}
+			if command := strings.Join(client.Command(conn, tt.args, tt.interactive), " "); command != tt.expected {
+				t.Errorf("Command() = %q, want %q", command, tt.expected)
+			}
+		})
+	}
+}
+
+func TestShellClientsKeepPasswordsOffCommandLine(t *testing.T) {
+	conn := shell.Connection{Database: "dockenv", Username: "dockenv", Password: "s3cret"}
+	for name, client := range shell.Clients {
+		if _, exists := services.GetService(name); !exists {
+			t.Errorf("client for unknown service %s", name)
+		}
+		for _, interactive := range []bool{true, false} {
+			command := strings.Join(client.Command(conn, nil, interactive), " ")
+			if strings.Contains(command, conn.Password) {
+				t.Errorf("Command() of %s contains the password: %s", name, command)
+			}
+		}
+		if client.PasswordVar != "" && client.PasswordEnv == "" {
+			t.Errorf("client for %s has no way to pass its password", name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mohammed-bageri/dockenv/internal/snapshot"
+)
+
+func TestSnapshotValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "clean-seed", valid: true},
+		{name: "before_migration.42", valid: true},
+		{name: ""},
+		{name: ".hidden"},
+		{name: "../data"},
+		{name: "a/b"},
+		{name: "with space"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := snapshot.ValidateName(tt.name)
+			if tt.valid && err != nil {
+				t.Errorf("ValidateName(%q) unexpected error: %v", tt.name, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("ValidateName(%q) expected error", tt.name)
+			}
+		})
+	}
+}
+
+func TestSnapshotManifests(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "snapshots")
+
+	if manifests, err := snapshot.List(root); err != nil || len(manifests) != 0 {
+		t.Fatalf("List() of a missing directory = %v, %v, want none", manifests, err)
+	}
+	if _, err := snapshot.Load(root, "seed"); !os.IsNotExist(err) {
+		t.Errorf("Load() of a missing snapshot error = %v, want not exist", err)
+	}
+
+	later := &snapshot.Manifest{
+		Version:  snapshot.ManifestVersion,
+		Name:     "migrated",
+		Created:  time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC),
+		Project:  "shop",
+		Services: []string{"postgres"},
+		Versions: map[string]string{"postgres": "15"},
+		Dirs:     []string{"postgres"},
+	}
+	earlier := &snapshot.Manifest{
+		Version:  snapshot.ManifestVersion,
+		Name:     "seed",
+		Created:  time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		Project:  "shop",
+		Services: []string{"postgres", "kafka"},
+		Versions: map[string]string{"postgres": "15", "kafka": "latest"},
+		Dirs:     []string{"postgres", "kafka", "zookeeper"},
+	}
+	for _, manifest := range []*snapshot.Manifest{later, earlier} {
+		if err := manifest.Save(root); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// An interrupted snapshot has data but no manifest
+	if err := os.MkdirAll(filepath.Join(root, "interrupted", "postgres"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := snapshot.Load(root, "seed")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, earlier) {
+		t.Errorf("Load() = %+v, want %+v", loaded, earlier)
+	}
+
+	manifests, err := snapshot.List(root)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(manifests) != 2 || manifests[0].Name != "seed" || manifests[1].Name != "migrated" {
+		t.Errorf("List() should return seed and migrated, oldest first; got %d snapshots", len(manifests))
+	}
+}
+
+func TestSnapshotBranchName(t *testing.T) {
+	if name := snapshot.BranchName("main"); name != "branch-main" {
+		t.Errorf("BranchName(main) = %s, want branch-main", name)
+	}
+
+	slash := snapshot.BranchName("feature/login")
+	dash := snapshot.BranchName("feature-login")
+	if slash == dash {
+		t.Errorf("BranchName() should tell feature/login and feature-login apart, both are %s", slash)
+	}
+	for _, name := range []string{slash, dash} {
+		if err := snapshot.ValidateName(name); err != nil {
+			t.Errorf("BranchName() returned invalid name %s: %v", name, err)
+		}
+	}
+}
+
+func TestSnapshotLiveBranch(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "snapshots")
+
+	if live, err := snapshot.LiveBranch(root); err != nil || live != "" {
+		t.Errorf("LiveBranch() before any sync = %q, %v, want none", live, err)
+	}
+	if err := snapshot.SetLiveBranch(root, "feature/login"); err != nil {
+		t.Fatalf("SetLiveBranch() error = %v", err)
+	}
+	if live, err := snapshot.LiveBranch(root); err != nil || live != "feature/login" {
+		t.Errorf("LiveBranch() = %q, %v, want feature/login", live, err)
+	}
+
+	// The state file is not a snapshot
+	if manifests, err := snapshot.List(root); err != nil || len(manifests) != 0 {
+		t.Errorf("List() = %v, %v, want none", manifests, err)
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"testing"
 
 	"github.com/mohammed-bageri/dockenv/internal/config"
+	"github.com/mohammed-bageri/dockenv/internal/docker"
+	"github.com/mohammed-bageri/dockenv/internal/services"
 	"github.com/mohammed-bageri/dockenv/internal/templates"
 	"github.com/mohammed-bageri/dockenv/internal/utils"
+
+	yaml "gopkg.in/yaml.v3"
 )
 
 func TestGenerateDockerCompose(t *testing.T) {
@@ -19,9 +23,9 @@ func TestGenerateDockerCompose(t *testing.T) {
 	cfg := &config.Config{
 		Version:  "1.0",
 		Services: []string{"mysql", "redis"},
-		Ports: map[string]int{
-			"mysql": 3306,
-			"redis": 6379,
+		Ports: map[string]config.ServicePorts{
+			"mysql": {"mysql": 3306},
+			"redis": {"redis": 6379},
 		},
 		Env: map[string]string{
 			"DB_HOST": "127.0.0.1",
@@ -38,7 +42,7 @@ func TestGenerateDockerCompose(t *testing.T) {
 	os.Chdir(tempDir)
 
 	// Use embedded templates instead of external files
-	err := templates.GenerateDockerComposeEmbedded(cfg)
+	err := templates.GenerateDockerComposeEmbedded(cfg, templates.Environment{})
 	if err != nil {
 		t.Errorf("GenerateDockerComposeEmbedded() error = %v, want nil", err)
 		return
@@ -80,6 +84,7 @@ func TestCreateEnvFile(t *testing.T) {
 		name            string
 		initialContent  string
 		newVars         map[string]string
+		stale           []string
 		expectedContent string
 		description     string
 	}{
@@ -109,6 +114,18 @@ EXISTING_VAR=value
 			expectedContent: "", // Will check individual variables and comments
 			description:     "should preserve existing content and comments while merging new variables",
 		},
+		{
+			name: "drop_stale_keys",
+			initialContent: `DB_HOST=127.0.0.1
+REDIS_HOST=127.0.0.1
+REDIS_PORT=6379
+EXISTING_VAR=value
+`,
+			newVars:         map[string]string{"DB_HOST": "127.0.0.1"},
+			stale:           []string{"REDIS_HOST", "REDIS_PORT", "DB_HOST"},
+			expectedContent: "DB_HOST=127.0.0.1\nEXISTING_VAR=value\n",
+			description:     "should drop stale keys, except those still set, and keep the user's own",
+		},
 	}
 
 	for _, tt := range tests {
@@ -130,7 +147,7 @@ EXISTING_VAR=value
 			}
 
 			// Call CreateEnvFile
-			err := utils.CreateEnvFile(tt.newVars)
+			err := utils.CreateEnvFile(tt.newVars, tt.stale)
 			if err != nil {
 				t.Errorf("CreateEnvFile() error = %v, want nil", err)
 				return
@@ -176,6 +193,8 @@ EXISTING_VAR=value
 						t.Errorf("Merged env file should contain '%s'.\nActual content:\n%s", expected, contentStr)
 					}
 				}
+			} else if contentStr != tt.expectedContent {
+				t.Errorf("Env file = %q, want %q", contentStr, tt.expectedContent)
 			}
 		})
 	}
@@ -221,14 +240,14 @@ func TestGetEmbeddedTemplate(t *testing.T) {
 	}
 }
 
-func TestGenerateDockerComposeWithInvalidService(t *testing.T) {
+func TestRenderDockerComposeWithInvalidService(t *testing.T) {
 	tempDir := t.TempDir()
 
 	// Create a config with invalid service
 	cfg := &config.Config{
 		Version:  "1.0",
 		Services: []string{"invalid_service"},
-		Ports:    map[string]int{},
+		Ports:    map[string]config.ServicePorts{},
 		Env:      map[string]string{},
 		Volumes:  map[string]string{},
 		DataPath: tempDir,
@@ -239,8 +258,374 @@ func TestGenerateDockerComposeWithInvalidService(t *testing.T) {
 	defer os.Chdir(oldCwd)
 	os.Chdir(tempDir)
 
-	err := templates.GenerateDockerCompose(cfg)
-	if err == nil {
-		t.Errorf("GenerateDockerCompose() expected error for invalid service, got nil")
+	if _, err := templates.RenderDockerCompose(cfg, templates.Environment{}); err == nil {
+		t.Errorf("RenderDockerCompose() expected error for invalid service, got nil")
+	}
+}
+
+func TestRenderDockerComposeWithCustomTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldCwd, _ := os.Getwd()
+	defer os.Chdir(oldCwd)
+	os.Chdir(tempDir)
+
+	os.Setenv("COMPOSE_PROJECT_NAME", "shop")
+	defer os.Unsetenv("COMPOSE_PROJECT_NAME")
+
+	customTemplate := `  redis:
+    image: redis:{{.Version}}
+    container_name: {{projectName}}-{{.Name}}
+    environment:
+      REDIS_PASSWORD: {{secret "redis" | quote}}
+      LOG_LEVEL: {{env "LOG_LEVEL" "warning"}}
+      DB_URL: {{quote (printf "mysql://%s:%d" hostIP (port "mysql"))}}
+    ports:
+      - "{{.Port}}:6379"`
+
+	if err := os.MkdirAll(config.TemplatesDir, 0755); err != nil {
+		t.Fatalf("Failed to create templates dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(config.TemplatesDir, "redis.yaml"), []byte(customTemplate), 0644); err != nil {
+		t.Fatalf("Failed to write custom template: %v", err)
+	}
+
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql", "redis"},
+		Ports:    map[string]config.ServicePorts{"mysql": {"mysql": 3307}},
+		Env:      map[string]string{},
+		Secrets:  map[string]string{"redis": "s3cret"},
+		DataPath: tempDir,
+	}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	contentStr := string(content)
+
+	expectedStrings := []string{
+		"image: redis:7-alpine",
+		"container_name: shop-redis",
+		`REDIS_PASSWORD: "s3cret"`,
+		"LOG_LEVEL: warning",
+		`DB_URL: "mysql://127.0.0.1:3307"`,
+		`- "6379:6379"`,
+		"container_name: dockenv-mysql",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(contentStr, expected) {
+			t.Errorf("Rendered compose file should contain '%s'.\nActual content:\n%s", expected, contentStr)
+		}
+	}
+
+	delete(cfg.Secrets, "redis")
+	if _, err := templates.RenderDockerCompose(cfg, templates.Environment{}); err == nil {
+		t.Errorf("RenderDockerCompose() expected error for missing secret")
+	}
+}
+
+func TestRenderDockerComposeWithLegacyTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldCwd, _ := os.Getwd()
+	defer os.Chdir(oldCwd)
+	os.Chdir(tempDir)
+
+	if err := os.MkdirAll(config.LegacyTemplatesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacyTemplate := "  redis:\n    image: redis:{{.Version}}\n    container_name: legacy-redis\n"
+	if err := os.WriteFile(filepath.Join(config.LegacyTemplatesDir, "redis.yaml"), []byte(legacyTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"mysql", "redis"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{},
+		DataPath: tempDir,
+	}
+
+	// The deprecated directory is still read, and reported
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	if !strings.Contains(string(content), "container_name: legacy-redis") {
+		t.Errorf("Rendered compose file should use the legacy template.\nActual content:\n%s", content)
+	}
+	legacy := templates.LegacyTemplates(cfg)
+	if len(legacy) != 1 || legacy[0] != filepath.Join(config.LegacyTemplatesDir, "redis.yaml") {
+		t.Errorf("LegacyTemplates() = %v, want the redis template", legacy)
+	}
+
+	// A template in .dockenv/templates takes precedence
+	if err := os.MkdirAll(config.TemplatesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	customTemplate := "  redis:\n    image: redis:{{.Version}}\n    container_name: custom-redis\n"
+	if err := os.WriteFile(filepath.Join(config.TemplatesDir, "redis.yaml"), []byte(customTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err = templates.RenderDockerCompose(cfg, templates.Environment{})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	if !strings.Contains(string(content), "container_name: custom-redis") {
+		t.Errorf("Rendered compose file should prefer .dockenv/templates.\nActual content:\n%s", content)
+	}
+	if legacy := templates.LegacyTemplates(cfg); len(legacy) != 0 {
+		t.Errorf("LegacyTemplates() = %v, want none once the template moved", legacy)
+	}
+}
+
+func TestRenderDockerComposeQuote(t *testing.T) {
+	tempDir := t.TempDir()
+
+	oldCwd, _ := os.Getwd()
+	defer os.Chdir(oldCwd)
+	os.Chdir(tempDir)
+
+	customTemplate := `  redis:
+    image: redis:{{.Version}}
+    environment:
+      VALUE: {{env "VALUE" | quote}}`
+	if err := os.MkdirAll(config.TemplatesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(config.TemplatesDir, "redis.yaml"), []byte(customTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value  string
+		quoted string
+	}{
+		{value: "plain", quoted: `"plain"`},
+		{value: `C:\data "x"`, quoted: `"C:\\data \"x\""`},
+		{value: "café ☕ 🐳", quoted: `"café ☕ 🐳"`},
+		{value: "line\nnext\ttab", quoted: `"line\nnext\ttab"`},
+		{value: "bell\a del\x7f next\u2028line", quoted: `"bell\x07 del\x7f next\u2028line"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg := &config.Config{
+				Version:  "1.0",
+				Services: []string{"redis"},
+				Ports:    map[string]config.ServicePorts{},
+				Env:      map[string]string{"VALUE": tt.value},
+				DataPath: tempDir,
+			}
+			content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+			if err != nil {
+				t.Fatalf("RenderDockerCompose() error = %v", err)
+			}
+			if !strings.Contains(string(content), "VALUE: "+tt.quoted+"\n") {
+				t.Errorf("quote(%q) should render %s.\nActual content:\n%s", tt.value, tt.quoted, content)
+			}
+
+			var compose struct {
+				Services map[string]struct {
+					Environment map[string]string `yaml:"environment"`
+				} `yaml:"services"`
+			}
+			if err := yaml.Unmarshal(content, &compose); err != nil {
+				t.Fatalf("rendered compose file is not valid YAML: %v", err)
+			}
+			if value := compose.Services["redis"].Environment["VALUE"]; value != tt.value {
+				t.Errorf("quoted value reads back as %q, want %q", value, tt.value)
+			}
+		})
+	}
+}
+
+func TestRenderDockerComposeWithNamedPorts(t *testing.T) {
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"rabbitmq", "elasticsearch"},
+		Ports: map[string]config.ServicePorts{
+			"rabbitmq":      {"management": 25672},
+			"elasticsearch": {"http": 9201},
+		},
+		Env:      map[string]string{},
+		DataPath: t.TempDir(),
+	}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	contentStr := string(content)
+
+	expectedStrings := []string{
+		`- "127.0.0.1:5672:5672"`,
+		`- "127.0.0.1:25672:15672"`,
+		`- "127.0.0.1:9201:9200"`,
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(contentStr, expected) {
+			t.Errorf("Rendered compose file should contain '%s'.\nActual content:\n%s", expected, contentStr)
+		}
+	}
+
+	// The optional transport port is not published unless configured
+	if strings.Contains(contentStr, ":9300") {
+		t.Errorf("Rendered compose file should not publish the transport port:\n%s", contentStr)
+	}
+}
+
+func TestRenderDockerComposeBindAddress(t *testing.T) {
+	tests := []struct {
+		name          string
+		bindAddress   string
+		bindAddresses map[string]string
+		expected      []string
+		expectError   bool
+	}{
+		{
+			name:     "loopback by default",
+			expected: []string{`- "127.0.0.1:3306:3306"`, `- "127.0.0.1:6379:6379"`},
+		},
+		{
+			name:        "project exposed",
+			bindAddress: "0.0.0.0",
+			expected:    []string{`- "0.0.0.0:3306:3306"`, `- "0.0.0.0:6379:6379"`},
+		},
+		{
+			name:          "service override",
+			bindAddresses: map[string]string{"redis": "::1"},
+			expected:      []string{`- "127.0.0.1:3306:3306"`, `- "[::1]:6379:6379"`},
+		},
+		{
+			name:        "invalid address",
+			bindAddress: "localhost",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Version:       "1.0",
+				Services:      []string{"mysql", "redis"},
+				Ports:         map[string]config.ServicePorts{},
+				Env:           map[string]string{},
+				BindAddress:   tt.bindAddress,
+				BindAddresses: tt.bindAddresses,
+				DataPath:      t.TempDir(),
+			}
+
+			content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("RenderDockerCompose() expected error for bind address %q", tt.bindAddress)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderDockerCompose() error = %v", err)
+			}
+
+			for _, expected := range tt.expected {
+				if !strings.Contains(string(content), expected) {
+					t.Errorf("Rendered compose file should contain '%s'.\nActual content:\n%s", expected, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderDockerComposePodmanMounts(t *testing.T) {
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"kafka"},
+		Ports:    map[string]config.ServicePorts{},
+		Env:      map[string]string{},
+		Runtime:  "podman-compose",
+		DataPath: "/data",
+	}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{Podman: docker.EngineFor(cfg.Runtime) == "podman"})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"- /data/zookeeper:/var/lib/zookeeper/data:Z",
+		"- /data/kafka:/var/lib/kafka/data:Z",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Rendered compose file should contain '%s'.\nActual content:\n%s", expected, content)
+		}
+	}
+
+	cfg.Runtime = "docker-compose"
+	content, err = templates.RenderDockerCompose(cfg, templates.Environment{Podman: docker.EngineFor(cfg.Runtime) == "podman"})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+	if strings.Contains(string(content), ":Z") {
+		t.Errorf("Docker bind mounts should not be relabelled.\nActual content:\n%s", content)
+	}
+}
+
+func TestRenderDockerComposeVersion(t *testing.T) {
+	cfg := &config.Config{
+		Version:  "1.0",
+		Services: []string{"postgres", "redis"},
+		Ports:    map[string]config.ServicePorts{},
+		Versions: map[string]string{"postgres": "16"},
+		Env:      map[string]string{},
+		DataPath: t.TempDir(),
+	}
+
+	content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+	if err != nil {
+		t.Fatalf("RenderDockerCompose() error = %v", err)
+	}
+
+	for _, expected := range []string{"image: postgres:16", "image: redis:7-alpine"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Rendered compose file should contain '%s'.\nActual content:\n%s", expected, content)
+		}
+	}
+}
+
+// Commands that stop or wipe a single service rely on every data directory
+// being used by the Compose service and container of the same name.
+func TestRenderDockerComposeDataDirs(t *testing.T) {
+	for _, name := range services.GetServiceNames() {
+		service, _ := services.GetService(name)
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.Config{
+				Version:  "1.0",
+				Services: []string{service.Name},
+				Ports:    map[string]config.ServicePorts{},
+				Env:      map[string]string{},
+				DataPath: "/data",
+			}
+
+			content, err := templates.RenderDockerCompose(cfg, templates.Environment{})
+			if err != nil {
+				t.Fatalf("RenderDockerCompose() error = %v", err)
+			}
+
+			for _, dir := range service.DataDirs() {
+				for _, expected := range []string{
+					"\n  " + dir + ":\n",
+					"container_name: dockenv-" + dir + "\n",
+					"- /data/" + dir + ":",
+				} {
+					if !strings.Contains(string(content), expected) {
+						t.Errorf("Rendered compose file should contain %q.\nActual content:\n%s", expected, content)
+					}
+				}
+			}
+		})
 	}
 }
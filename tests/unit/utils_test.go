@@ -70,8 +70,28 @@ data_path: "/custom/path"`,
 			setupConfig: true,
 			expectError: false,
 			expectedFields: map[string]interface{}{
-				"version":  "1.0",
-				"services": 2,
+				"version":    "1.0",
+				"services":   2,
+				"mysql_port": 3306, // legacy single-port format
+			},
+		},
+		{
+			name: "named ports",
+			configContent: `version: "1.0"
+services:
+  - mysql
+  - rabbitmq
+ports:
+  mysql:
+    mysql: 3307
+  rabbitmq:
+    amqp: 5672
+    management: 25672`,
+			setupConfig: true,
+			expectError: false,
+			expectedFields: map[string]interface{}{
+				"services":   2,
+				"mysql_port": 3307,
 			},
 		},
 		{
@@ -127,6 +147,12 @@ services:
 				}
 			}
 
+			if mysqlPort, ok := tt.expectedFields["mysql_port"]; ok {
+				if cfg.Ports["mysql"]["mysql"] != mysqlPort.(int) {
+					t.Errorf("LoadConfig() mysql port = %v, want %v", cfg.Ports["mysql"], mysqlPort)
+				}
+			}
+
 			if servicesLen, ok := tt.expectedFields["services"]; ok {
 				if len(cfg.Services) != servicesLen.(int) {
 					t.Errorf("LoadConfig() services length = %v, want %v", len(cfg.Services), servicesLen)
@@ -158,9 +184,9 @@ func TestSaveConfig(t *testing.T) {
 	cfg := &config.Config{
 		Version:  "1.0",
 		Services: []string{"mysql", "redis"},
-		Ports: map[string]int{
-			"mysql": 3306,
-			"redis": 6379,
+		Ports: map[string]config.ServicePorts{
+			"mysql": {"mysql": 3306},
+			"redis": {"redis": 6379},
 		},
 		Env: map[string]string{
 			"DB_HOST": "127.0.0.1",